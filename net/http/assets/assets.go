@@ -0,0 +1,221 @@
+// Package assets stores the images, stylesheets and icons
+// of html documents into an fsi.FileSystem,
+// and points the documents to the stored copies.
+//
+// Copies are named by the sha1 of their content,
+// thus an asset shared by many documents is stored once.
+// An index from source url to copy spares repeated downloads.
+package assets
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Getter downloads an asset.
+// contentType may be empty; it is then sniffed.
+type Getter func(surl string) (body []byte, contentType string, err error)
+
+// Localizer holds the index of one asset directory.
+type Localizer struct {
+	MaxSize int // bigger assets remain remote
+
+	fs        fsi.FileSystem
+	dir       string
+	urlPrefix string
+	get       Getter
+
+	mu    sync.Mutex
+	index map[string]string // source url => name below dir
+	dirty bool
+}
+
+// Report counts the work of Localize.
+type Report struct {
+	Refs    int      // references pointed to local copies
+	Fetched int      // successful downloads
+	Reused  int      // references found in the index, without download
+	Stored  int      // new files; Fetched - Stored were known by content
+	Failed  []string // urls, which remain remote
+}
+
+func (r *Report) add(o Report) {
+	r.Refs += o.Refs
+	r.Fetched += o.Fetched
+	r.Reused += o.Reused
+	r.Stored += o.Stored
+	r.Failed = append(r.Failed, o.Failed...)
+}
+
+// Open loads the index of dir.
+// Documents will reference the copies as urlPrefix + name.
+func Open(fs fsi.FileSystem, dir, urlPrefix string, get Getter) (*Localizer, error) {
+	l := &Localizer{
+		MaxSize:   5 << 20,
+		fs:        fs,
+		dir:       dir,
+		urlPrefix: urlPrefix,
+		get:       get,
+		index:     map[string]string{},
+	}
+	b, err := fs.ReadFile(l.indexFile())
+	if err != nil {
+		return l, nil // no index yet
+	}
+	if err := json.Unmarshal(b, &l.index); err != nil {
+		return nil, fmt.Errorf("%v: %v", l.indexFile(), err)
+	}
+	return l, nil
+}
+
+func (l *Localizer) indexFile() string {
+	return path.Join(l.dir, "index.json")
+}
+
+// Save writes the index, if it has changed.
+func (l *Localizer) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dirty {
+		return nil
+	}
+	b, err := json.MarshalIndent(l.index, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := common.WriteFile(l.fs, l.indexFile(), b); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// Len is the number of indexed source urls.
+func (l *Localizer) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.index)
+}
+
+// Asset returns the name of the local copy of surl,
+// downloading and storing it, if necessary.
+func (l *Localizer) Asset(surl string) (string, Report, error) {
+	return l.asset(surl, 0)
+}
+
+func (l *Localizer) asset(surl string, depth int) (string, Report, error) {
+
+	var rep Report
+
+	l.mu.Lock()
+	name, ok := l.index[surl]
+	l.mu.Unlock()
+	if ok {
+		rep.Reused++
+		return name, rep, nil
+	}
+
+	body, ctype, err := l.get(surl)
+	if err != nil {
+		return "", rep, err
+	}
+	rep.Fetched++
+	if l.MaxSize > 0 && len(body) > l.MaxSize {
+		return "", rep, fmt.Errorf("%v bytes exceed %v", len(body), l.MaxSize)
+	}
+	if ctype == "" {
+		ctype = http.DetectContentType(body)
+	}
+	ext := extension(ctype, surl)
+
+	// stylesheets reference further assets;
+	// the rewritten stylesheet is what we address
+	if ext == ".css" && depth < 2 {
+		base, _ := url.Parse(surl)
+		var crep Report
+		body, crep = l.rewriteCSS(body, base, depth+1, "../")
+		rep.add(crep)
+	}
+
+	sum := fmt.Sprintf("%x", sha1.Sum(body))
+	name = sum[:2] + "/" + sum[2:] + ext
+
+	fn := path.Join(l.dir, name)
+	if _, err := l.fs.Stat(fn); err != nil {
+		if err := common.WriteFile(l.fs, fn, body); err != nil {
+			return "", rep, err
+		}
+		rep.Stored++
+	}
+
+	l.mu.Lock()
+	l.index[surl] = name
+	l.dirty = true
+	l.mu.Unlock()
+	return name, rep, nil
+}
+
+var extensions = map[string]string{
+	"image/jpeg":               ".jpg",
+	"image/png":                ".png",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/svg+xml":            ".svg",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"text/css":                 ".css",
+	"font/woff":                ".woff",
+	"font/woff2":               ".woff2",
+	"application/font-woff":    ".woff",
+}
+
+// extension by content type, else by url path.
+func extension(ctype, surl string) string {
+	ctype = strings.ToLower(strings.TrimSpace(strings.Split(ctype, ";")[0]))
+	if ext, ok := extensions[ctype]; ok {
+		return ext
+	}
+	u, err := url.Parse(surl)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if len(ext) < 2 || len(ext) > 6 {
+		return ""
+	}
+	for _, c := range ext[1:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// resolve returns the absolute http url of ref, or empty string.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,110 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+	"golang.org/x/net/html"
+)
+
+var remote = map[string]string{
+	"http://www.example.com/img/a.jpg":      "\xff\xd8\xff\xe0 jpeg a",
+	"http://www.example.com/img/a-2x.jpg":   "\xff\xd8\xff\xe0 jpeg a 2x",
+	"http://cdn.example.com/same-as-a.jpg":  "\xff\xd8\xff\xe0 jpeg a",
+	"http://www.example.com/css/site.css":   `body { background: url("../img/bg.png") } @import "print.css";`,
+	"http://www.example.com/css/print.css":  `p { color: black }`,
+	"http://www.example.com/img/bg.png":     "\x89PNG\r\n\x1a\n background",
+	"http://www.example.com/favicon.ico":    "\x00\x00\x01\x00 icon",
+	"http://www.example.com/img/broken.jpg": "",
+}
+
+func getter(cnt *int) Getter {
+	return func(surl string) ([]byte, string, error) {
+		*cnt++
+		body, ok := remote[surl]
+		if !ok || body == "" {
+			return nil, "", fmt.Errorf("404 %v", surl)
+		}
+		ctype := ""
+		if strings.HasSuffix(surl, ".ico") {
+			ctype = "image/x-icon"
+		}
+		return []byte(body), ctype, nil
+	}
+}
+
+const page = `<html><head>
+	<link rel="stylesheet" href="/css/site.css">
+	<link rel="shortcut icon" href="/favicon.ico">
+	<style>h1 { background: url(/img/bg.png) }</style>
+</head><body>
+	<img src="/img/a.jpg" srcset="/img/a.jpg 1x, /img/a-2x.jpg 2x">
+	<img src="http://cdn.example.com/same-as-a.jpg">
+	<img src="data:image/gif;base64,R0lGOD">
+	<a href="/img/broken.jpg" cfrom="img">[img]</a>
+	<a href="/news/europe">text link</a>
+</body></html>`
+
+func TestLocalize(t *testing.T) {
+
+	fs := memfs.New()
+	cnt := 0
+	l, err := Open(fs, "/repo/_assets", "/serve/_assets/", getter(&cnt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _ := html.Parse(strings.NewReader(page))
+	base, _ := url.Parse("http://www.example.com/news/europe/a1")
+	rep := l.Localize(doc, base)
+
+	var b bytes.Buffer
+	html.Render(&b, doc)
+	out := b.String()
+
+	// a.jpg twice, a-2x, same-as-a, site.css with bg.png and print.css, favicon, bg.png in <style>
+	if rep.Refs != 9 || rep.Fetched != 7 || len(rep.Failed) != 1 {
+		t.Errorf("report %+v", rep)
+	}
+	// same-as-a has the content of a; bg.png is fetched once
+	if rep.Stored != 6 || rep.Reused != 2 {
+		t.Errorf("report %+v", rep)
+	}
+	if strings.Contains(out, "www.example.com/img") || strings.Contains(out, "cdn.example.com") {
+		t.Errorf("remote images left:\n%v", out)
+	}
+	if !strings.Contains(out, "/img/broken.jpg") || !strings.Contains(out, "data:image/gif") || !strings.Contains(out, `href="/news/europe"`) {
+		t.Errorf("untouchables touched:\n%v", out)
+	}
+	if !strings.Contains(out, ` 2x"`) || strings.Count(out, "/serve/_assets/") != 7 {
+		t.Errorf("srcset or count:\n%v", out)
+	}
+
+	name, _, err := l.Asset("http://www.example.com/css/site.css")
+	if err != nil || !strings.HasSuffix(name, ".css") {
+		t.Fatalf("%v %v", name, err)
+	}
+	css, _ := fs.ReadFile("/repo/_assets/" + name)
+	if !strings.Contains(string(css), `url("../`) || !strings.Contains(string(css), `@import "../`) {
+		t.Errorf("stylesheet references not relative to the copy: %s", css)
+	}
+
+	// a second article reuses the index - without downloads
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+	l2, err := Open(fs, "/repo/_assets", "/serve/_assets/", getter(&cnt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := cnt
+	doc, _ = html.Parse(strings.NewReader(page))
+	rep = l2.Localize(doc, base)
+	if cnt-before != 1 || rep.Stored != 0 { // only the broken one is retried
+		t.Errorf("downloads %v, report %+v", cnt-before, rep)
+	}
+}
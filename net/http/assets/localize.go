@@ -0,0 +1,138 @@
+package assets
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+)
+
+// Localize points the images, stylesheets and icons of doc
+// to local copies; base resolves relative references.
+// Next to img and source, it takes the <a cfrom="img">,
+// into which domclean2 converts images.
+// References, which cannot be fetched, remain as they are.
+func (l *Localizer) Localize(doc *html.Node, base *url.URL) Report {
+
+	var rep Report
+
+	local := func(ref string) (string, bool) {
+		surl := resolve(base, ref)
+		if surl == "" {
+			return "", false
+		}
+		name, r, err := l.asset(surl, 0)
+		rep.add(r)
+		if err != nil {
+			rep.Failed = append(rep.Failed, surl)
+			return "", false
+		}
+		rep.Refs++
+		return l.urlPrefix + name, true
+	}
+
+	var fr func(n *html.Node)
+	fr = func(n *html.Node) {
+
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if u := resolve(base, dom.Attr(n.Attr, "href")); u != "" {
+					base, _ = url.Parse(u)
+				}
+			case "img", "source":
+				for i, a := range n.Attr {
+					switch a.Key {
+					case "src", "data-src":
+						if loc, ok := local(a.Val); ok {
+							n.Attr[i].Val = loc
+						}
+					case "srcset", "data-srcset":
+						n.Attr[i].Val = rewriteSrcset(a.Val, local)
+					}
+				}
+			case "a":
+				if dom.Attr(n.Attr, "cfrom") == "img" {
+					setAttr(n, "href", local)
+				}
+			case "link":
+				rel := " " + strings.ToLower(dom.Attr(n.Attr, "rel")) + " "
+				if strings.Contains(rel, " stylesheet ") || strings.Contains(rel, "icon") {
+					setAttr(n, "href", local)
+				}
+			case "style":
+				if c := n.FirstChild; c != nil && c.Type == html.TextNode {
+					css, r := l.rewriteCSS([]byte(c.Data), base, 0, l.urlPrefix)
+					rep.add(r)
+					c.Data = string(css)
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(doc)
+	return rep
+}
+
+func setAttr(n *html.Node, key string, local func(string) (string, bool)) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			if loc, ok := local(a.Val); ok {
+				n.Attr[i].Val = loc
+			}
+		}
+	}
+}
+
+// rewriteSrcset localizes each candidate of
+//
+//	srcset="a.jpg 1x, b.jpg 2x"
+func rewriteSrcset(srcset string, local func(string) (string, bool)) string {
+	cands := strings.Split(srcset, ",")
+	for i, cand := range cands {
+		fields := strings.Fields(cand)
+		if len(fields) == 0 {
+			continue
+		}
+		if loc, ok := local(fields[0]); ok {
+			fields[0] = loc
+		}
+		cands[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(cands, ", ")
+}
+
+var cssRefs = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)|@import\s+['"]([^'"]+)['"]`)
+
+// rewriteCSS localizes url() and @import references of a stylesheet.
+// The copies are referenced as prefix + name;
+// stylesheets stored below dir need "../", since names have a subdirectory.
+func (l *Localizer) rewriteCSS(css []byte, base *url.URL, depth int, prefix string) ([]byte, Report) {
+
+	var rep Report
+	out := cssRefs.ReplaceAllFunc(css, func(m []byte) []byte {
+		sm := cssRefs.FindSubmatch(m)
+		ref := string(sm[1])
+		if ref == "" {
+			ref = string(sm[2])
+		}
+		surl := resolve(base, ref)
+		if surl == "" {
+			return m
+		}
+		name, r, err := l.asset(surl, depth)
+		rep.add(r)
+		if err != nil {
+			rep.Failed = append(rep.Failed, surl)
+			return m
+		}
+		rep.Refs++
+		return []byte(strings.Replace(string(m), ref, prefix+name, 1))
+	})
+	return out, rep
+}
@@ -15,17 +15,16 @@ import (
 
 var opt = levenshtein.Options{1, 1, 1} // cheap substitution
 
-var levelsToProcess = map[int]bool{1: true}
-
-var levelsTolerance = 0
-
 const excerptLen = 20
 
 var appliedLevenshtein = 0
 var appliedCompare = 0
 var breakMapsTooDistinct = 0
 
-func similarTextifiedTrees(mp map[string][]*TextifiedTree, skipPrefix map[string]bool, onlyKeys map[string]bool) []TextifiedTree {
+// similarTextifiedTrees compares the fragments of level lvl
+// of the onlyKeys documents to the fragments of all other documents.
+func similarTextifiedTrees(mp map[string][]*TextifiedTree, skipPrefix map[string]bool, onlyKeys map[string]bool,
+	lvl int, o DedupOptions) []TextifiedTree {
 
 	pf = pfDevNull
 	defer func() { pf = pfRestore }()
@@ -41,7 +40,7 @@ func similarTextifiedTrees(mp map[string][]*TextifiedTree, skipPrefix map[string
 	MarkX:
 		for _, tt := range tts {
 
-			if !levelsToProcess[tt.Lvl] {
+			if tt.Lvl != lvl {
 				continue
 			}
 
@@ -57,7 +56,7 @@ func similarTextifiedTrees(mp map[string][]*TextifiedTree, skipPrefix map[string
 
 			}
 
-			similarTextifiedTrees2(tt, mp, skipPrefix)
+			similarTextifiedTrees2(tt, mp, skipPrefix, o)
 			if len(tt.Similars) > 0 {
 				frags = append(frags, *tt)
 			}
@@ -67,7 +66,7 @@ func similarTextifiedTrees(mp map[string][]*TextifiedTree, skipPrefix map[string
 	return frags
 }
 
-func similarTextifiedTrees2(src *TextifiedTree, mp map[string][]*TextifiedTree, skipPrefix map[string]bool) {
+func similarTextifiedTrees2(src *TextifiedTree, mp map[string][]*TextifiedTree, skipPrefix map[string]bool, o DedupOptions) {
 
 	// srcE := word.WrapAsEqualer(string(src.Text), true) // ssrc as Equaler
 	srcE := wordb.WrapAsEqualer(src.Text, true)
@@ -86,12 +85,12 @@ func similarTextifiedTrees2(src *TextifiedTree, mp map[string][]*TextifiedTree,
 		for _, tt := range tts {
 			// outl, text := tt.Outl, tt.Text
 
-			if tt.Lvl > src.Lvl+levelsTolerance {
+			if tt.Lvl > src.Lvl+o.LevelTolerance {
 				break // since we are now sorted by lvl, we can this is safe
 			}
 
 			if tt.Lvl == src.Lvl ||
-				(tt.Lvl > src.Lvl && tt.Lvl <= src.Lvl+levelsTolerance) {
+				(tt.Lvl > src.Lvl && tt.Lvl <= src.Lvl+o.LevelTolerance) {
 				// proceed
 			} else {
 				continue
@@ -105,7 +104,7 @@ func similarTextifiedTrees2(src *TextifiedTree, mp map[string][]*TextifiedTree,
 				continue
 			}
 
-			if HistoBasedDistance(src, tt) > 0.51 {
+			if HistoBasedDistance(src, tt) > o.MaxHistoDistance {
 				breakMapsTooDistinct++
 				continue
 			}
@@ -130,7 +129,7 @@ func similarTextifiedTrees2(src *TextifiedTree, mp map[string][]*TextifiedTree,
 			}
 
 			//
-			if relDist < 0.26 && absDist < 10 {
+			if relDist < o.MaxRelLevenshtein && absDist < o.MaxAbsLevenshtein {
 				if br {
 					pf("\t")
 				}
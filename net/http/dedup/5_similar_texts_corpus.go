@@ -0,0 +1,202 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/repo"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"github.com/pbberlin/tools/text/fingerprint"
+)
+
+const (
+	corpusBands      = 8  // of 8 bits each
+	corpusMaxHamming = 3  // below corpusBands => every match is found
+	corpusMinShingle = 10 // shorter fragments have unreliable SimHashes
+
+	corpusShards  = 16   // files per host
+	corpusMaxDocs = 2000 // the oldest are dropped beyond; keeps each shard far below 1 MB
+)
+
+// Corpus keeps the SimHashes of the fragments of all documents
+// deduped so far - thousands, unlike the few of a single Dedup call.
+// Fragments recurring in many of them are boilerplate,
+// even if the comparison documents of a Dedup call lack them.
+//
+// The documents are spread over corpusShards files by URL hash;
+// Save writes only the shards changed.
+type Corpus struct {
+	fs    fsi.FileSystem
+	dir   string
+	idx   *fingerprint.SimIndex
+	docs  map[string]*corpusEntry
+	dirty map[int]bool // shards to save
+}
+
+type corpusEntry struct {
+	Added  time.Time
+	Hashes map[string]uint64 // outline => simhash
+}
+
+// OpenCorpus loads the corpus shards from dir; missing shards are empty.
+func OpenCorpus(fs fsi.FileSystem, dir string) (*Corpus, error) {
+	c := &Corpus{fs: fs, dir: dir, idx: fingerprint.NewSimIndex(corpusBands),
+		docs: map[string]*corpusEntry{}, dirty: map[int]bool{}}
+	for i := 0; i < corpusShards; i++ {
+		b, err := fs.ReadFile(c.shardFile(i))
+		if err != nil {
+			continue
+		}
+		docs := map[string]*corpusEntry{}
+		if err := json.Unmarshal(b, &docs); err != nil {
+			return nil, fmt.Errorf("%v: %v", c.shardFile(i), err)
+		}
+		for surl, d := range docs {
+			c.docs[surl] = d
+			for outl, h := range d.Hashes {
+				c.idx.Add(surl+" "+outl, h)
+			}
+		}
+	}
+	return c, nil
+}
+
+// hostCorpus opens the corpus of a host.
+// Concurrent requests may overwrite each other's additions;
+// the corpus is a statistic, not a record.
+func hostCorpus(fs fsi.FileSystem, host string) (*Corpus, error) {
+	return OpenCorpus(fs, path.Join(repo.DocRoot(), corpusDir, host))
+}
+
+func shardOf(surl string) int {
+	h := fnv.New32a()
+	h.Write([]byte(surl))
+	return int(h.Sum32() % corpusShards)
+}
+
+func (c *Corpus) shardFile(i int) string {
+	return path.Join(c.dir, fmt.Sprintf("shard-%02d.json", i))
+}
+
+// Save writes the changed shards.
+func (c *Corpus) Save() error {
+	shards := map[int]map[string]*corpusEntry{}
+	for i := range c.dirty {
+		shards[i] = map[string]*corpusEntry{}
+	}
+	for surl, d := range c.docs {
+		if sh, ok := shards[shardOf(surl)]; ok {
+			sh[surl] = d
+		}
+	}
+	for i, sh := range shards {
+		b, err := json.Marshal(sh)
+		if err != nil {
+			return err
+		}
+		if err := common.WriteFile(c.fs, c.shardFile(i), b); err != nil {
+			return err
+		}
+		delete(c.dirty, i)
+	}
+	return nil
+}
+
+// Docs is the number of documents.
+func (c *Corpus) Docs() int { return len(c.docs) }
+
+func corpusHash(tt *TextifiedTree) (uint64, bool) {
+	shingles := fingerprint.Shingles(string(tt.Text), shingleLen)
+	if len(shingles) < corpusMinShingle {
+		return 0, false
+	}
+	return fingerprint.SimHash(shingles), true
+}
+
+// Add replaces the fragments of document surl.
+// Beyond corpusMaxDocs, the documents added first are dropped.
+func (c *Corpus) Add(surl string, tts []*TextifiedTree) {
+	c.remove(surl)
+	hashes := map[string]uint64{}
+	for _, tt := range tts {
+		if h, ok := corpusHash(tt); ok {
+			hashes[tt.Outline] = h
+			c.idx.Add(surl+" "+tt.Outline, h)
+		}
+	}
+	c.docs[surl] = &corpusEntry{Added: time.Now(), Hashes: hashes}
+	c.dirty[shardOf(surl)] = true
+
+	for len(c.docs) > corpusMaxDocs {
+		oldest := ""
+		for u, d := range c.docs {
+			if oldest == "" || d.Added.Before(c.docs[oldest].Added) {
+				oldest = u
+			}
+		}
+		c.remove(oldest)
+	}
+}
+
+func (c *Corpus) remove(surl string) {
+	d, ok := c.docs[surl]
+	if !ok {
+		return
+	}
+	for outl := range d.Hashes {
+		c.idx.Remove(surl + " " + outl)
+	}
+	delete(c.docs, surl)
+	c.dirty[shardOf(surl)] = true
+}
+
+// Recurring returns the fragments of document surl,
+// found in at least minDocs other documents, by outline.
+func (c *Corpus) Recurring(surl string, tts []*TextifiedTree, minDocs int) map[string][]fingerprint.Match {
+	ret := map[string][]fingerprint.Match{}
+	for _, tt := range tts {
+		h, ok := corpusHash(tt)
+		if !ok {
+			continue
+		}
+		docs := map[string]bool{}
+		ms := []fingerprint.Match{}
+		for _, m := range c.idx.Query(h, corpusMaxHamming) {
+			d := strings.SplitN(m.ID, " ", 2)[0]
+			if d != surl {
+				docs[d] = true
+				ms = append(ms, m)
+			}
+		}
+		if len(docs) >= minDocs {
+			ret[tt.Outline] = ms
+		}
+	}
+	return ret
+}
+
+// weed adds the fragments of document surl, recurring in the corpus,
+// to skipPrefixes - in outline order, skipping the descendants of removed ones.
+func (c *Corpus) weed(surl string, tts []*TextifiedTree, minDocs int, skipPrefixes map[string]bool) []Removed {
+	rms := []Removed{}
+	cms := c.Recurring(surl, tts, minDocs)
+	for _, tt := range tts {
+		ms, ok := cms[tt.Outline]
+		if !ok || skipPrefixes[tt.Outline+"."] || skippedAncestor(tt.Outline, skipPrefixes) {
+			continue
+		}
+		skipPrefixes[tt.Outline+"."] = true
+		rm := Removed{Outline: tt.Outline, Lvl: tt.Lvl, Text: string(tt.Text)}
+		for _, m := range ms {
+			parts := strings.SplitN(m.ID, " ", 2) // url outline
+			rm.Matches = append(rm.Matches, Match{URL: parts[0], Outline: parts[1], Similarity: m.Similarity})
+		}
+		rms = append(rms, rm)
+	}
+	return rms
+}
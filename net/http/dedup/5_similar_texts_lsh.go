@@ -0,0 +1,71 @@
+package dedup
+
+import (
+	"github.com/pbberlin/tools/text/fingerprint"
+)
+
+const (
+	shingleLen = 3   // words
+	lshBands   = 20  // buckets per fragment
+	lshRows    = 5   // signature components per bucket
+	minJaccard = 0.8 // roughly RelLevenshtein 0.2, as in the weed stages
+)
+
+var minHasher = fingerprint.NewMinHasher(lshBands*lshRows, 1)
+
+// nearDuplicates finds the fragments of document baseKey,
+// which recur nearly identical in at least minOthers other documents.
+// It returns their outline prefixes - as required by dedupApply.
+func nearDuplicates(mp map[string][]*TextifiedTree, baseKey string, minOthers int) map[string]bool {
+	skipPrefixes := map[string]bool{}
+	for outl := range fingerprintMatches(mp, baseKey, minOthers, minJaccard) {
+		skipPrefixes[outl+"."] = true
+	}
+	return skipPrefixes
+}
+
+// fingerprintMatches returns the matching fragments of the other documents
+// by outline of the base fragment.
+//
+// Unlike similarTextifiedTrees, it does not compare all pairs:
+// fragments are put into LSH buckets once,
+// and only bucket mates are compared.
+// Thus it scales to thousands of documents.
+func fingerprintMatches(mp map[string][]*TextifiedTree, baseKey string,
+	minOthers int, min float64) map[string][]fingerprint.Match {
+
+	sourceOf := map[string]string{} // fragment id => document
+
+	idx := fingerprint.NewIndex(lshBands, lshRows)
+	for fnKey, tts := range mp {
+		if fnKey == baseKey {
+			continue
+		}
+		for _, tt := range tts {
+			sig := minHasher.Signature(fingerprint.Shingles(string(tt.Text), shingleLen))
+			if sig == nil {
+				continue
+			}
+			id := fnKey + " " + tt.Outline
+			sourceOf[id] = fnKey
+			idx.Add(id, sig)
+		}
+	}
+
+	ret := map[string][]fingerprint.Match{}
+	for _, tt := range mp[baseKey] {
+		sig := minHasher.Signature(fingerprint.Shingles(string(tt.Text), shingleLen))
+		if sig == nil {
+			continue
+		}
+		sources := map[string]bool{}
+		ms := idx.Query(sig, min)
+		for _, m := range ms {
+			sources[sourceOf[m.ID]] = true
+		}
+		if len(sources) >= minOthers {
+			ret[tt.Outline] = ms
+		}
+	}
+	return ret
+}
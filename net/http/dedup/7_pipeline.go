@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -14,15 +15,50 @@ import (
 	"github.com/pbberlin/tools/net/http/loghttp"
 	"github.com/pbberlin/tools/net/http/repo"
 	"github.com/pbberlin/tools/net/http/routes"
+	"github.com/pbberlin/tools/net/http/urlnorm"
 	"github.com/pbberlin/tools/os/fsi"
 	"github.com/pbberlin/tools/stringspb"
 	"github.com/pbberlin/tools/util"
 	"golang.org/x/net/html"
 )
 
-// Puttting it all together
-func Dedup(oURL *url.URL,
-	least3Files []repo.FullArticle, lg loghttp.FuncBufUniv, fs fsi.FileSystem) *html.Node {
+// DedupResult lists, what Dedup removed, and why.
+type DedupResult struct {
+	Node    *html.Node // the base document without the removed fragments
+	URLs    []string   // the base document, then the comparison documents
+	Removed []Removed  // in order of removal
+}
+
+// Removed is a fragment of the base document,
+// which was found in the comparison documents.
+type Removed struct {
+	Outline string // ol attribute of the fragment root
+	Lvl     int
+	Stage   int    // 0 for fingerprints and corpus, else the weed stage
+	Text    string // textified, as it was compared
+	Matches []Match
+}
+
+// Match is the counterpart of a removed fragment in a comparison document.
+type Match struct {
+	URL            string
+	Outline        string
+	Jaccard        float64 // estimated; fingerprint stage only
+	Similarity     float64 // of SimHashes, 1 - hamming/64; corpus only
+	AbsLevenshtein int     // weed stages only
+	RelLevenshtein float64
+}
+
+// Puttting it all together.
+// Dedup removes the fragments of least3Files[0],
+// which recur in o.NumTotal-1 of the other files.
+// Stages are written to o.DumpDir, if set.
+func Dedup(oURL *url.URL, least3Files []repo.FullArticle,
+	o DedupOptions, lg loghttp.FuncBufUniv) (*DedupResult, error) {
+
+	if len(least3Files) < o.NumTotal || o.NumTotal < 2 {
+		return nil, fmt.Errorf("%v documents; dedup requires %v", len(least3Files), util.Max(2, o.NumTotal))
+	}
 
 	opts := domclean2.CleaningOptions{Proxify: true, Beautify: true}
 	// opts.FNamer = fNamer
@@ -30,20 +66,49 @@ func Dedup(oURL *url.URL,
 	// opts.RemoteHost = fetch.HostFromStringUrl(least3Files[0].Url)
 	opts.RemoteHost = oURL.Host
 
+	// fNamer yields nil without dumping
+	fNamer := func(i int) func() string {
+		if o.DumpDir == "" || o.DumpFS == nil {
+			return nil
+		}
+		fNamer := domclean2.FileNamer(o.DumpDir, i)
+		fNamer() // first call yields key
+		return fNamer
+	}
+	fnKey := func(i int) string {
+		return domclean2.FileNamer("", i)() // first call yields key
+	}
+
+	res := &DedupResult{}
+	urlOf := map[string]string{}
+	for i, a := range least3Files {
+		res.URLs = append(res.URLs, a.Url)
+		urlOf[fnKey(i)] = a.Url
+	}
+
 	//
 	// domclean
+	docs := make([]*html.Node, len(least3Files))
+	namers := make([]func() string, len(least3Files))
 	for i := 0; i < len(least3Files); i++ {
 
-		fNamer := domclean2.FileNamer(logDir, i)
-		fNamer() // first call yields key
+		namers[i] = fNamer(i)
 
 		lg("cleaning %4.1fkB from %v", float64(len(least3Files[i].Body))/1024,
 			stringspb.ToLenR(least3Files[i].Url, 60))
 
+		// the comparison documents are discarded; their assets are not needed
+		opts.Assets = nil
+		if i == 0 {
+			opts.Assets = o.Assets
+		}
 		doc, err := domclean2.DomClean(least3Files[i].Body, opts)
-		lg(err)
+		if err != nil {
+			return nil, fmt.Errorf("cleaning %v: %v", least3Files[i].Url, err)
+		}
+		docs[i] = doc
 
-		fileDump(lg, fs, doc, fNamer, ".html")
+		fileDump(lg, o.DumpFS, doc, namers[i], ".html")
 
 	}
 
@@ -52,24 +117,22 @@ func Dedup(oURL *url.URL,
 		// Textify with brute force
 		for i := 0; i < len(least3Files); i++ {
 
-			fNamer := domclean2.FileNamer(logDir, i)
-			fNamer() // first call yields key
-
-			bts, err := fs.ReadFile(fNamer() + ".html")
+			var buf bytes.Buffer
+			err := html.Render(&buf, docs[i])
 			lg(err)
-			doc, err := html.Parse(bytes.NewReader(bts))
+			doc, err := html.Parse(&buf) // textifyBruteForce changes the doc
 			lg(err)
 
 			textifyBruteForce(doc)
 
-			var buf bytes.Buffer
+			buf.Reset()
 			err = html.Render(&buf, doc)
 			lg(err)
 
 			b := buf.Bytes()
 			b = bytes.Replace(b, []byte("[br]"), []byte("\n"), -1)
 
-			fileDump(lg, fs, b, fNamer, "_raw.txt")
+			fileDump(lg, o.DumpFS, b, namers[i], "_raw.txt")
 		}
 	}
 
@@ -79,23 +142,13 @@ func Dedup(oURL *url.URL,
 	textsByArticOutl := map[string][]*TextifiedTree{}
 	for i := 0; i < len(least3Files); i++ {
 
-		fNamer := domclean2.FileNamer(logDir, i)
-		fnKey := fNamer() // first call yields key
-
-		bts, err := fs.ReadFile(fNamer() + ".html")
-
-		doc, err := html.Parse(bytes.NewReader(bts))
-		lg(err)
-
-		fNamer() // one more
-
 		//
-		mp, bts := BubbledUpTextExtraction(doc, fnKey)
-		fileDump(lg, fs, bts, fNamer, ".txt")
+		mp, bts := BubbledUpTextExtraction(docs[i], fnKey(i))
+		fileDump(lg, o.DumpFS, bts, namers[i], ".txt")
 
 		mpSorted, dump := orderByOutline(mp)
-		fileDump(lg, fs, dump, fNamer, ".txt")
-		textsByArticOutl[fnKey] = mpSorted
+		fileDump(lg, o.DumpFS, dump, namers[i], ".txt")
+		textsByArticOutl[fnKey(i)] = mpSorted
 
 		// for k, v := range mpSorted {
 		// 	if k%33 != 0 {
@@ -106,47 +159,80 @@ func Dedup(oURL *url.URL,
 
 	}
 
+	baseKey := fnKey(0)
+	byOutline := map[string]*TextifiedTree{}
+	for _, tt := range textsByArticOutl[baseKey] {
+		byOutline[tt.Outline] = tt
+	}
+
 	//
 	//
 	// We progress from level 1 downwards.
 	// Lower levels skip weeded out higher levels,
 	// to save expensive levenshtein comparisons
+	//
+	// Fingerprints catch the near identical fragments cheaply;
+	// the levenshtein stages only process the remainder.
 	var skipPrefixes = map[string]bool{}
-	for weedStage := 1; weedStage <= stageMax; weedStage++ {
+	if o.MinJaccard > 0 {
+		fms := fingerprintMatches(textsByArticOutl, baseKey, o.NumTotal-1, o.MinJaccard)
+		for outl := range fms {
+			skipPrefixes[outl+"."] = true
+		}
+		for _, tt := range textsByArticOutl[baseKey] { // in outline order
+			ms, ok := fms[tt.Outline]
+			if !ok || skippedAncestor(tt.Outline, skipPrefixes) {
+				continue
+			}
+			rm := Removed{Outline: tt.Outline, Lvl: tt.Lvl, Text: string(tt.Text)}
+			for _, m := range ms {
+				parts := strings.SplitN(m.ID, " ", 2) // fnKey outline
+				rm.Matches = append(rm.Matches, Match{URL: urlOf[parts[0]], Outline: parts[1], Jaccard: m.Similarity})
+			}
+			res.Removed = append(res.Removed, rm)
+		}
+		lg("fingerprints weeded out %v fragments", len(skipPrefixes))
+	}
+
+	// The corpus knows boilerplate of more documents than the NumTotal at hand.
+	if o.Corpus != nil {
+		rms := o.Corpus.weed(least3Files[0].Url, textsByArticOutl[baseKey], o.MinCorpusDocs, skipPrefixes)
+		res.Removed = append(res.Removed, rms...)
+		for i, a := range least3Files {
+			o.Corpus.Add(a.Url, textsByArticOutl[fnKey(i)])
+		}
+		lg("corpus of %v documents weeded out %v fragments", o.Corpus.Docs(), len(rms))
+	}
 
-		fNamer := domclean2.FileNamer(logDir, 0)
-		fnKey := fNamer() // first call yields key
+	for weedStage := 1; weedStage <= o.MaxLevel; weedStage++ {
 
-		levelsToProcess = map[int]bool{weedStage: true}
-		frags := similarTextifiedTrees(textsByArticOutl, skipPrefixes, map[string]bool{fnKey: true})
+		frags := similarTextifiedTrees(textsByArticOutl, skipPrefixes, map[string]bool{baseKey: true}, weedStage, o)
 
-		similaritiesToFile(fs, logDir, frags, weedStage)
+		if namers[0] != nil {
+			similaritiesToFile(o.DumpFS, o.DumpDir, frags, weedStage)
+		}
 
 		for _, frag := range frags {
-			if len(frag.Similars) >= numTotal-1 &&
-				frag.SumRelLevenshtein/(numTotal-1) < 0.2 {
+			if len(frag.Similars) >= o.NumTotal-1 &&
+				frag.SumRelLevenshtein/float64(o.NumTotal-1) < o.MaxAvgLevenshtein {
+				if skipPrefixes[frag.Outline+"."] {
+					continue // weeded out by fingerprints
+				}
 				skipPrefixes[frag.Outline+"."] = true
+				rm := Removed{Outline: frag.Outline, Lvl: frag.Lvl, Stage: weedStage, Text: string(frag.Text)}
+				for _, sim := range frag.Similars {
+					rm.Matches = append(rm.Matches, Match{URL: urlOf[sim.SourceID], Outline: sim.Outline,
+						AbsLevenshtein: sim.AbsLevenshtein, RelLevenshtein: sim.RelLevenshtein})
+				}
+				res.Removed = append(res.Removed, rm)
 			}
 		}
-		b := new(bytes.Buffer)
-		for k, _ := range skipPrefixes {
-			b.WriteString(k)
-			b.WriteByte(32)
-		}
-		// log.Printf("%v\n", b.String())
 
 	}
 
 	//
 	// Apply dedup
-	fNamer := domclean2.FileNamer(logDir, 0)
-	fNamer() // first call yields key
-
-	bts, err := fs.ReadFile(fNamer() + ".html")
-	lg(err)
-	doc, err := html.Parse(bytes.NewReader(bts))
-	lg(err)
-
+	doc := docs[0]
 	dedupApply(doc, skipPrefixes)
 
 	// A special after dedup cleaning:
@@ -181,13 +267,47 @@ func Dedup(oURL *url.URL,
 		domclean2.DomFormat(doc)
 	}
 
-	return doc
+	res.Node = doc
+	return res, nil
+}
+
+// skippedAncestor is true, if a parent of outline was weeded out.
+func skippedAncestor(outline string, skipPrefixes map[string]bool) bool {
+	outls := strings.Split(outline, ".")
+	for i := 0; i < len(outls)-1; i++ {
+		if skipPrefixes[strings.Join(outls[0:i+1], ".")+"."] {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport lists the removed fragments with their matches.
+func (res *DedupResult) WriteReport(w io.Writer) {
+	for i, u := range res.URLs {
+		fmt.Fprintf(w, "doc %v %v\n", i, u)
+	}
+	fmt.Fprintf(w, "\n")
+	for _, rm := range res.Removed {
+		fmt.Fprintf(w, "stage %v lvl %v %-12v %v\n", rm.Stage, rm.Lvl, rm.Outline, stringspb.ToLen(rm.Text, 60))
+		for _, m := range rm.Matches {
+			if m.Similarity > 0 {
+				fmt.Fprintf(w, "    %-12v simhash %4.2f      %v\n", m.Outline, m.Similarity, m.URL)
+			} else if rm.Stage == 0 {
+				fmt.Fprintf(w, "    %-12v jaccard %4.2f      %v\n", m.Outline, m.Jaccard, m.URL)
+			} else {
+				fmt.Fprintf(w, "    %-12v levensh %3v %4.2f  %v\n", m.Outline, m.AbsLevenshtein, m.RelLevenshtein, m.URL)
+			}
+		}
+	}
 }
 
-func FetchAndDecodeJSON(r *http.Request, surl, knownProtocol string, lg loghttp.FuncBufUniv, fs fsi.FileSystem) []repo.FullArticle {
+// FetchAndDecodeJSON requests the article surl
+// and at least cnt similar articles from the repo.
+func FetchAndDecodeJSON(r *http.Request, surl, knownProtocol string, cnt int, lg loghttp.FuncBufUniv, fs fsi.FileSystem) []repo.FullArticle {
 
 	fullURL := fmt.Sprintf("%s%s?%s=%s&cnt=%v&prot=%v", routes.AppHost(), routes.FetchSimilarURI,
-		routes.URLParamKey, surl, numTotal-1, knownProtocol)
+		routes.URLParamKey, surl, cnt, knownProtocol)
 
 	// fullURL = fmt.Sprintf("%s%s?%s=%s&cnt=%v", r.URL.Host, repo.routes.FetchSimilarURI,
 	// 	routes.URLParamKey, surl, numTotal-1)
@@ -225,7 +345,7 @@ func FetchAndDecodeJSON(r *http.Request, surl, knownProtocol string, lg loghttp.
 
 	smaxFound := string(mp["lensimilar"])
 	maxFound := util.Stoi(smaxFound)
-	if maxFound < numTotal-1 {
+	if maxFound < cnt {
 		lg("not enough files returned by FetchSimilar 1 - mp[lensimilar] too small: %s", mp["lensimilar"])
 		return nil
 	}
@@ -280,6 +400,14 @@ func FetchAndDecodeJSON(r *http.Request, surl, knownProtocol string, lg loghttp.
 
 	lg("found %v similar; decoding complete after %4.2v secs", maxFound, time.Now().Sub(start).Seconds())
 
+	// The same article under two names would pass as "similar"
+	// and erase its own content.
+	least3Files = uniqueByURL(least3Files, lg)
+	if len(least3Files) < cnt+1 {
+		lg("not enough distinct files after url normalization: %v", len(least3Files))
+		return nil
+	}
+
 	for _, v := range least3Files {
 		lg("%v %v", v.Url, len(v.Body))
 	}
@@ -287,3 +415,22 @@ func FetchAndDecodeJSON(r *http.Request, surl, knownProtocol string, lg loghttp.
 	return least3Files
 
 }
+
+// uniqueByURL keeps the first of articles with the same normalized url.
+func uniqueByURL(arts []repo.FullArticle, lg loghttp.FuncBufUniv) []repo.FullArticle {
+	seen := map[string]bool{}
+	ret := make([]repo.FullArticle, 0, len(arts))
+	for _, a := range arts {
+		key, err := urlnorm.Normalize(a.Url)
+		if err != nil {
+			key = a.Url
+		}
+		if seen[key] {
+			lg("skipping duplicate %v", a.Url)
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, a)
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package dedup
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/pbberlin/tools/net/http/domclean2"
+	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/readability"
+	"github.com/pbberlin/tools/net/http/repo"
+)
+
+// MainContent returns the main content of the first article.
+// With o.NumTotal similar articles, Dedup strips the boilerplate,
+// and the readability scoring only picks from the remainder.
+// A single article is cleaned and scored on its own.
+// Either way, o.Corpus removes the boilerplate of earlier documents.
+func MainContent(oURL *url.URL, arts []repo.FullArticle,
+	o DedupOptions, lg loghttp.FuncBufUniv) (*readability.Result, error) {
+
+	if len(arts) == 0 {
+		return nil, fmt.Errorf("no article for %v", oURL)
+	}
+
+	var res *readability.Result
+	if len(arts) >= o.NumTotal {
+		dr, err := Dedup(oURL, arts, o, lg)
+		if err != nil {
+			return nil, err
+		}
+		doc := dr.Node
+		res = readability.Extract(doc)
+		if res == nil {
+			// dedup left only scraps; take them all
+			res = &readability.Result{Node: doc, Text: readability.Text(doc)}
+		}
+	} else {
+		lg("%v similar articles; extracting from the single document", len(arts)-1)
+		opts := domclean2.CleaningOptions{Proxify: true, Beautify: true, AddOutline: true}
+		opts.RemoteHost = oURL.Host
+		opts.Assets = o.Assets
+		doc, err := domclean2.DomClean(arts[0].Body, opts)
+		if err != nil {
+			return nil, err
+		}
+		if o.Corpus != nil {
+			mp, _ := BubbledUpTextExtraction(doc, "")
+			tts, _ := orderByOutline(mp)
+			skipPrefixes := map[string]bool{}
+			rms := o.Corpus.weed(arts[0].Url, tts, o.MinCorpusDocs, skipPrefixes)
+			dedupApply(doc, skipPrefixes)
+			o.Corpus.Add(arts[0].Url, tts)
+			lg("corpus of %v documents weeded out %v fragments", o.Corpus.Docs(), len(rms))
+		}
+		res = readability.Extract(doc)
+		if res == nil {
+			return nil, fmt.Errorf("no main content found in %v", oURL)
+		}
+	}
+
+	if md := arts[0].Meta; md != nil && md.Title != "" {
+		res.Title = md.Title
+	}
+	return res, nil
+}
@@ -0,0 +1,270 @@
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/feed"
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/repo"
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/html"
+	"google.golang.org/appengine"
+)
+
+// feedHTTP republishes the stored articles of a host as a feed
+// with their cleaned content - RSS 2.0, or Atom with fmt=atom.
+// Cleaned content is persisted; each request cleans at most
+// feedCleanMax new articles, the others follow on later polls.
+//
+//	?host=www.economist.com&prefix=/news/europe&n=20
+func feedHTTP(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	lg, _ := loghttp.BuffLoggerUniversal(nil, r) // not into the feed
+
+	host := r.FormValue("host")
+	if host == "" {
+		http.Error(w, "host param required", http.StatusBadRequest)
+		return
+	}
+	prefix := r.FormValue("prefix")
+	if prefix == "" {
+		prefix = "/"
+	}
+	n, _ := strconv.Atoi(r.FormValue("n"))
+	if n < 1 || n > 100 {
+		n = 20
+	}
+	format := feed.RSS2
+	if r.FormValue("fmt") == "atom" {
+		format = feed.Atom
+	}
+
+	fs := repo.GetFS(appengine.NewContext(r))
+	arts, err := repo.Articles(fs, host, prefix, n)
+	if err == nil && len(arts) == 0 {
+		err = fmt.Errorf("no articles below %v%v", host, prefix)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	titles := make([]string, len(arts))
+	contents := make([]string, len(arts))
+	missing := []int{}
+	for i, a := range arts {
+		var ok bool
+		titles[i], contents[i], ok = loadCleaned(fs, a)
+		if !ok {
+			missing = append(missing, i)
+		}
+	}
+	if todo := len(missing); todo > 0 {
+		if todo > feedCleanMax {
+			todo = feedCleanMax
+		}
+		loc, err := repo.AssetLocalizer(r, fs)
+		if err != nil {
+			lg(err) // images remain remote
+			loc = nil
+		}
+		o := DefaultDedupOptions() // without dumping
+		o.Assets = loc
+		o.Corpus, err = hostCorpus(fs, host)
+		lg(err)
+		for _, i := range missing[:todo] {
+			// failures are persisted empty, so that they are not retried on every poll
+			titles[i], contents[i] = cleanedContent(arts[i], siblings(arts, i, o.NumTotal), o, lg)
+			lg(saveCleaned(fs, arts[i], titles[i], contents[i]))
+		}
+		if o.Corpus != nil {
+			lg(o.Corpus.Save())
+		}
+		if loc != nil {
+			if err := loc.Save(); err != nil {
+				lg(err)
+			}
+		}
+		missing = missing[todo:]
+	}
+
+	// validators cover the content cleaned so far;
+	// Last-Modified is withheld until all is cleaned
+	lastMod := arts[0].Mod // newest first
+	hsh := sha1.New()
+	fmt.Fprint(hsh, format)
+	for i, a := range arts {
+		fmt.Fprint(hsh, a.Url, a.Mod.UnixNano(), contents[i] != "")
+	}
+	etag := fmt.Sprintf(`"%x"`, hsh.Sum(nil)[:10])
+	w.Header().Set("ETag", etag)
+	if len(missing) == 0 {
+		w.Header().Set("Last-Modified", lastMod.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, lastMod, len(missing) == 0) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// paths condensed by CondenseTrailingDirs do not exist on the host
+	condensed := repo.HostDefaults(fs, host).CondenseTrailingDirs > 0
+	f := &feed.Feed{
+		Title:   host + prefix,
+		Link:    "http://" + host + prefix,
+		Updated: lastMod,
+	}
+	for i, a := range arts {
+		f.Items = append(f.Items, feedItem(a, titles[i], contents[i], condensed))
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	self := scheme + "://" + r.Host + r.URL.RequestURI()
+
+	var bts []byte
+	if format == feed.Atom {
+		bts, err = f.Atom(self)
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	} else {
+		bts, err = f.RSS2(self)
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bts)
+}
+
+// notModified evaluates If-None-Match, else If-Modified-Since;
+// the latter only if the content is complete.
+func notModified(r *http.Request, etag string, lastMod time.Time, complete bool) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == etag || t == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if !complete {
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastMod.Truncate(time.Second).After(ims)
+}
+
+// siblings are the articles, arts[i] is compared against by Dedup;
+// articles of the same section share most of their boilerplate.
+func siblings(arts []repo.FullArticle, i, numTotal int) []repo.FullArticle {
+	if len(arts) < numTotal {
+		return nil
+	}
+	sibs := []repo.FullArticle{}
+	for j := 1; j < numTotal; j++ {
+		sibs = append(sibs, arts[(i+j)%len(arts)])
+	}
+	return sibs
+}
+
+// feedItem dates the item by its metadata, else by FullArticle.Mod.
+// The GUID is the normalized url; it stays the same across re-fetches.
+// With condensed paths, it is no permalink.
+func feedItem(a repo.FullArticle, title, content string, condensed bool) feed.Item {
+
+	link := "http://" + a.Url
+	if nurl, err := urlnorm.Normalize(link); err == nil {
+		link = nurl
+	}
+	it := feed.Item{Link: link, GUID: link, Published: a.Mod, Updated: a.Mod}
+	it.NoPermaLink = condensed
+
+	if md := a.Meta; md != nil {
+		it.Title = md.Title
+		it.Author = strings.Join(md.Authors, ", ")
+		if !md.Published.IsZero() {
+			it.Published = md.Published
+		}
+		if md.Section != "" {
+			it.Categories = append(it.Categories, md.Section)
+		}
+		it.Categories = append(it.Categories, md.Keywords...)
+		it.Summary = md.Subtitle
+		if md.Canonical != "" {
+			it.Link = md.Canonical
+		}
+	}
+
+	if it.Title == "" {
+		it.Title = title
+	}
+	if it.Title == "" {
+		it.Title = a.Url
+	}
+	it.Content = content
+	return it
+}
+
+// cleanedContent returns title and main content of an article,
+// deduplicated against its siblings, rendered as html.
+// With o.Assets, its images point to copies in the repo.
+func cleanedContent(a repo.FullArticle, sibs []repo.FullArticle,
+	o DedupOptions, lg loghttp.FuncBufUniv) (string, string) {
+
+	ourl, err := fetch.URLFromString(a.Url)
+	if err != nil {
+		lg(err)
+		return "", ""
+	}
+	res, err := MainContent(ourl, append([]repo.FullArticle{a}, sibs...), o, lg)
+	if err != nil {
+		lg(err)
+		return "", ""
+	}
+	var b bytes.Buffer
+	if err := html.Render(&b, res.Node); err != nil {
+		lg(err)
+		return "", ""
+	}
+	return strings.Replace(res.Title, "\n", " ", -1), b.String()
+}
+
+func cleanedFile(surl string) string {
+	return path.Join(repo.DocRoot(), cleanedDir, surl) + ".html"
+}
+
+// loadCleaned returns the persisted cleaned content of an article,
+// if it was cleaned from its current version.
+func loadCleaned(fs fsi.FileSystem, a repo.FullArticle) (string, string, bool) {
+	b, err := fs.ReadFile(cleanedFile(a.Url))
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(b), "\n", 3)
+	if len(parts) < 3 || parts[0] != strconv.FormatInt(a.Mod.UnixNano(), 10) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// saveCleaned persists title and content, preceded by the version of the article.
+func saveCleaned(fs fsi.FileSystem, a repo.FullArticle, title, content string) error {
+	b := fmt.Sprintf("%v\n%v\n%v", a.Mod.UnixNano(), title, content)
+	return common.WriteFile(fs, cleanedFile(a.Url), []byte(b))
+}
@@ -1,14 +1,65 @@
 package dedup
 
-import "golang.org/x/net/html"
+import (
+	"github.com/pbberlin/tools/net/http/assets"
+	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/html"
+)
 
 // !DOCTYPE html head
 // !DOCTYPE html body
 //        0    1    2
+// Must equal domclean2's, which numbers the outlines below.
 const cScaffoldLvls = 2
 
-const numTotal = 3 // comparable html docs, including base doc
-const stageMax = 4 // weedstages
+// DedupOptions tune Dedup.
+// Levels are counted below cScaffoldLvls; level 1 are the children of body.
+type DedupOptions struct {
+	NumTotal int // comparable html docs, including base doc
+
+	MaxLevel       int // levels 1...MaxLevel are compared, one weed stage each
+	LevelTolerance int // fragments are compared to fragments up to this many levels deeper
+
+	MinJaccard float64 // fingerprint stage; fragments above are removed right away; zero skips the stage
+
+	Corpus        *Corpus // all documents deduped so far; nil skips the stage
+	MinCorpusDocs int     // fragments recurring in this many corpus documents are removed
+
+	MaxHistoDistance  float64 // cheap prefilter; more distinct fragments are not compared by levenshtein
+	MaxAbsLevenshtein int     // fragment pairs below both levenshtein limits are similar
+	MaxRelLevenshtein float64
+	MaxAvgLevenshtein float64 // fragments similar to all NumTotal-1 others, below this average rel. distance, are removed
+
+	Assets *assets.Localizer // stores the images of the deduped document locally; nil leaves them remote
+
+	DumpFS  fsi.FileSystem // receives the intermediate stages
+	DumpDir string         // empty: no dumping
+}
+
+// DefaultDedupOptions were tuned on economist.com and welt.de.
+func DefaultDedupOptions() DedupOptions {
+	return DedupOptions{
+		NumTotal:          3,
+		MaxLevel:          4,
+		LevelTolerance:    0,
+		MinJaccard:        minJaccard,
+		MinCorpusDocs:     5,
+		MaxHistoDistance:  0.51,
+		MaxAbsLevenshtein: 10,
+		MaxRelLevenshtein: 0.26,
+		MaxAvgLevenshtein: 0.2,
+	}
+}
+
+const uriFeed = "/dedup/feed" // cleaned articles as RSS or Atom
+
+// Cleaned feed content, one file per article; below the docRoot of the repo filesystem
+const cleanedDir = "_cleaned"
+
+const feedCleanMax = 10 // articles cleaned per feed request; the others follow on later polls
+
+// SimHashes of all deduped fragments, one dir of shards per host; below the docRoot of the repo filesystem
+const corpusDir = "_corpus"
 
 var URLs = []string{
 	"www.welt.de/politik/ausland/article146154432/Tuerkische-Bodentruppen-marschieren-im-Nordirak-ein.html",
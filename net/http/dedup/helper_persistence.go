@@ -16,7 +16,7 @@ import (
 
 var logDir = "c:/tmp/dedup/"
 
-var memMapFileSys = memfs.New(memfs.DirSort("byDateDesc")) // package variable required as "persistence"
+var memMapFileSys = memfs.New(memfs.DirSort(common.Desc(common.ByModTime))) // package variable required as "persistence"
 
 func GetFS(c context.Context, whichType int) (fs fsi.FileSystem) {
 
@@ -26,11 +26,11 @@ func GetFS(c context.Context, whichType int) (fs fsi.FileSystem) {
 		fs = fsi.FileSystem(memMapFileSys)
 	case 1:
 		// must be re-instantiated for each request
-		dsFileSys := dsfs.New(dsfs.DirSort("byDateDesc"), dsfs.MountName("mntTest"), dsfs.AeContext(c))
+		dsFileSys := dsfs.New(dsfs.DirSort(common.DirsFirst, common.Desc(common.ByModTime)), dsfs.MountName("mntTest"), dsfs.AeContext(c))
 		fs = fsi.FileSystem(dsFileSys)
 	case 2:
 
-		osFileSys := osfs.New(osfs.DirSort("byDateDesc"))
+		osFileSys := osfs.New(osfs.DirSort(common.Desc(common.ByModTime)))
 		fs = fsi.FileSystem(osFileSys)
 		os.Chdir(logDir)
 	default:
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/pbberlin/tools/net/http/domclean2"
 	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/htmlfrag"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/repo"
 	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/net/http/urlnorm"
 	"golang.org/x/net/html"
 	"google.golang.org/appengine"
 )
@@ -19,6 +22,7 @@ import (
 // and makes the EndPoints available.
 func InitHandlers() {
 	http.HandleFunc(routes.DedupURI, loghttp.Adapter(dedupHTTP))
+	http.HandleFunc(uriFeed, loghttp.Adapter(feedHTTP))
 }
 
 // BackendUIRendered returns a userinterface rendered to HTML
@@ -26,13 +30,19 @@ func BackendUIRendered() *bytes.Buffer {
 	var b1 = new(bytes.Buffer)
 	// htmlfrag.Wb(b1, "Deduplicate", "")
 
-	fullURL := fmt.Sprintf("%s?%s=%s&cnt=%v", routes.DedupURI, routes.URLParamKey, URLs[0], numTotal-1)
+	fullURL := fmt.Sprintf("%s?%s=%s&cnt=%v", routes.DedupURI, routes.URLParamKey, URLs[0], DefaultDedupOptions().NumTotal-1)
 	htmlfrag.Wb(b1, "Deduplicate", fullURL)
+	htmlfrag.Wb(b1, "report", fullURL+"&report=1", "removed fragments, their matches and distances")
+	htmlfrag.Wb(b1, "local assets", fullURL+"&assets=1", "images and stylesheets from the repo")
+	htmlfrag.Wb(b1, "feed", uriFeed+"?host=www.economist.com&prefix=/news/europe", "cleaned articles as RSS; &fmt=atom")
 
 	return b1
 }
 
 // dedupHTTP wraps Dedup()
+//
+//	cnt     similar articles to compare; default 2
+//	report  list the removed fragments instead of the document
 func dedupHTTP(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
 
 	lg, b := loghttp.BuffLoggerUniversal(w, r)
@@ -53,6 +63,9 @@ func dedupHTTP(w http.ResponseWriter, r *http.Request, m map[string]interface{})
 	lg(err)
 
 	surl := r.FormValue(routes.URLParamKey)
+	if nurl, err := urlnorm.Normalize(surl); err == nil {
+		surl = nurl
+	}
 	ourl, err := fetch.URLFromString(surl)
 	lg(err)
 	if err != nil {
@@ -72,16 +85,44 @@ func dedupHTTP(w http.ResponseWriter, r *http.Request, m map[string]interface{})
 
 	fs := GetFS(appengine.NewContext(r), 0)
 
-	least3Files := FetchAndDecodeJSON(r, ourl.String(), knownProtocol, lg, fs)
+	o := DefaultDedupOptions()
+	if cnt, err := strconv.Atoi(r.FormValue("cnt")); err == nil && cnt > 0 {
+		o.NumTotal = cnt + 1
+	}
+	o.DumpFS, o.DumpDir = fs, logDir
+	fsRepo := repo.GetFS(appengine.NewContext(r))
+	o.Corpus, err = hostCorpus(fsRepo, ourl.Host)
+	lg(err)
+	if o.Corpus != nil {
+		defer func() { lg(o.Corpus.Save()) }()
+	}
+	if r.FormValue("assets") != "" {
+		o.Assets, err = repo.AssetLocalizer(r, fsRepo)
+		lg(err)
+		if o.Assets != nil {
+			defer func() { lg(o.Assets.Save()) }()
+		}
+	}
+
+	least3Files := FetchAndDecodeJSON(r, ourl.String(), knownProtocol, o.NumTotal-1, lg, fs)
 
 	lg("Fetched and decoded; found %v", len(least3Files))
 	if len(least3Files) > 0 {
-		doc := Dedup(ourl, least3Files, lg, fs)
+		res, err := Dedup(ourl, least3Files, o, lg)
+		lg(err)
+		if err != nil {
+			return
+		}
+		doc := res.Node
 
 		fNamer := domclean2.FileNamer(logDir, 0)
 		fNamer() // first call yields key
-		fsPerm := GetFS(appengine.NewContext(r), 0)
-		fileDump(lg, fsPerm, doc, fNamer, "_fin.html")
+		fileDump(lg, fs, doc, fNamer, "_fin.html")
+
+		if r.FormValue("report") != "" {
+			res.WriteReport(b)
+			return
+		}
 
 		lg("MapSimiliarCompares: %v SimpleCompares: %v LevenstheinComp: %v\n", breakMapsTooDistinct, appliedLevenshtein, appliedCompare)
 		lg("Finish\n")
@@ -98,6 +139,32 @@ func dedupHTTP(w http.ResponseWriter, r *http.Request, m map[string]interface{})
 		w.Header().Set("Content-type", "text/html; charset=utf-8")
 		w.Write(b2.Bytes())
 
+	} else {
+
+		// no siblings - extract from the page alone
+		bts, inf, err := fetch.UrlGetter(r, fetch.Options{URL: ourl.String(), KnownProtocol: knownProtocol})
+		lg(err)
+		if err != nil {
+			lg("msg %v", inf.Msg)
+			return
+		}
+		res, err := MainContent(ourl, []repo.FullArticle{{Url: ourl.String(), Body: bts}}, o, lg)
+		lg(err)
+		if err != nil {
+			return
+		}
+		lg("main content %q at outline %v, score %4.1f", res.Title, res.Outline, res.Score)
+
+		var b2 bytes.Buffer
+		err = html.Render(&b2, res.Node)
+		lg(err)
+		if err != nil {
+			return
+		}
+
+		b = new(bytes.Buffer)
+		w.Header().Set("Content-type", "text/html; charset=utf-8")
+		w.Write(b2.Bytes())
 	}
 
 }
@@ -0,0 +1,22 @@
+package dedup
+
+import (
+	"flag"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/routes"
+	"github.com/pbberlin/tools/os/fsi/osfs"
+)
+
+// go test -tags=dedup1 -record
+var record = flag.Bool("record", false, "record http responses into testdata/recordings")
+
+// The fixtures are hand written responses of the dev server;
+// see testdata/fixtures/README.md.
+const fixtureAppHost = "localhost:8085"
+
+// useRecordings must precede GetFS(c, 2), which changes the working dir.
+func useRecordings() {
+	routes.SetAppHost(fixtureAppHost)
+	fetch.UseRecordings(osfs.New(), "testdata/recordings", *record, "testdata/fixtures")
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/pbberlin/tools/net/http/fileserver"
 	"github.com/pbberlin/tools/net/http/loghttp"
 	"github.com/pbberlin/tools/net/http/repo"
+	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/stringspb"
 )
 
@@ -22,6 +23,9 @@ func Test1(t *testing.T) {
 	lg, b := loghttp.BuffLoggerUniversal(nil, nil)
 	_ = b
 
+	useRecordings()
+	repoURL := routes.AppHost() + repo.UriMountNameY // repo.RepoURL is fixed at init
+
 	c, err := aetest.NewContext(nil)
 	lg(err)
 	if err != nil {
@@ -29,11 +33,13 @@ func Test1(t *testing.T) {
 	}
 	defer c.Close()
 	fs := GetFS(c, 2)
+	o := DefaultDedupOptions()
+	o.DumpFS, o.DumpDir = fs, logDir
 
 	remoteHostname := "www.welt.de"
 	remoteHostname = "www.welt.de/politik/ausland"
 
-	dirs1, _, msg, err := fileserver.GetDirContents(repo.RepoURL, remoteHostname)
+	dirs1, _, msg, err := fileserver.GetDirContents(repoURL, remoteHostname)
 	if err != nil {
 		lg(err)
 		lg("%s", msg)
@@ -48,7 +54,7 @@ func Test1(t *testing.T) {
 	for _, v1 := range dirs1 {
 
 		p := path.Join(remoteHostname, v1)
-		dirs2, fils2, msg, err := fileserver.GetDirContents(repo.RepoURL, p)
+		dirs2, fils2, msg, err := fileserver.GetDirContents(repoURL, p)
 		_ = dirs2
 		if err != nil {
 			lg(err)
@@ -62,11 +68,11 @@ func Test1(t *testing.T) {
 		}
 	}
 
-	if len(least3URLs) < numTotal {
+	if len(least3URLs) < o.NumTotal {
 		lg("not enough files in rss fetcher cache")
 		return
 	} else {
-		least3URLs = least3URLs[:numTotal+1]
+		least3URLs = least3URLs[:o.NumTotal+1]
 	}
 
 	lg("fils2")
@@ -79,7 +85,7 @@ func Test1(t *testing.T) {
 	least3Files := make([]repo.FullArticle, 0, len(least3URLs))
 	for i := 0; i < len(least3URLs); i++ {
 
-		surl := spf("%v/%v", repo.RepoURL, least3URLs[i])
+		surl := path.Join(repoURL, least3URLs[i])
 
 		fNamer := domclean2.FileNamer(logDir, i)
 		fNamer() // first call yields key
@@ -99,7 +105,15 @@ func Test1(t *testing.T) {
 
 	}
 
-	doc := Dedup(least3Files, lg, fs)
+	ourl, err := fetch.URLFromString(least3Files[0].Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Dedup(ourl, least3Files, o, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := res.Node
 
 	fNamer := domclean2.FileNamer(logDir, 0)
 	fNamer() // first call yields key
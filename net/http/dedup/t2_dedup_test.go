@@ -10,6 +10,7 @@ import (
 	"appengine/aetest"
 
 	"github.com/pbberlin/tools/net/http/domclean2"
+	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/loghttp"
 )
 
@@ -24,6 +25,8 @@ func Test2(t *testing.T) {
 	// }
 	// defer closureOverBuf(b) // the argument is ignored,
 
+	useRecordings()
+
 	var c aetest.Context
 	if false {
 		var err error
@@ -35,14 +38,24 @@ func Test2(t *testing.T) {
 		defer c.Close()
 	}
 	fs := GetFS(c, 2)
+	o := DefaultDedupOptions()
+	o.DumpFS, o.DumpDir = fs, logDir
 
 	lg("took1 %4.2v secs", time.Now().Sub(start).Seconds())
 
-	least3Files := FetchAndDecodeJSON(nil, URLs[0], "", lg, fs)
+	least3Files := FetchAndDecodeJSON(nil, URLs[0], "", o.NumTotal-1, lg, fs)
 
 	lg("took2 %4.2v secs", time.Now().Sub(start).Seconds())
 
-	doc := Dedup(least3Files, lg, fs)
+	ourl, err := fetch.URLFromString(URLs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Dedup(ourl, least3Files, o, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := res.Node
 
 	fNamer := domclean2.FileNamer(logDir, 0)
 	fNamer() // first call yields key
@@ -0,0 +1,38 @@
+package dedup
+
+import "testing"
+
+func TestNearDuplicates(t *testing.T) {
+
+	nav := "Home World Business Finance Economics Science Technology Culture"
+	footer := "Copyright The Economist Newspaper Limited 2015. All rights reserved. Accessibility Privacy policy Cookies info Terms of use"
+	tt := func(src, outl, txt string) *TextifiedTree {
+		return &TextifiedTree{SourceID: src, Outline: outl, Text: []byte(txt)}
+	}
+
+	mp := map[string][]*TextifiedTree{
+		"outp_000": {
+			tt("outp_000", "1", nav),
+			tt("outp_000", "2", "Gender equality is good for economic growth. In Latin America, women entered the labour market in great numbers."),
+			tt("outp_000", "3", footer),
+		},
+		"outp_001": {
+			tt("outp_001", "1", nav),
+			tt("outp_001", "2", "Making Britain make things again is proving difficult; productivity growth in manufacturing has stalled."),
+			tt("outp_001", "4", footer+" Contact us"),
+		},
+		"outp_002": {
+			tt("outp_002", "1", nav),
+			tt("outp_002", "2", "The refugee crisis divides Europe; the distribution among member states remains disputed."),
+		},
+	}
+
+	skip := nearDuplicates(mp, "outp_000", 2)
+	if !skip["1."] || skip["2."] || skip["3."] || len(skip) != 1 {
+		t.Errorf("in two others: %v", skip)
+	}
+	skip = nearDuplicates(mp, "outp_000", 1)
+	if !skip["1."] || !skip["3."] || skip["2."] {
+		t.Errorf("in one other: %v", skip)
+	}
+}
@@ -0,0 +1,85 @@
+package dedup
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pbberlin/tools/net/http/repo"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+	"golang.org/x/net/html"
+)
+
+func TestDedupResult(t *testing.T) {
+
+	nav := "<div><a href='/'>Home</a> <a href='/world'>World</a> <a href='/business'>Business</a> <a href='/finance'>Finance</a> <a href='/science'>Science and technology</a></div>"
+	footer := "<div><p>Copyright The Economist Newspaper Limited 2015. All rights reserved. Accessibility Privacy policy Cookies info Terms of use</p></div>"
+	texts := []string{
+		"Gender equality is good for economic growth. In Latin America, women entered the labour market in great numbers over decades.",
+		"Making Britain make things again is proving difficult; productivity growth in manufacturing has stalled for years now.",
+		"The refugee crisis divides Europe; the distribution among member states remains disputed by many governments.",
+	}
+	arts := []repo.FullArticle{}
+	for i, txt := range texts {
+		body := "<html><head></head><body>" + nav + "<div><p>" + txt + "</p></div>" + footer + "</body></html>"
+		arts = append(arts, repo.FullArticle{Url: fmt.Sprintf("www.economist.com/news/a%v", i), Body: []byte(body)})
+	}
+	ourl, _ := url.Parse("http://www.economist.com/news/a0")
+	lg := func(a ...interface{}) {}
+
+	for _, minJaccard := range []float64{minJaccard, 0} {
+
+		fs := memfs.New()
+		o := DefaultDedupOptions()
+		o.MinJaccard = minJaccard
+		o.DumpFS, o.DumpDir = fs, "/dump"
+
+		res, err := Dedup(ourl, arts, o, lg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		html.Render(&b, res.Node)
+		if !strings.Contains(b.String(), "Gender equality") || strings.Contains(b.String(), "Finance") {
+			t.Errorf("jaccard %v: deduped\n%v", minJaccard, b.String())
+		}
+
+		if len(res.Removed) == 0 {
+			t.Errorf("jaccard %v: nav and footer not reported", minJaccard)
+		}
+		for _, rm := range res.Removed {
+			if (rm.Stage == 0) != (minJaccard > 0) {
+				t.Errorf("jaccard %v: stage %v", minJaccard, rm.Stage)
+			}
+			others := map[string]bool{}
+			for _, m := range rm.Matches {
+				others[m.URL] = true
+			}
+			if len(others) != 2 || others[arts[0].Url] {
+				t.Errorf("jaccard %v: %v matched in %v", minJaccard, rm.Outline, others)
+			}
+		}
+
+		if fis, _ := fs.ReadDir("/dump"); len(fis) == 0 {
+			t.Errorf("jaccard %v: no stages dumped", minJaccard)
+		}
+	}
+
+	// without DumpDir, nothing is written
+	o := DefaultDedupOptions()
+	o.DumpFS = memfs.New()
+	if _, err := Dedup(ourl, arts, o, lg); err != nil {
+		t.Fatal(err)
+	}
+	if fis, _ := o.DumpFS.ReadDir("/"); len(fis) != 0 {
+		t.Errorf("dumped without DumpDir: %v", len(fis))
+	}
+
+	o.NumTotal = 4
+	if _, err := Dedup(ourl, arts, o, lg); err == nil {
+		t.Errorf("want error for too few documents")
+	}
+}
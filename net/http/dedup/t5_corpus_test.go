@@ -0,0 +1,87 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+const footerText = "Copyright 2015 The Economist Newspaper Limited. All rights reserved. Accessibility Privacy policy Cookies info Terms of use Help"
+
+func corpusDoc(i int) []*TextifiedTree {
+	return []*TextifiedTree{
+		{Outline: "1.1", Lvl: 1, Text: []byte(fmt.Sprintf("article %v reports on subject %v with details %v, quotes %v and a conclusion %v of its own", i, i*3, i*7, i*11, i*13))},
+		{Outline: "1.2", Lvl: 1, Text: []byte(footerText)},
+	}
+}
+
+func TestCorpus(t *testing.T) {
+
+	fs := memfs.New()
+	c, err := OpenCorpus(fs, "/_corpus/www.economist.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		c.Add(fmt.Sprintf("www.economist.com/news/%v", i), corpusDoc(i))
+	}
+	c.Add("www.economist.com/news/0", corpusDoc(0)) // replaces
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = OpenCorpus(fs, "/_corpus/www.economist.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Docs() != 6 || c.idx.Len() != 12 {
+		t.Fatalf("reloaded %v docs, %v fragments", c.Docs(), c.idx.Len())
+	}
+
+	skip := map[string]bool{}
+	rms := c.weed("www.economist.com/news/99", corpusDoc(99), 5, skip)
+	if len(rms) != 1 || rms[0].Outline != "1.2" || len(rms[0].Matches) != 6 || !skip["1.2."] {
+		t.Fatalf("want the footer removed; got %+v", rms)
+	}
+	if m := rms[0].Matches[0]; m.Similarity < 0.9 || m.Jaccard != 0 {
+		t.Errorf("simhash similarity %+v", m)
+	}
+	if rms := c.weed("www.economist.com/news/99", corpusDoc(99), 7, map[string]bool{}); len(rms) != 0 {
+		t.Errorf("footer is in 6 documents only; got %+v", rms)
+	}
+}
+
+func TestCorpusShards(t *testing.T) {
+
+	fs := memfs.New()
+	c, _ := OpenCorpus(fs, "/_corpus/www.a.com")
+	for i := 0; i < corpusMaxDocs+10; i++ {
+		c.Add(fmt.Sprintf("www.a.com/%v", i), corpusDoc(i))
+	}
+	if c.Docs() != corpusMaxDocs || c.docs["www.a.com/0"] != nil {
+		t.Errorf("not bounded: %v docs", c.Docs())
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fis, _ := fs.ReadDir("/_corpus/www.a.com"); len(fis) != corpusShards {
+		t.Errorf("want %v shards; got %v", corpusShards, len(fis))
+	}
+
+	// only the shard of the changed document is written
+	c.Add("www.a.com/new", corpusDoc(1))
+	changed := c.shardFile(shardOf("www.a.com/new"))
+	other := c.shardFile((shardOf("www.a.com/new") + 1) % corpusShards)
+	fs.Remove(changed)
+	fs.Remove(other)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(changed); err != nil {
+		t.Errorf("changed shard not written")
+	}
+	if _, err := fs.Stat(other); err == nil {
+		t.Errorf("unchanged shard written")
+	}
+}
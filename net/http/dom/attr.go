@@ -0,0 +1,29 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attr returns the value of attribute key, or "".
+// Works for nodes (n.Attr) and tokens (tok.Attr) alike.
+func Attr(attributes []html.Attribute, key string) string {
+	for _, a := range attributes {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// HasToken reports whether the space separated list - such as rel="..." -
+// contains token, ignoring case.
+func HasToken(list, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(list)) {
+		if t == strings.ToLower(token) {
+			return true
+		}
+	}
+	return false
+}
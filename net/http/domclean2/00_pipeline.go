@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"path/filepath"
 
+	"github.com/pbberlin/tools/net/http/assets"
 	"github.com/pbberlin/tools/net/http/loghttp"
 	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/os/osutilpb"
@@ -18,6 +19,8 @@ type CleaningOptions struct {
 	ProxyHost  string
 	RemoteHost string
 
+	Assets *assets.Localizer // stores images and stylesheets locally; nil leaves them remote
+
 	AddOutline bool
 	AddID      bool
 
@@ -113,6 +116,13 @@ func DomClean(b []byte, opt CleaningOptions) (*html.Node, error) {
 		fileDump(doc, opt.FNamer)
 	}
 
+	//
+	//
+	if opt.Assets != nil {
+		rep := opt.Assets.Localize(doc, &url.URL{Scheme: "http", Host: opt.RemoteHost})
+		lg("assets: %v localized, %v stored, %v failed", rep.Refs, rep.Stored, len(rep.Failed))
+	}
+
 	if opt.Beautify {
 		removeCommentsAndIntertagWhitespace(NdX{doc, 0})
 		reIndent(doc, 0)
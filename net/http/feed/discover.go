@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Link is a feed, announced by a HTML page.
+type Link struct {
+	URL   string // absolute
+	Type  string
+	Title string
+}
+
+var feedTypes = map[string]string{
+	"application/rss+xml":  RSS2,
+	"application/atom+xml": Atom,
+	"application/rdf+xml":  RDF,
+}
+
+// Discover returns the feeds of <link rel="alternate" type="application/rss+xml" ...>
+// in the order of the document.
+// Relative hrefs are resolved against <base href> or base.
+func Discover(b []byte, base *url.URL) []Link {
+
+	var links []Link
+	seen := map[string]bool{}
+
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
+			continue
+		}
+
+		tok := z.Token()
+		switch tok.DataAtom {
+		case atom.Body:
+			return links // feeds are announced in the head
+		case atom.Base:
+			if href := dom.Attr(tok.Attr, "href"); href != "" && base != nil {
+				if u, err := base.Parse(href); err == nil {
+					base = u
+				}
+			}
+		case atom.Link:
+			typ := strings.ToLower(strings.TrimSpace(dom.Attr(tok.Attr, "type")))
+			if _, ok := feedTypes[typ]; !ok || !dom.HasToken(dom.Attr(tok.Attr, "rel"), "alternate") {
+				continue
+			}
+			href := strings.TrimSpace(dom.Attr(tok.Attr, "href"))
+			if href == "" {
+				continue
+			}
+			if base != nil {
+				u, err := base.Parse(href)
+				if err != nil {
+					continue
+				}
+				href = u.String()
+			}
+			if seen[href] {
+				continue
+			}
+			seen[href] = true
+			links = append(links, Link{URL: href, Type: typ, Title: dom.Attr(tok.Attr, "title")})
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Package feed parses RSS 2.0, RSS 1.0 (RDF) and Atom 1.0
+// into one common model.
+//
+// Extensions are recognized by local name only:
+// dc:creator, dc:date, dc:subject and content:encoded.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	RSS2 = "rss2.0"
+	RDF  = "rss1.0"
+	Atom = "atom1.0"
+)
+
+type Feed struct {
+	Format  string // RSS2, RDF or Atom
+	Title   string
+	Link    string // the website
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	Title       string
+	Link        string
+	GUID        string // RSS guid, RDF about, Atom id
+	NoPermaLink bool   // the GUID looks like a URL, but cannot be fetched; written only
+	Published   time.Time
+	Updated     time.Time
+	Author      string
+	Categories  []string
+	Enclosures  []Enclosure
+	Summary     string
+	Content     string
+}
+
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// Mod is the update date, else the publication date - like meta.Meta.Mod.
+func (it Item) Mod() time.Time {
+	if it.Updated.IsZero() {
+		return it.Published
+	}
+	return it.Updated
+}
+
+// Parse detects the format by the root element.
+func Parse(b []byte) (*Feed, error) {
+
+	root, err := rootElement(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		raw := xmlRSS{}
+		if err := decoder(b).Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw.feed(), nil
+	case "RDF":
+		raw := xmlRDF{}
+		if err := decoder(b).Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw.feed(), nil
+	case "feed":
+		raw := xmlAtom{}
+		if err := decoder(b).Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw.feed(), nil
+	}
+	return nil, fmt.Errorf("no feed; root element is %q", root)
+}
+
+// decoder reads feeds in any encoding, i.e. ISO-8859-1,
+// which fetch leaves untranscoded.
+func decoder(b []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.CharsetReader = charset.NewReaderLabel
+	return dec
+}
+
+func rootElement(b []byte) (string, error) {
+	dec := decoder(b)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
+
+// RSS 2.0 and RDF share the element names;
+// Atom elements in RSS - atom:link mostly - must not interfere.
+type xmlLink struct {
+	XMLName xml.Name
+	Href    string `xml:"href,attr"`
+	Rel     string `xml:"rel,attr"`
+	Type    string `xml:"type,attr"`
+	Length  int64  `xml:"length,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// plainLink returns the first link without namespace.
+func plainLink(links []xmlLink) string {
+	for _, l := range links {
+		if l.XMLName.Space == "" || l.XMLName.Space == nsRSS1 {
+			if s := strings.TrimSpace(l.Text); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+const nsRSS1 = "http://purl.org/rss/1.0/"
+
+type xmlRSSItem struct {
+	Title       string    `xml:"title"`
+	Links       []xmlLink `xml:"link"`
+	GUID        string    `xml:"guid"`
+	About       string    `xml:"about,attr"` // rdf:about
+	Description string    `xml:"description"`
+	Categories  []string  `xml:"category"`
+	Subjects    []string  `xml:"subject"` // dc:subject
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"date"` // dc:date
+	Author      string    `xml:"author"`
+	Creator     string    `xml:"creator"` // dc:creator
+	Enclosures  []struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Encoded string `xml:"encoded"` // content:encoded
+}
+
+func (x xmlRSSItem) item() Item {
+	it := Item{
+		Title:     strings.TrimSpace(x.Title),
+		Link:      plainLink(x.Links),
+		GUID:      strings.TrimSpace(x.GUID),
+		Published: ParseTime(x.PubDate),
+		Author:    strings.TrimSpace(x.Author),
+		Summary:   strings.TrimSpace(x.Description),
+		Content:   strings.TrimSpace(x.Encoded),
+	}
+	if it.GUID == "" {
+		it.GUID = x.About
+	}
+	if it.Published.IsZero() {
+		it.Published = ParseTime(x.Date)
+	}
+	if it.Author == "" {
+		it.Author = strings.TrimSpace(x.Creator)
+	}
+	for _, c := range append(x.Categories, x.Subjects...) {
+		if c = strings.TrimSpace(c); c != "" {
+			it.Categories = append(it.Categories, c)
+		}
+	}
+	for _, e := range x.Enclosures {
+		it.Enclosures = append(it.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: e.Length})
+	}
+	return it
+}
+
+type xmlChannel struct {
+	Title         string       `xml:"title"`
+	Links         []xmlLink    `xml:"link"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	PubDate       string       `xml:"pubDate"`
+	Date          string       `xml:"date"`
+	Items         []xmlRSSItem `xml:"item"`
+}
+
+func (c xmlChannel) feed(format string, items []xmlRSSItem) *Feed {
+	f := &Feed{Format: format, Title: strings.TrimSpace(c.Title), Link: plainLink(c.Links)}
+	for _, s := range []string{c.LastBuildDate, c.PubDate, c.Date} {
+		if f.Updated = ParseTime(s); !f.Updated.IsZero() {
+			break
+		}
+	}
+	for _, x := range items {
+		f.Items = append(f.Items, x.item())
+	}
+	return f
+}
+
+type xmlRSS struct {
+	Channel xmlChannel `xml:"channel"`
+}
+
+func (x xmlRSS) feed() *Feed { return x.Channel.feed(RSS2, x.Channel.Items) }
+
+// RDF items are siblings of the channel.
+type xmlRDF struct {
+	Channel xmlChannel   `xml:"channel"`
+	Items   []xmlRSSItem `xml:"item"`
+}
+
+func (x xmlRDF) feed() *Feed { return x.Channel.feed(RDF, x.Items) }
+
+type xmlAtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",innerxml"`
+	Text string `xml:",chardata"`
+}
+
+// String returns markup for types html and xhtml, else plain text.
+func (t xmlAtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Body)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+type xmlAtomEntry struct {
+	Title     xmlAtomText `xml:"title"`
+	Links     []xmlLink   `xml:"link"`
+	ID        string      `xml:"id"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	Summary xmlAtomText `xml:"summary"`
+	Content xmlAtomText `xml:"content"`
+}
+
+type xmlAtom struct {
+	Title   xmlAtomText `xml:"title"`
+	Links   []xmlLink   `xml:"link"`
+	Updated string      `xml:"updated"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Entries []xmlAtomEntry `xml:"entry"`
+}
+
+// alternate returns the rel="alternate" link; rel defaults to alternate.
+func alternate(links []xmlLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+func (x xmlAtom) feed() *Feed {
+	f := &Feed{
+		Format:  Atom,
+		Title:   x.Title.String(),
+		Link:    alternate(x.Links),
+		Updated: ParseTime(x.Updated),
+	}
+	feedAuthor := ""
+	if len(x.Authors) > 0 {
+		feedAuthor = strings.TrimSpace(x.Authors[0].Name)
+	}
+	for _, e := range x.Entries {
+		it := Item{
+			Title:     e.Title.String(),
+			Link:      alternate(e.Links),
+			GUID:      strings.TrimSpace(e.ID),
+			Published: ParseTime(e.Published),
+			Updated:   ParseTime(e.Updated),
+			Author:    feedAuthor,
+			Summary:   e.Summary.String(),
+			Content:   e.Content.String(),
+		}
+		if len(e.Authors) > 0 {
+			it.Author = strings.TrimSpace(e.Authors[0].Name)
+		}
+		for _, c := range e.Categories {
+			if c.Term != "" {
+				it.Categories = append(it.Categories, c.Term)
+			}
+		}
+		for _, l := range e.Links {
+			if l.Rel == "enclosure" {
+				it.Enclosures = append(it.Enclosures, Enclosure{URL: l.Href, Type: l.Type, Length: l.Length})
+			}
+		}
+		f.Items = append(f.Items, it)
+	}
+	return f
+}
+
+// RFC 822 as found in the wild, and RFC 3339 for Atom and dc:date
+var layouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime returns zero time for unknown formats.
+func ParseTime(s string) time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return time.Time{}
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,257 @@
+package feed
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rss2 = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Europe</title>
+	<atom:link href="http://www.example.com/rss.xml" rel="self" type="application/rss+xml"/>
+	<link>http://www.example.com/</link>
+	<lastBuildDate>Sun, 4 Oct 2015 18:30:02 +0000</lastBuildDate>
+	<item>
+		<title>Refugees</title>
+		<link>http://www.example.com/news/europe/a1</link>
+		<guid isPermaLink="false">a1</guid>
+		<pubDate>Sun, 04 Oct 2015 18:30:02 GMT</pubDate>
+		<dc:creator>Charlemagne</dc:creator>
+		<category>Europe</category>
+		<category>Migration</category>
+		<enclosure url="http://www.example.com/a1.mp3" type="audio/mpeg" length="1024"/>
+		<content:encoded><![CDATA[<p>Body</p>]]></content:encoded>
+	</item>
+</channel>
+</rss>`
+
+const rdf = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="http://www.example.com/rdf">
+		<title>Politik</title>
+		<link>http://www.example.com/</link>
+		<dc:date>2015-10-04T10:00:00+02:00</dc:date>
+	</channel>
+	<item rdf:about="http://www.example.com/politik/p1">
+		<title>Wahl</title>
+		<link>http://www.example.com/politik/p1</link>
+		<dc:date>2015-10-04T09:00:00+02:00</dc:date>
+		<dc:subject>Inland</dc:subject>
+	</item>
+	<item rdf:about="http://www.example.com/politik/p2">
+		<title>Koalition</title>
+		<link>http://www.example.com/politik/p2</link>
+	</item>
+</rdf:RDF>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title type="text">Blog</title>
+	<link href="http://www.example.com/atom.xml" rel="self"/>
+	<link href="http://www.example.com/"/>
+	<updated>2015-10-05T12:00:00Z</updated>
+	<author><name>Editor</name></author>
+	<entry>
+		<title type="html">A &amp;lt;b&amp;gt;bold&amp;lt;/b&amp;gt; claim</title>
+		<link rel="alternate" href="http://www.example.com/blog/e1"/>
+		<link rel="enclosure" href="http://www.example.com/e1.pdf" type="application/pdf" length="2048"/>
+		<id>urn:uuid:1225c695</id>
+		<updated>2015-10-05T12:00:00Z</updated>
+		<category term="opinion"/>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Text</div></content>
+	</entry>
+</feed>`
+
+func TestParseRSS2(t *testing.T) {
+	f, err := Parse([]byte(rss2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Format != RSS2 || f.Link != "http://www.example.com/" || f.Updated.IsZero() || len(f.Items) != 1 {
+		t.Fatalf("got %+v", f)
+	}
+	it := f.Items[0]
+	if it.Link != "http://www.example.com/news/europe/a1" || it.GUID != "a1" || it.Author != "Charlemagne" {
+		t.Errorf("got %+v", it)
+	}
+	if !it.Mod().Equal(time.Date(2015, 10, 4, 18, 30, 2, 0, time.UTC)) {
+		t.Errorf("pubDate %v", it.Published)
+	}
+	if len(it.Categories) != 2 || len(it.Enclosures) != 1 || it.Enclosures[0].Length != 1024 {
+		t.Errorf("categories %v, enclosures %v", it.Categories, it.Enclosures)
+	}
+	if it.Content != "<p>Body</p>" {
+		t.Errorf("content %q", it.Content)
+	}
+}
+
+func TestParseRDF(t *testing.T) {
+	f, err := Parse([]byte(rdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Format != RDF || f.Title != "Politik" || f.Updated.IsZero() || len(f.Items) != 2 {
+		t.Fatalf("got %+v", f)
+	}
+	it := f.Items[0]
+	if it.GUID != "http://www.example.com/politik/p1" || it.Published.IsZero() || len(it.Categories) != 1 {
+		t.Errorf("got %+v", it)
+	}
+	if f.Items[1].Link != "http://www.example.com/politik/p2" {
+		t.Errorf("got %+v", f.Items[1])
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	f, err := Parse([]byte(atomFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Format != Atom || f.Link != "http://www.example.com/" || f.Title != "Blog" || len(f.Items) != 1 {
+		t.Fatalf("got %+v", f)
+	}
+	it := f.Items[0]
+	if it.Link != "http://www.example.com/blog/e1" || it.Author != "Editor" || it.GUID != "urn:uuid:1225c695" {
+		t.Errorf("got %+v", it)
+	}
+	if it.Title != "A &lt;b&gt;bold&lt;/b&gt; claim" {
+		t.Errorf("title %q", it.Title)
+	}
+	if it.Mod().IsZero() || len(it.Enclosures) != 1 || len(it.Categories) != 1 {
+		t.Errorf("got %+v", it)
+	}
+	if it.Content != `<div xmlns="http://www.w3.org/1999/xhtml">Text</div>` {
+		t.Errorf("content %q", it.Content)
+	}
+
+	_, err = Parse([]byte(`<html><head></head></html>`))
+	if err == nil {
+		t.Errorf("html accepted as feed")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	page := `<!DOCTYPE html><html><head>
+		<base href="/sub/">
+		<link rel="stylesheet" type="text/css" href="s.css">
+		<link rel="alternate" type="application/rss+xml" title="News" href="feeds/news.xml">
+		<link rel="Alternate home" type="application/atom+xml" href="http://other.example.com/atom">
+		<link rel="alternate" type="application/rss+xml" href="feeds/news.xml">
+		<link rel="alternate" hreflang="de" href="/de/">
+	</head><body>
+		<link rel="alternate" type="application/rss+xml" href="/ignored.xml">
+	</body></html>`
+
+	base, _ := url.Parse("http://www.example.com/politik/index.html")
+	links := Discover([]byte(page), base)
+	if len(links) != 2 {
+		t.Fatalf("want 2 feeds; got %+v", links)
+	}
+	if links[0].URL != "http://www.example.com/sub/feeds/news.xml" || links[0].Title != "News" {
+		t.Errorf("got %+v", links[0])
+	}
+	if links[1].URL != "http://other.example.com/atom" || links[1].Type != "application/atom+xml" {
+		t.Errorf("got %+v", links[1])
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+
+	pub := time.Date(2015, 10, 4, 18, 30, 2, 0, time.UTC)
+	f := &Feed{
+		Title:   "Europe & more",
+		Link:    "http://www.example.com/news/europe",
+		Updated: pub,
+		Items: []Item{
+			{
+				Title:      "Refugees",
+				Link:       "http://www.example.com/news/europe/a1",
+				GUID:       "http://www.example.com/news/europe/a1",
+				Published:  pub,
+				Author:     "Charlemagne",
+				Categories: []string{"Europe"},
+				Content:    "<p>Body &amp; soul</p>",
+			},
+			{Title: "Journey", Link: "http://www.example.com/news/europe/a2"},
+		},
+	}
+
+	for _, format := range []string{RSS2, Atom} {
+		var b []byte
+		var err error
+		if format == RSS2 {
+			b, err = f.RSS2("http://feeds.example.com/europe.rss")
+		} else {
+			b, err = f.Atom("http://feeds.example.com/europe.atom")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := Parse(b)
+		if err != nil {
+			t.Fatalf("%v: %v\n%s", format, err, b)
+		}
+		if g.Format != format || g.Title != f.Title || g.Link != f.Link || !g.Updated.Equal(pub) || len(g.Items) != 2 {
+			t.Fatalf("%v: got %+v\n%s", format, g, b)
+		}
+		it := g.Items[0]
+		if it.Link != f.Items[0].Link || it.GUID != f.Items[0].GUID || it.Author != "Charlemagne" || !it.Mod().Equal(pub) {
+			t.Errorf("%v: got %+v", format, it)
+		}
+		if it.Content != f.Items[0].Content || len(it.Categories) != 1 {
+			t.Errorf("%v: content %q, categories %v", format, it.Content, it.Categories)
+		}
+		if it := g.Items[1]; it.GUID != "http://www.example.com/news/europe/a2" {
+			t.Errorf("%v: guid defaults to link; got %q", format, it.GUID)
+		}
+	}
+}
+
+func TestWriteAttributes(t *testing.T) {
+	f := &Feed{Title: "t", Items: []Item{
+		{Title: "a", GUID: "http://www.example.com/news/a1"},
+		{Title: "b", GUID: "http://www.example.com/news", NoPermaLink: true, Summary: "Tom & Jerry"},
+	}}
+	b, _ := f.RSS2("http://feeds.example.com/t.rss")
+	if s := string(b); !strings.Contains(s, `isPermaLink="true">http://www.example.com/news/a1<`) ||
+		!strings.Contains(s, `isPermaLink="false">http://www.example.com/news<`) {
+		t.Errorf("permalinks\n%s", b)
+	}
+	b, _ = f.Atom("http://feeds.example.com/t.atom")
+	if !strings.Contains(string(b), `<summary type="text">Tom &amp; Jerry</summary>`) {
+		t.Errorf("summary\n%s", b)
+	}
+}
+
+func TestItemMod(t *testing.T) {
+	pub := time.Date(2015, 10, 4, 18, 30, 2, 0, time.UTC)
+	upd := pub.Add(3 * time.Hour)
+	if it := (Item{Published: pub}); !it.Mod().Equal(pub) {
+		t.Errorf("published only: %v", it.Mod())
+	}
+	if it := (Item{Published: pub, Updated: upd}); !it.Mod().Equal(upd) || !it.pubDate().Equal(pub) {
+		t.Errorf("updated: mod %v, pubDate %v", it.Mod(), it.pubDate())
+	}
+}
+
+func TestParseLatin1(t *testing.T) {
+	latin1 := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<rss version=\"2.0\"><channel><title>Schlagzeilen</title>" +
+		"<item><title>Gr\xfc\xdfe aus K\xf6ln</title><link>http://www.handelsblatt.com/a1</link></item>" +
+		"</channel></rss>"
+	f, err := Parse([]byte(latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Items) != 1 || f.Items[0].Title != "Grüße aus Köln" {
+		t.Errorf("got %+v", f.Items)
+	}
+}
@@ -0,0 +1,221 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+const (
+	nsAtom    = "http://www.w3.org/2005/Atom"
+	nsContent = "http://purl.org/rss/1.0/modules/content/"
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+)
+
+type outLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type outGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+type outEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+type outRSSItem struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	GUID        outGUID        `xml:"guid"`
+	PubDate     string         `xml:"pubDate,omitempty"`
+	Creator     string         `xml:"dc:creator,omitempty"`
+	Categories  []string       `xml:"category"`
+	Enclosures  []outEnclosure `xml:"enclosure"`
+	Description string         `xml:"description,omitempty"`
+	Content     string         `xml:"content:encoded,omitempty"`
+}
+
+type outRSS struct {
+	XMLName   xml.Name `xml:"rss"`
+	Version   string   `xml:"version,attr"`
+	NsAtom    string   `xml:"xmlns:atom,attr"`
+	NsContent string   `xml:"xmlns:content,attr"`
+	NsDC      string   `xml:"xmlns:dc,attr"`
+	Channel   struct {
+		Title         string       `xml:"title"`
+		Link          string       `xml:"link"`
+		Description   string       `xml:"description"`
+		Self          outLink      `xml:"atom:link"`
+		LastBuildDate string       `xml:"lastBuildDate,omitempty"`
+		Items         []outRSSItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// RSS2 renders the feed as RSS 2.0.
+// self is the URL, under which the feed is served.
+// Item content goes into content:encoded, the summary into description;
+// GUIDs, which are URLs, are marked as permalinks - unless NoPermaLink.
+func (f *Feed) RSS2(self string) ([]byte, error) {
+
+	x := outRSS{Version: "2.0", NsAtom: nsAtom, NsContent: nsContent, NsDC: nsDC}
+	c := &x.Channel
+	c.Title = f.Title
+	c.Link = f.Link
+	c.Description = f.Title
+	c.Self = outLink{Href: self, Rel: "self", Type: "application/rss+xml"}
+	c.LastBuildDate = rfc822(f.Updated)
+
+	for _, it := range f.Items {
+		guid := it.GUID
+		if guid == "" {
+			guid = it.Link
+		}
+		xi := outRSSItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			GUID:        outGUID{ID: guid, IsPermaLink: isURL(guid) && !it.NoPermaLink},
+			PubDate:     rfc822(it.pubDate()),
+			Creator:     it.Author,
+			Categories:  it.Categories,
+			Description: it.Summary,
+			Content:     it.Content,
+		}
+		for _, e := range it.Enclosures {
+			xi.Enclosures = append(xi.Enclosures, outEnclosure{URL: e.URL, Type: e.Type, Length: e.Length})
+		}
+		c.Items = append(c.Items, xi)
+	}
+	return marshal(x)
+}
+
+type outAtomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type outCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type outAtomEntry struct {
+	Title      string        `xml:"title"`
+	ID         string        `xml:"id"`
+	Links      []outLink     `xml:"link"`
+	Published  string        `xml:"published,omitempty"`
+	Updated    string        `xml:"updated"`
+	Authors    []string      `xml:"author>name"`
+	Categories []outCategory `xml:"category"`
+	Summary    *outAtomText  `xml:"summary"`
+	Content    *outAtomText  `xml:"content"`
+}
+
+type outAtom struct {
+	XMLName xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string         `xml:"title"`
+	ID      string         `xml:"id"`
+	Updated string         `xml:"updated"`
+	Links   []outLink      `xml:"link"`
+	Authors []string       `xml:"author>name"`
+	Entries []outAtomEntry `xml:"entry"`
+}
+
+// Atom renders the feed as Atom 1.0.
+// self is the URL, under which the feed is served; it is also the feed id.
+// Atom requires an author; the feed title stands in.
+func (f *Feed) Atom(self string) ([]byte, error) {
+
+	x := outAtom{
+		Title:   f.Title,
+		ID:      self,
+		Updated: rfc3339(f.Updated),
+		Links:   []outLink{{Href: self, Rel: "self", Type: "application/atom+xml"}},
+		Authors: []string{f.Title},
+	}
+	if f.Link != "" {
+		x.Links = append(x.Links, outLink{Href: f.Link, Rel: "alternate", Type: "text/html"})
+	}
+
+	for _, it := range f.Items {
+		e := outAtomEntry{
+			Title:     it.Title,
+			ID:        it.GUID,
+			Published: rfc3339(it.Published),
+			Updated:   rfc3339(it.Updated),
+		}
+		if e.ID == "" {
+			e.ID = it.Link
+		}
+		if e.Updated == "" {
+			e.Updated = rfc3339(it.Mod())
+		}
+		if e.Updated == "" {
+			e.Updated = x.Updated
+		}
+		if it.Link != "" {
+			e.Links = append(e.Links, outLink{Href: it.Link, Rel: "alternate", Type: "text/html"})
+		}
+		for _, enc := range it.Enclosures {
+			e.Links = append(e.Links, outLink{Href: enc.URL, Rel: "enclosure", Type: enc.Type, Length: enc.Length})
+		}
+		if it.Author != "" {
+			e.Authors = []string{it.Author}
+		}
+		for _, c := range it.Categories {
+			e.Categories = append(e.Categories, outCategory{Term: c})
+		}
+		if it.Summary != "" {
+			e.Summary = &outAtomText{Type: "text", Body: it.Summary}
+		}
+		if it.Content != "" {
+			e.Content = &outAtomText{Type: "html", Body: it.Content}
+		}
+		x.Entries = append(x.Entries, e)
+	}
+	return marshal(x)
+}
+
+func marshal(x interface{}) ([]byte, error) {
+	b := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(b)
+	enc.Indent("", "\t")
+	if err := enc.Encode(x); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func rfc822(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC1123Z)
+}
+
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// pubDate is the publication date, else the update date;
+// RSS 2.0 items have no update date of their own.
+func (it Item) pubDate() time.Time {
+	if it.Published.IsZero() {
+		return it.Updated
+	}
+	return it.Published
+}
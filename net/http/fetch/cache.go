@@ -0,0 +1,187 @@
+package fetch
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Cache stores GET responses in any fsi.FileSystem.
+// It is a private cache in the sense of RFC 7234:
+// s-maxage and public/private are ignored.
+//
+// Each response is stored as two files below Dir/host/:
+// a JSON header file and the body.
+// Both can be swept by package expiry.
+type Cache struct {
+	FS  fsi.FileSystem
+	Dir string
+
+	// Heuristic freshness, if the response has neither max-age nor Expires,
+	// is a tenth of the age of Last-Modified - capped by MaxHeuristic.
+	MaxHeuristic time.Duration
+}
+
+func NewCache(fs fsi.FileSystem, dir string) *Cache {
+	return &Cache{FS: fs, Dir: dir, MaxHeuristic: 24 * time.Hour}
+}
+
+// cacheEntry is the JSON header file.
+type cacheEntry struct {
+	URL          string
+	Stored       time.Time // response time
+	Expires      time.Time // zero => must revalidate
+	NoCache      bool
+	ETag         string
+	LastModified string
+	Mod          time.Time
+	ContentType  string
+
+	body []byte
+}
+
+// key is based on the normalized URL;
+// tracking params and the like do not multiply entries.
+func (c *Cache) key(u *url.URL) string {
+	nu := urlnorm.Std.NormalizeURL(u)
+	h := sha1.Sum([]byte(nu.String()))
+	return path.Join(c.Dir, nu.Host, fmt.Sprintf("%x", h[:10]))
+}
+
+// lookup returns nil, if the URL is not cached.
+func (c *Cache) lookup(u *url.URL) *cacheEntry {
+	k := c.key(u)
+	bts, err := c.FS.ReadFile(k + ".json")
+	if err != nil {
+		return nil
+	}
+	e := &cacheEntry{}
+	err = json.Unmarshal(bts, e)
+	if err != nil || e.URL != urlnorm.Std.NormalizeURL(u).String() {
+		return nil
+	}
+	e.body, err = c.FS.ReadFile(k + ".body")
+	if err != nil {
+		return nil
+	}
+	return e
+}
+
+// store saves a 200 response.
+// Responses with no-store are not saved.
+func (c *Cache) store(u *url.URL, hdr http.Header, body []byte, mod time.Time) error {
+	e := &cacheEntry{URL: urlnorm.Std.NormalizeURL(u).String(), Mod: mod, ContentType: hdr.Get("Content-Type"), body: body}
+	if !e.update(hdr, time.Now(), c.MaxHeuristic) {
+		return nil
+	}
+	k := c.key(u)
+	err := common.WriteFile(c.FS, k+".body", body)
+	if err != nil {
+		return err
+	}
+	return c.saveEntry(k, e)
+}
+
+// refresh applies the headers of a 304 response.
+func (c *Cache) refresh(u *url.URL, e *cacheEntry, hdr http.Header) error {
+	if !e.update(hdr, time.Now(), c.MaxHeuristic) {
+		return nil
+	}
+	return c.saveEntry(c.key(u), e)
+}
+
+func (c *Cache) saveEntry(k string, e *cacheEntry) error {
+	bts, err := json.MarshalIndent(e, "", "\t")
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(c.FS, k+".json", bts)
+}
+
+// update takes validators and freshness from response headers.
+// It returns false for no-store.
+func (e *cacheEntry) update(hdr http.Header, now time.Time, maxHeuristic time.Duration) bool {
+
+	cc := parseCacheControl(hdr.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	_, e.NoCache = cc["no-cache"]
+
+	// a 304 may omit validators; keep the old ones then
+	if et := hdr.Get("ETag"); et != "" {
+		e.ETag = et
+	}
+	if lm := hdr.Get("Last-Modified"); lm != "" {
+		e.LastModified = lm
+	}
+
+	e.Stored = now
+	e.Expires = time.Time{}
+
+	date := now
+	if d, err := http.ParseTime(hdr.Get("Date")); err == nil {
+		date = d
+	}
+
+	if ma, ok := cc["max-age"]; ok {
+		if secs, err := strconv.Atoi(ma); err == nil {
+			e.Expires = now.Add(time.Duration(secs) * time.Second)
+		}
+	} else if exp := hdr.Get("Expires"); exp != "" {
+		// invalid Expires means: already expired
+		if t, err := http.ParseTime(exp); err == nil {
+			e.Expires = now.Add(t.Sub(date))
+		}
+	} else if lm, err := http.ParseTime(e.LastModified); err == nil && lm.Before(date) {
+		fresh := date.Sub(lm) / 10
+		if fresh > maxHeuristic {
+			fresh = maxHeuristic
+		}
+		e.Expires = now.Add(fresh)
+	}
+	return true
+}
+
+func (e *cacheEntry) fresh(now time.Time) bool {
+	return !e.NoCache && now.Before(e.Expires)
+}
+
+// conditional adds validators to a revalidation request.
+func (e *cacheEntry) conditional(r *http.Request) {
+	if e.ETag != "" {
+		r.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		r.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+// parseCacheControl returns directives, lower cased, with their values.
+func parseCacheControl(s string) map[string]string {
+	cc := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		k := strings.ToLower(strings.TrimSpace(kv[0]))
+		v := ""
+		if len(kv) == 2 {
+			v = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+		cc[k] = v
+	}
+	return cc
+}
@@ -0,0 +1,106 @@
+package fetch
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestCacheFreshness(t *testing.T) {
+
+	now := time.Date(2015, 8, 29, 12, 0, 0, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+
+	cases := []struct {
+		hdr   map[string]string
+		store bool
+		fresh time.Duration // zero => stale at once
+	}{
+		{map[string]string{"Cache-Control": "max-age=60"}, true, time.Minute},
+		{map[string]string{"Cache-Control": "public, max-age=60", "Expires": "Sat, 01 Jan 2000 00:00:00 GMT"}, true, time.Minute},
+		{map[string]string{"Cache-Control": "no-store"}, false, 0},
+		{map[string]string{"Cache-Control": "no-cache, max-age=60"}, true, 0},
+		{map[string]string{"Date": date, "Expires": now.Add(time.Hour).Format(http.TimeFormat)}, true, time.Hour},
+		{map[string]string{"Expires": "0"}, true, 0},
+		{map[string]string{"Date": date, "Last-Modified": now.Add(-10 * time.Hour).Format(http.TimeFormat)}, true, time.Hour},
+		{map[string]string{}, true, 0},
+	}
+
+	for i, tc := range cases {
+		hdr := http.Header{}
+		for k, v := range tc.hdr {
+			hdr.Set(k, v)
+		}
+		e := &cacheEntry{}
+		stored := e.update(hdr, now, 24*time.Hour)
+		if stored != tc.store {
+			t.Errorf("case %v: store %v; want %v", i, stored, tc.store)
+			continue
+		}
+		if !stored {
+			continue
+		}
+		if tc.fresh == 0 {
+			if e.fresh(now) {
+				t.Errorf("case %v: should be stale", i)
+			}
+			continue
+		}
+		if !e.fresh(now.Add(tc.fresh - time.Second)) {
+			t.Errorf("case %v: should be fresh until %v", i, tc.fresh)
+		}
+		if e.fresh(now.Add(tc.fresh + time.Second)) {
+			t.Errorf("case %v: should be stale after %v", i, tc.fresh)
+		}
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+
+	c := NewCache(memfs.New(), "/httpcache")
+	u, _ := url.Parse("http://test.economist.com/someurl?x=1")
+
+	if e := c.lookup(u); e != nil {
+		t.Fatalf("empty cache yields %v", e)
+	}
+
+	hdr := http.Header{}
+	hdr.Set("ETag", `"abc"`)
+	hdr.Set("Cache-Control", "max-age=0")
+	err := c.store(u, hdr, []byte("body"), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := c.lookup(u)
+	if e == nil || string(e.body) != "body" {
+		t.Fatalf("lookup failed: %v", e)
+	}
+
+	r, _ := http.NewRequest("GET", u.String(), nil)
+	e.conditional(r)
+	if r.Header.Get("If-None-Match") != `"abc"` {
+		t.Errorf("missing validator: %v", r.Header)
+	}
+
+	// 304 without validators keeps the etag
+	hdr = http.Header{}
+	hdr.Set("Cache-Control", "max-age=60")
+	err = c.refresh(u, e, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = c.lookup(u)
+	if !e.fresh(time.Now()) || e.ETag != `"abc"` {
+		t.Errorf("refresh failed: %+v", e)
+	}
+
+	// tracking params and host case do not matter
+	u2, _ := url.Parse("http://Test.Economist.com/someurl?utm_source=x&x=1#top")
+	if e := c.lookup(u2); e == nil {
+		t.Errorf("normalized lookup failed for %v", u2)
+	}
+}
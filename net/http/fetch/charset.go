@@ -0,0 +1,58 @@
+package fetch
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+var bom = []byte("\xef\xbb\xbf")
+
+// toUTF8 transcodes html and plain text bodies.
+// The charset is taken from BOM, Content-Type header
+// and <meta charset> or http-equiv - in this order of precedence.
+// Labels are mapped as browsers do: iso-8859-1 => windows-1252.
+//
+// XML is left alone; encoding/xml requires the body
+// to match its encoding declaration.
+//
+// Without declaration, DetermineEncoding guesses windows-1252
+// for utf-8 bodies, whose first 1024 bytes are ascii;
+// valid utf-8 is therefore kept, unless the charset is certain.
+//
+// The returned name is the original charset, i.e. "windows-1252".
+func toUTF8(bts []byte, contentType string) ([]byte, string, error) {
+
+	if contentType == "" {
+		// sniffing always claims utf-8; we drop that
+		contentType = strings.Split(http.DetectContentType(bts), ";")[0]
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	if mt != "text/html" && mt != "application/xhtml+xml" && mt != "text/plain" {
+		return bts, "", nil
+	}
+
+	enc, name, certain := charset.DetermineEncoding(bts, contentType)
+	if name == "utf-8" || !certain && utf8.Valid(bts) {
+		return bytes.TrimPrefix(bts, bom), "utf-8", nil
+	}
+
+	out, err := enc.NewDecoder().Bytes(bts)
+	if err != nil {
+		return bts, name, err
+	}
+	return bytes.TrimPrefix(out, bom), name, nil // utf-16 bom becomes utf-8 bom
+}
+
+// ToUTF8 transcodes a body, which did not pass UrlGetter,
+// i.e. one restored from a WARC archive.
+func ToUTF8(bts []byte, contentType string) ([]byte, string, error) {
+	return toUTF8(bts, contentType)
+}
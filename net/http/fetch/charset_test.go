@@ -0,0 +1,43 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+
+	latin1 := []byte("Gr\xfc\xdfe aus K\xf6ln \x80") // windows-1252 euro sign
+
+	// undeclared utf-8, ascii up to byte 1024
+	lateUmlauts := "<html><body>" + strings.Repeat("ascii ", 200) + "Grüße aus Köln</body></html>"
+
+	cases := []struct {
+		body        []byte
+		contentType string
+		want        string
+		wantCharset string
+	}{
+		{latin1, "text/html; charset=ISO-8859-1", "Grüße aus Köln €", "windows-1252"},
+		{append([]byte(`<meta charset="iso-8859-1">`), latin1...), "text/html", `<meta charset="iso-8859-1">Grüße aus Köln €`, "windows-1252"},
+		{append([]byte(`<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">`), latin1...), "", `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">Grüße aus Köln €`, "windows-1252"},
+		{[]byte("\xef\xbb\xbfGrüße"), "text/html; charset=iso-8859-1", "Grüße", "utf-8"}, // bom wins
+		{[]byte("\xff\xfeG\x00r\x00\xfc\x00"), "text/plain", "Grü", "utf-16le"},
+		{[]byte("Grüße"), "text/html; charset=utf-8", "Grüße", "utf-8"},
+		{[]byte(lateUmlauts), "", lateUmlauts, "utf-8"},
+		{[]byte(lateUmlauts), "text/html", lateUmlauts, "utf-8"},
+		{latin1, "application/xml", string(latin1), ""},
+		{latin1, "image/png", string(latin1), ""},
+	}
+
+	for i, tc := range cases {
+		got, cs, err := toUTF8(tc.body, tc.contentType)
+		if err != nil {
+			t.Errorf("case %v: %v", i, err)
+			continue
+		}
+		if string(got) != tc.want || cs != tc.wantCharset {
+			t.Errorf("case %v:\ngot  %q %v\nwant %q %v", i, got, cs, tc.want, tc.wantCharset)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy for transient errors and retryable status codes.
+// The zero value means no retries.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration // first backoff; doubled for each retry
+	MaxDelay   time.Duration // cap for backoff - and for Retry-After
+
+	Status []int // retryable status codes; nil => 429 and 503
+}
+
+var DefaultRetry = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+func (p RetryPolicy) retryableStatus(code int) bool {
+	if p.Status == nil {
+		return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+	}
+	for _, c := range p.Status {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff uses "full jitter":
+// a random wait between zero and the exponential ceiling.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceil := p.BaseDelay << uint(attempt)
+	if ceil <= 0 || p.MaxDelay > 0 && ceil > p.MaxDelay {
+		ceil = p.MaxDelay
+	}
+	if ceil <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceil)))
+}
+
+// retryAfter reads delay seconds or an http date.
+func retryAfter(hdr http.Header, now time.Time) (time.Duration, bool) {
+	ra := hdr.Get("Retry-After")
+	if ra == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func transient(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	ne, ok := err.(net.Error) // *url.Error is a net.Error
+	return ok && (ne.Timeout() || ne.Temporary())
+}
+
+var ErrDeadline = errors.New("fetch deadline would pass while waiting")
+var ErrCancelled = errors.New("fetch cancelled while waiting")
+
+// sleep waits d, unless the wait would end after deadline,
+// or cancel is closed meanwhile.
+// A zero deadline and a nil cancel never interrupt.
+func sleep(d time.Duration, deadline time.Time, cancel <-chan struct{}) error {
+	if !deadline.IsZero() && time.Now().Add(d).After(deadline) {
+		return ErrDeadline
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-cancel:
+		return ErrCancelled
+	}
+}
+
+// HostLimits keep us polite towards a single host.
+type HostLimits struct {
+	MaxConcurrent int           // requests in flight; 0 => unlimited
+	MinDelay      time.Duration // between the starts of two requests
+}
+
+var DefaultHostLimits = HostLimits{MaxConcurrent: 2, MinDelay: 500 * time.Millisecond}
+
+type hostSlot struct {
+	lim  HostLimits
+	sem  chan struct{}
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+
+	// guarded by hostsMu
+	users     int       // requests between slotFor and done
+	idleSince time.Time // of the last done
+	pinned    bool      // set by SetHostLimits; never evicted
+}
+
+var hostsMu sync.Mutex
+var hosts = map[string]*hostSlot{}
+var hostsSwept = time.Now()
+
+// Slots of hosts without requests for hostIdle are evicted;
+// a crawl touches thousands of hosts once.
+const hostIdle = 10 * time.Minute
+
+// SetHostLimits overrides DefaultHostLimits for one host.
+// Requests already waiting keep the former limits.
+func SetHostLimits(host string, lim HostLimits) {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+	hs := newHostSlot(lim)
+	hs.pinned = true
+	hosts[host] = hs
+}
+
+func newHostSlot(lim HostLimits) *hostSlot {
+	hs := &hostSlot{lim: lim}
+	if lim.MaxConcurrent > 0 {
+		hs.sem = make(chan struct{}, lim.MaxConcurrent)
+	}
+	return hs
+}
+
+// slotFor must be paired with done.
+func slotFor(host string) *hostSlot {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+	now := time.Now()
+	if now.Sub(hostsSwept) > hostIdle {
+		sweepHosts(now)
+	}
+	hs, ok := hosts[host]
+	if !ok {
+		hs = newHostSlot(DefaultHostLimits)
+		hosts[host] = hs
+	}
+	hs.users++
+	return hs
+}
+
+func (hs *hostSlot) done() {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+	hs.users--
+	hs.idleSince = time.Now()
+}
+
+// sweepHosts evicts idle slots; hostsMu must be held.
+func sweepHosts(now time.Time) {
+	for host, hs := range hosts {
+		if hs.pinned || hs.users > 0 || now.Sub(hs.idleSince) < hostIdle {
+			continue
+		}
+		hs.mu.Lock()
+		paused := hs.next.After(now) // i.e. Retry-After
+		hs.mu.Unlock()
+		if !paused {
+			delete(hosts, host)
+		}
+	}
+	hostsSwept = now
+}
+
+// acquire blocks for a free slot and for MinDelay.
+// Without error, the slot must be released.
+func (hs *hostSlot) acquire(deadline time.Time, cancel <-chan struct{}) error {
+	if hs.sem != nil {
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			t := time.NewTimer(deadline.Sub(time.Now()))
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case hs.sem <- struct{}{}:
+		case <-timeout:
+			return ErrDeadline
+		case <-cancel:
+			return ErrCancelled
+		}
+	}
+	hs.mu.Lock()
+	now := time.Now()
+	start := hs.next
+	if start.Before(now) {
+		start = now
+	}
+	if !deadline.IsZero() && start.After(deadline) {
+		hs.mu.Unlock()
+		hs.release()
+		return ErrDeadline
+	}
+	hs.next = start.Add(hs.lim.MinDelay)
+	hs.mu.Unlock()
+	if err := sleep(start.Sub(now), time.Time{}, cancel); err != nil {
+		hs.release()
+		return err
+	}
+	return nil
+}
+
+func (hs *hostSlot) release() {
+	if hs.sem != nil {
+		<-hs.sem
+	}
+}
+
+// pause delays all requests to the host, i.e. after a Retry-After.
+func (hs *hostSlot) pause(d time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if t := time.Now().Add(d); t.After(hs.next) {
+		hs.next = t
+	}
+}
+
+// RateLimiter is a token bucket.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; <= 0 => unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// GlobalLimiter is shared by all fetch callers.
+// Replace it before the first fetch, or use SetRate.
+var GlobalLimiter = NewRateLimiter(20, 20)
+
+func (rl *RateLimiter) SetRate(perSecond float64, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if burst < 1 {
+		burst = 1
+	}
+	rl.rate, rl.burst = perSecond, float64(burst)
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+}
+
+// Wait blocks until a token is available and takes it.
+func (rl *RateLimiter) Wait() {
+	rl.WaitUntil(time.Time{}, nil)
+}
+
+// WaitUntil takes no token, if it would be available only after deadline.
+// A token, taken before cancel is closed, is not returned.
+func (rl *RateLimiter) WaitUntil(deadline time.Time, cancel <-chan struct{}) error {
+	rl.mu.Lock()
+	if rl.rate <= 0 {
+		rl.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+	wait := time.Duration(0)
+	if rl.tokens < 1 { // we owe the wait
+		wait = time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+	}
+	if !deadline.IsZero() && now.Add(wait).After(deadline) {
+		rl.mu.Unlock()
+		return ErrDeadline
+	}
+	rl.tokens-- // may become negative
+	rl.mu.Unlock()
+	return sleep(wait, time.Time{}, cancel)
+}
+
+// doPolite wraps client.Do with the global limiter,
+// the host limits and the retry policy.
+// The host slot is held until the response header arrives;
+// reading the body is not counted.
+// Waits ending after deadline are not begun; r.Cancel interrupts them.
+// A retry, which would end after deadline, yields the last response.
+func doPolite(client *http.Client, r *http.Request, p RetryPolicy, deadline time.Time, inf *Info) (*http.Response, error) {
+
+	// recorded responses need no politeness
+	if _, ok := client.Transport.(*ReplayTransport); ok {
+		return client.Do(r)
+	}
+
+	hs := slotFor(r.URL.Host)
+	defer hs.done()
+
+	// a consumed request body cannot be sent again
+	if r.Method != "GET" && r.Method != "HEAD" {
+		p.MaxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		if err := GlobalLimiter.WaitUntil(deadline, r.Cancel); err != nil {
+			return nil, err
+		}
+		if err := hs.acquire(deadline, r.Cancel); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(r)
+		hs.release()
+
+		if attempt >= p.MaxRetries {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil && transient(err):
+			wait = p.backoff(attempt)
+		case err == nil && p.retryableStatus(resp.StatusCode):
+			wait = p.backoff(attempt)
+			if ra, ok := retryAfter(resp.Header, time.Now()); ok {
+				if p.MaxDelay > 0 && ra > p.MaxDelay {
+					return resp, err // server wants more patience than we have
+				}
+				hs.pause(ra)
+				wait = ra
+			}
+		default:
+			return resp, err
+		}
+
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		inf.Retries++
+		if err := sleep(wait, time.Time{}, r.Cancel); err != nil {
+			return nil, err
+		}
+	}
+}
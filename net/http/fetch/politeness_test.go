@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		ceil := p.BaseDelay << uint(attempt)
+		if ceil > p.MaxDelay {
+			ceil = p.MaxDelay
+		}
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < 0 || d >= ceil {
+				t.Errorf("attempt %v: backoff %v outside [0,%v)", attempt, d, ceil)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2015, 8, 29, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"120": 2 * time.Minute,
+		now.Add(time.Hour).Format(http.TimeFormat):  time.Hour,
+		now.Add(-time.Hour).Format(http.TimeFormat): 0,
+	}
+	for v, want := range cases {
+		hdr := http.Header{}
+		hdr.Set("Retry-After", v)
+		got, ok := retryAfter(hdr, now)
+		if !ok || got != want {
+			t.Errorf("%q: got %v %v; want %v", v, got, ok, want)
+		}
+	}
+	if _, ok := retryAfter(http.Header{}, now); ok {
+		t.Errorf("missing header must not yield a delay")
+	}
+}
+
+func TestDoPolite(t *testing.T) {
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	r, _ := http.NewRequest("GET", srv.URL, nil)
+	SetHostLimits(r.URL.Host, HostLimits{MaxConcurrent: 1, MinDelay: 10 * time.Millisecond})
+
+	inf := Info{}
+	resp, err := doPolite(&http.Client{}, r, DefaultRetry, time.Time{}, &inf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || inf.Retries != 2 {
+		t.Errorf("status %v after %v retries; want 200 after 2", resp.StatusCode, inf.Retries)
+	}
+
+	// no retries => the 503 is returned
+	atomic.StoreInt32(&calls, 0)
+	resp, err = doPolite(&http.Client{}, r, RetryPolicy{}, time.Time{}, &inf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status %v; want 503", resp.StatusCode)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl := NewRateLimiter(100, 1)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		rl.Wait()
+	}
+	if el := time.Since(start); el < 40*time.Millisecond {
+		t.Errorf("6 tokens at 100/s with burst 1 took only %v", el)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r, _ := http.NewRequest("GET", srv.URL, nil)
+	SetHostLimits(r.URL.Host, HostLimits{MaxConcurrent: 1})
+
+	// the retry would end after the deadline => the 503 is returned at once
+	inf := Info{}
+	start := time.Now()
+	resp, err := doPolite(&http.Client{}, r, DefaultRetry, start.Add(500*time.Millisecond), &inf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || inf.Retries != 0 || time.Since(start) > 400*time.Millisecond {
+		t.Errorf("status %v after %v retries and %v", resp.StatusCode, inf.Retries, time.Since(start))
+	}
+
+	// the host is paused by Retry-After
+	if _, err := doPolite(&http.Client{}, r, DefaultRetry, time.Now().Add(100*time.Millisecond), &inf); err != ErrDeadline {
+		t.Errorf("got %v; want ErrDeadline", err)
+	}
+
+	rl := NewRateLimiter(1, 1)
+	rl.Wait()
+	if err := rl.WaitUntil(time.Now().Add(100*time.Millisecond), nil); err != ErrDeadline {
+		t.Errorf("limiter: got %v; want ErrDeadline", err)
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := rl.WaitUntil(time.Time{}, cancel); err != ErrCancelled {
+		t.Errorf("limiter: got %v; want ErrCancelled", err)
+	}
+}
+
+func TestSweepHosts(t *testing.T) {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+
+	now := time.Now()
+	idle, busy, pinned := newHostSlot(DefaultHostLimits), newHostSlot(DefaultHostLimits), newHostSlot(DefaultHostLimits)
+	idle.idleSince = now.Add(-2 * hostIdle)
+	busy.idleSince, busy.users = idle.idleSince, 1
+	pinned.idleSince, pinned.pinned = idle.idleSince, true
+	hosts["idle.example"], hosts["busy.example"], hosts["pinned.example"] = idle, busy, pinned
+
+	sweepHosts(now)
+	if _, ok := hosts["idle.example"]; ok {
+		t.Errorf("idle host kept")
+	}
+	if hosts["busy.example"] != busy || hosts["pinned.example"] != pinned {
+		t.Errorf("busy or pinned host evicted")
+	}
+	delete(hosts, "busy.example")
+	delete(hosts, "pinned.example")
+}
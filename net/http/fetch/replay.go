@@ -0,0 +1,330 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/warc"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+var ErrNoRecording = fmt.Errorf("no recorded response")
+
+// Exchange is a recorded request/response pair.
+// It is stored as JSON; the body is stored next to it.
+type Exchange struct {
+	Method   string
+	URL      string
+	Status   int
+	Header   http.Header
+	Recorded time.Time
+}
+
+// MatchRule decides, which requests are considered equal.
+// Recording and replay must use the same rule.
+type MatchRule struct {
+	IgnoreScheme bool     // http and https
+	IgnoreQuery  bool     // the entire query
+	IgnoreParams []string // single params, i.e. utm_source, fsrc
+}
+
+func (m MatchRule) key(method string, u *url.URL) string {
+
+	k := method + " "
+	if !m.IgnoreScheme {
+		k += u.Scheme + "://"
+	}
+	k += u.Host
+	if u.Path == "" {
+		k += "/"
+	} else {
+		k += u.Path
+	}
+
+	if m.IgnoreQuery {
+		return k
+	}
+	q := u.Query()
+	for _, p := range m.IgnoreParams {
+		q.Del(p)
+	}
+	if len(q) > 0 {
+		k += "?" + q.Encode() // Encode() sorts by key
+	}
+	return k
+}
+
+// Store keeps exchanges in any fsi.FileSystem, below Dir/host/.
+// The port is separated by "_" - "localhost_8085" -
+// since windows forbids ":" in file names.
+type Store struct {
+	FS    fsi.FileSystem
+	Dir   string
+	Match MatchRule
+}
+
+func (s *Store) file(method string, u *url.URL) string {
+	h := sha1.Sum([]byte(s.Match.key(method, u)))
+	return path.Join(s.Dir, hostDir(u.Host), fmt.Sprintf("%x", h[:10]))
+}
+
+func hostDir(host string) string {
+	return strings.Replace(host, ":", "_", -1)
+}
+
+// dirHost reverts hostDir; host names contain no "_" followed by digits only.
+func dirHost(dir string) string {
+	i := strings.LastIndex(dir, "_")
+	if i < 0 || i == len(dir)-1 || strings.Trim(dir[i+1:], "0123456789") != "" {
+		return dir
+	}
+	return dir[:i] + ":" + dir[i+1:]
+}
+
+// Get returns the recorded exchange and body.
+func (s *Store) Get(method, surl string) (*Exchange, []byte, error) {
+	u, err := URLFromString(surl)
+	if err != nil {
+		return nil, nil, err
+	}
+	fn := s.file(method, u)
+	bts, err := s.FS.ReadFile(fn + ".json")
+	if err != nil {
+		return nil, nil, ErrNoRecording
+	}
+	x := &Exchange{}
+	err = json.Unmarshal(bts, x)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := s.FS.ReadFile(fn + ".body")
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, body, nil
+}
+
+// Put saves or overwrites an exchange.
+// It may be used to edit fixtures.
+func (s *Store) Put(x *Exchange, body []byte) error {
+	u, err := URLFromString(x.URL)
+	if err != nil {
+		return err
+	}
+	if x.Method == "" {
+		x.Method = "GET"
+	}
+	if x.Recorded.IsZero() {
+		x.Recorded = time.Now()
+	}
+	fn := s.file(x.Method, u)
+	err = common.WriteFile(s.FS, fn+".body", body)
+	if err != nil {
+		return err
+	}
+	bts, err := json.MarshalIndent(x, "", "\t")
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(s.FS, fn+".json", bts)
+}
+
+// Hosts lists the hosts with recordings.
+func (s *Store) Hosts() []string {
+	hosts := []string{}
+	fis, _ := s.FS.ReadDir(s.Dir)
+	for _, fi := range fis {
+		if fi.IsDir() {
+			hosts = append(hosts, dirHost(strings.TrimSuffix(fi.Name(), "/")))
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// RecordingTransport passes requests on to Base
+// and saves every response into Store.
+type RecordingTransport struct {
+	Store *Store
+	Base  http.RoundTripper // nil => http.DefaultTransport
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	x := &Exchange{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+	}
+	err = t.Store.Put(x, body)
+	if err != nil {
+		return nil, fmt.Errorf("recording %v failed: %v", x.URL, err)
+	}
+	return resp, nil
+}
+
+// ReplayTransport serves recorded responses.
+// Unrecorded requests go to Fallback - or fail with ErrNoRecording.
+type ReplayTransport struct {
+	Store    *Store
+	Fallback http.RoundTripper
+}
+
+func (t *ReplayTransport) Has(req *http.Request) bool {
+	_, _, err := t.Store.Get(req.Method, req.URL.String())
+	return err == nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	x, body, err := t.Store.Get(req.Method, req.URL.String())
+	if err == ErrNoRecording && t.Fallback != nil {
+		return t.Fallback.RoundTrip(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v %v", err, req.Method, req.URL)
+	}
+	hdr := http.Header{}
+	for k, v := range x.Header {
+		hdr[k] = v
+	}
+	resp := &http.Response{
+		Status:        strconv.Itoa(x.Status) + " " + http.StatusText(x.Status),
+		StatusCode:    x.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        hdr,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	return resp, nil
+}
+
+// Fixtures hold test pages, formerly TestData.
+// They are served for every URL they contain,
+// also on appengine. See t_data.go.
+var Fixtures = &Store{
+	FS:    memfs.New(memfs.Ident("mntfixtures")),
+	Dir:   "/fixtures",
+	Match: MatchRule{IgnoreScheme: true},
+}
+
+// Replay is consulted by UrlGetter before any network access.
+// Without Fallback, it only serves recorded URLs;
+// with Fallback, it serves all.
+var Replay = &ReplayTransport{Store: Fixtures}
+
+// UseRecordings points Replay to recordings in fs.
+// With record, missing responses are fetched and recorded.
+// Tests call it with a flag, to refresh recordings:
+//
+//	var record = flag.Bool("record", false, "record http responses")
+//	fetch.UseRecordings(osfs.New(), "testdata/recordings", *record, "testdata/fixtures")
+//
+// fixtureDirs hold hand written exchanges in the same format;
+// they are consulted after the recordings, and never written to.
+// Afterwards, UrlGetter has no network access, except for recording.
+// Fixtures remain available.
+// Relative dirs are resolved at once; tests may change the working dir later.
+func UseRecordings(fs fsi.FileSystem, dir string, record bool, fixtureDirs ...string) {
+	st := &Store{FS: fs, Dir: absSlash(dir), Match: MatchRule{IgnoreScheme: true}}
+	fixt := &ReplayTransport{Store: Fixtures}
+	if record {
+		fixt.Fallback = &RecordingTransport{Store: st}
+	}
+	for i := len(fixtureDirs) - 1; i >= 0; i-- {
+		fst := &Store{FS: fs, Dir: absSlash(fixtureDirs[i]), Match: MatchRule{IgnoreScheme: true}}
+		fixt = &ReplayTransport{Store: fst, Fallback: fixt}
+	}
+	Replay = &ReplayTransport{Store: st, Fallback: fixt}
+}
+
+func absSlash(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = filepath.ToSlash(abs)
+	}
+	return dir
+}
+
+// ImportWARC puts the responses of WARC records into the store,
+// so that archived crawls can be replayed.
+// Revisits are served with the payload of the response
+// with the same payload digest; revisits without one are skipped.
+func (s *Store) ImportWARC(recs []*warc.Record) (int, error) {
+
+	payloads := map[string][]byte{} // payload digest => body
+	cnt := 0
+	for _, rec := range recs {
+
+		var body []byte
+		switch rec.Type() {
+		case warc.Response:
+			b, err := rec.Payload()
+			if err != nil {
+				return cnt, err
+			}
+			body = b
+			payloads[rec.Header.Get("WARC-Payload-Digest")] = b
+		case warc.Revisit:
+			b, ok := payloads[rec.Header.Get("WARC-Payload-Digest")]
+			if !ok {
+				continue
+			}
+			body = b
+		default:
+			continue
+		}
+
+		resp, err := rec.HTTPResponse(nil)
+		if err != nil {
+			return cnt, err
+		}
+		resp.Body.Close()
+
+		x := &Exchange{
+			Method:   "GET",
+			URL:      rec.TargetURI(),
+			Status:   resp.StatusCode,
+			Header:   resp.Header,
+			Recorded: rec.Date(),
+		}
+		if rec.Type() == warc.Revisit {
+			x.Status = http.StatusOK // the full response is replayed
+		}
+		err = s.Put(x, body)
+		if err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
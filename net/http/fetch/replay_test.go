@@ -0,0 +1,94 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/warc"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestRecordReplay(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("page " + r.URL.Path))
+	}))
+
+	st := &Store{
+		FS:    memfs.New(memfs.Ident("mntrecord")),
+		Dir:   "/recordings",
+		Match: MatchRule{IgnoreScheme: true, IgnoreParams: []string{"fsrc"}},
+	}
+
+	rec := &http.Client{Transport: &RecordingTransport{Store: st}}
+	resp, err := rec.Get(srv.URL + "/news/a?fsrc=rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	srv.Close() // offline from now on
+
+	// the port is escaped in the directory name
+	host := resp.Request.URL.Host
+	if hs := st.Hosts(); len(hs) != 1 || hs[0] != host {
+		t.Errorf("hosts %v; want %v", hs, host)
+	}
+	if _, err := st.FS.Stat("/recordings/" + strings.Replace(host, ":", "_", -1)); err != nil {
+		t.Errorf("no escaped host dir: %v", err)
+	}
+
+	rpl := &http.Client{Transport: &ReplayTransport{Store: st}}
+	resp, err = rpl.Get(srv.URL + "/news/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "page /news/a" || resp.StatusCode != http.StatusAccepted || resp.Header.Get("ETag") != `"v1"` {
+		t.Errorf("replay yields %v %v %q", resp.StatusCode, resp.Header, body)
+	}
+
+	_, err = rpl.Get(srv.URL + "/news/b")
+	if err == nil {
+		t.Errorf("unrecorded url should fail")
+	}
+
+	// fixtures are seeded
+	x, body, err := Fixtures.Get("GET", "http://test.economist.com/someurl")
+	if err != nil || x.Status != http.StatusOK || len(body) == 0 {
+		t.Errorf("fixture missing: %v %v", x, err)
+	}
+}
+
+func TestImportWARC(t *testing.T) {
+
+	now := time.Now()
+	uri := "http://www.economist.com/news/a"
+	body := []byte("page a")
+	hdr := http.Header{"Content-Type": {"text/html"}}
+	recs := []*warc.Record{
+		warc.NewRequest("GET", uri, http.Header{}, now),
+		warc.NewResponse(uri, http.StatusOK, hdr, body, now),
+		warc.NewRevisit(uri+"?page=1", http.StatusNotModified, http.Header{}, warc.Digest(body), "", now, now),
+		warc.NewRevisit(uri+"/unknown", http.StatusNotModified, http.Header{}, warc.Digest([]byte("x")), "", now, now),
+	}
+
+	st := &Store{FS: memfs.New(), Dir: "/recordings"}
+	n, err := st.ImportWARC(recs)
+	if err != nil || n != 2 {
+		t.Fatalf("imported %v; %v", n, err)
+	}
+	for _, u := range []string{uri, uri + "?page=1"} {
+		x, b, err := st.Get("GET", u)
+		if err != nil || x.Status != http.StatusOK || string(b) != "page a" {
+			t.Errorf("%v: %v %q %v", u, x, b, err)
+		}
+	}
+}
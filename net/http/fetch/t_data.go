@@ -1,8 +1,34 @@
 package fetch
 
-var TestData = map[string][]byte{
+import (
+	"net/http"
+	"strings"
+)
+
+// Test pages are seeded into Fixtures.
+// Change them at runtime with Fixtures.Get() and Fixtures.Put().
+func init() {
+	for surl, body := range fixturePages {
+		ct := "text/html; charset=utf-8"
+		switch {
+		case strings.HasSuffix(surl, ".xml"):
+			ct = "application/rss+xml; charset=utf-8"
+		case strings.HasSuffix(surl, ".txt"), strings.HasSuffix(surl, "/someurl"):
+			ct = "text/plain; charset=utf-8"
+		}
+		x := &Exchange{URL: surl, Status: http.StatusOK, Header: http.Header{"Content-Type": {ct}}}
+		err := Fixtures.Put(x, body)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+var fixturePages = map[string][]byte{
 	"test.economist.com/someurl": []byte("requesting test.economist.com/someurl will yield this content"),
 
+	"test.economist.com/robots.txt": []byte("User-agent: *\nDisallow: /search\n"),
+
 	"test.economist.com/sections/business-finance/rss.xml": []byte(`<?xml version="1.0" encoding="utf-8"?>
 <rss version="2.0" xml:base="http://test.economist.com/sections/business-finance/rss.xml"  xmlns:dc="http://purl.org/dc/elements/1.1/">
 <channel>
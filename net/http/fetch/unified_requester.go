@@ -12,11 +12,9 @@ import (
 	"time"
 
 	"github.com/pbberlin/tools/appengine/util_appengine"
+	"github.com/pbberlin/tools/net/http/urlnorm"
 	"github.com/pbberlin/tools/stringspb"
 	"google.golang.org/appengine"
-
-	oldAE "appengine"
-	oldFetch "appengine/urlfetch"
 )
 
 var MsgNoRdirects = "redirect cancelled"
@@ -34,6 +32,33 @@ type Options struct {
 
 	KnownProtocol                     string
 	ForceHTTPSEvenOnDevelopmentServer bool
+
+	Cache *Cache // GET responses are cached; nil => no caching
+
+	// Retry is zero by default => no retries.
+	// All requests obey GlobalLimiter and the HostLimits.
+	Retry RetryPolicy
+
+	// Waiting for GlobalLimiter, the HostLimits and retries ends here,
+	// i.e. before the deadline of the appengine request.
+	// Zero => RequestDeadline on appengine, no limit elsewhere.
+	Deadline time.Time
+}
+
+// AERequestBudget is the time, fetches may take within an appengine request,
+// which is aborted after 60 seconds.
+var AERequestBudget = 50 * time.Second
+
+// RequestDeadline is the deadline of the context of an appengine request,
+// else AERequestBudget from now.
+// Handlers take it at their start and pass it to all their fetches.
+func RequestDeadline(gaeReq *http.Request) time.Time {
+	if c := util_appengine.SafelyExtractGaeContext(gaeReq); c != nil {
+		if dl, ok := c.Deadline(); ok {
+			return dl
+		}
+	}
+	return time.Now().Add(AERequestBudget)
 }
 
 // Response info
@@ -42,6 +67,24 @@ type Info struct {
 	Mod    time.Time
 	Status int
 	Msg    string
+
+	FromCache   bool // body came from Options.Cache
+	Revalidated bool // server confirmed the cached body with 304
+	Retries     int
+
+	Charset string // original charset of html and text; body is always transcoded to utf-8
+
+	// Normalized URL of the document - from <link rel="canonical"> on the same host,
+	// otherwise from the request URL. See package urlnorm.
+	Canonical *url.URL
+
+	// The exchange as on the wire - for archiving.
+	// Raw is the body before transcoding.
+	// Header is nil for fresh cache hits; CachedAt is the time of the cached response.
+	ReqHeader http.Header
+	Header    http.Header
+	Raw       []byte
+	CachedAt  time.Time
 }
 
 // UrlGetter universal http getter for app engine and standalone go programs.
@@ -102,19 +145,11 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 		c := util_appengine.SafelyExtractGaeContext(gaeReq)
 		if c != nil {
 
-			ctxOld := oldAE.NewContext(gaeReq)
-			client = oldFetch.Client(ctxOld)
-
-			// this does not prevent urlfetch: SSL_CERTIFICATE_ERROR
-			// it merely leads to err = "DEADLINE_EXCEEDED"
-			tr := oldFetch.Transport{Context: ctxOld, AllowInvalidServerCertificate: true}
-			// thus
-			tr = oldFetch.Transport{Context: ctxOld, AllowInvalidServerCertificate: false}
+			client = aeClient(gaeReq)
 
-			tr.Deadline = 20 * time.Second // only possible on aeOld
-
-			client.Transport = &tr
-			// client.Timeout = 20 * time.Second // also not in google.golang.org/appengine/urlfetch
+			if options.Deadline.IsZero() {
+				options.Deadline = RequestDeadline(gaeReq)
+			}
 
 		} else {
 			return nil, inf, ErrNoContext
@@ -151,15 +186,35 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 
 	//
 	//
-	// Respond to test.economist.com directly from memory
-	if _, ok := TestData[r.URL.Host+r.URL.Path]; ok {
-		return TestData[r.URL.Host+r.URL.Path], inf, nil
+	// Recorded responses - i.e. test.economist.com - from fixtures or recordings
+	if Replay != nil && (Replay.Fallback != nil || Replay.Has(r)) {
+		client = &http.Client{Transport: Replay, CheckRedirect: client.CheckRedirect}
+		inf.Msg += fmt.Sprintf("replaying %v\n", r.URL.String())
+	}
+
+	//
+	// Fresh from cache - or revalidate
+	var cached *cacheEntry
+	if options.Cache != nil && r.Method == "GET" {
+		cached = options.Cache.lookup(r.URL)
+		if cached != nil && cached.fresh(time.Now()) {
+			inf.FromCache = true
+			inf.Mod = cached.Mod
+			inf.Status = http.StatusOK
+			inf.ReqHeader, inf.Raw, inf.CachedAt = r.Header, cached.body, cached.Stored
+			bts := transcode(cached.body, cached.ContentType, &inf)
+			setCanonical(bts, cached.ContentType, r.URL, &inf)
+			return bts, inf, nil
+		}
+		if cached != nil {
+			cached.conditional(r)
+		}
 	}
 
 	// The actual call
 	// =============================
 
-	resp, err := client.Do(r)
+	resp, err := doPolite(client, r, options.Retry, options.Deadline, &inf)
 
 	// Swallow redirect errors
 	if err != nil {
@@ -184,7 +239,7 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 		if isHTTPSProblem && r.URL.Scheme == "https" && r.Method == "GET" {
 			r.URL.Scheme = "http"
 			var err2nd error
-			resp, err2nd = client.Do(r)
+			resp, err2nd = doPolite(client, r, RetryPolicy{}, options.Deadline, &inf)
 			// while protocol http may go through
 			// next obstacle might be - again - a redirect error:
 			if err2nd != nil {
@@ -225,6 +280,25 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 		return nil, inf, fmt.Errorf("request failed: %v - %v", err, hintAE)
 	}
 
+	//
+	// Cached body is still valid
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		err = options.Cache.refresh(r.URL, cached, resp.Header)
+		if err != nil {
+			inf.Msg += fmt.Sprintf("cache refresh failed: %v\n", err)
+		}
+		inf.FromCache = true
+		inf.Revalidated = true
+		inf.Mod = cached.Mod
+		inf.Status = http.StatusOK
+		inf.ReqHeader, inf.Raw, inf.CachedAt = r.Header, cached.body, cached.Stored
+		inf.Header = resp.Header
+		bts := transcode(cached.body, cached.ContentType, &inf)
+		setCanonical(bts, cached.ContentType, r.URL, &inf)
+		return bts, inf, nil
+	}
+
 	//
 	// We got response, but
 	// explicit bad response from server
@@ -256,7 +330,7 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 				r.URL.Path = "/"
 			}
 			var err2nd error
-			resp, err2nd = client.Do(r)
+			resp, err2nd = doPolite(client, r, RetryPolicy{}, options.Deadline, &inf)
 			if err2nd != nil {
 				return nil, inf, fmt.Errorf("again error %v \n%v", err2nd, err2)
 			}
@@ -270,6 +344,7 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 			// return nil, inf, err2
 
 		} else {
+			inf.Status = resp.StatusCode
 			return nil, inf, fmt.Errorf("bad http resp code: %v - %v", resp.StatusCode, r.URL.String())
 		}
 	}
@@ -296,10 +371,45 @@ func UrlGetter(gaeReq *http.Request, options Options) (
 	inf.Mod = tlm
 	// log.Printf("    hdr  %v %v\n", lm, tlm.Format(time.ANSIC))
 
+	if options.Cache != nil && r.Method == "GET" {
+		err = options.Cache.store(r.URL, resp.Header, bts, inf.Mod)
+		if err != nil {
+			inf.Msg += fmt.Sprintf("cache store failed: %v\n", err)
+		}
+	}
+
+	inf.Status = resp.StatusCode
+	inf.ReqHeader, inf.Header, inf.Raw = r.Header, resp.Header, bts
+
+	bts = transcode(bts, resp.Header.Get("Content-Type"), &inf)
+	setCanonical(bts, resp.Header.Get("Content-Type"), r.URL, &inf)
+
 	return bts, inf, nil
 
 }
 
+// transcode wraps toUTF8; failures are only reported in inf.Msg.
+func transcode(bts []byte, contentType string, inf *Info) []byte {
+	utf8, cs, err := toUTF8(bts, contentType)
+	inf.Charset = cs
+	if err != nil {
+		inf.Msg += fmt.Sprintf("transcoding from %v failed: %v\n", cs, err)
+	}
+	return utf8
+}
+
+// setCanonical only parses html for <link rel="canonical">.
+func setCanonical(bts []byte, contentType string, u *url.URL, inf *Info) {
+	if contentType == "" {
+		contentType = http.DetectContentType(bts)
+	}
+	if strings.Contains(contentType, "html") {
+		inf.Canonical = urlnorm.Resolve(u, bts)
+	} else {
+		inf.Canonical = urlnorm.Std.NormalizeURL(u)
+	}
+}
+
 func addFallBackSuccessInfo(options Options, inf *Info, r *http.Request, err error) {
 	if options.LogLevel > 0 {
 		inf.Msg += fmt.Sprintf("\tsuccessful fallback to http %v", r.URL.String())
@@ -0,0 +1,17 @@
+//go:build !appengine
+// +build !appengine
+
+package fetch
+
+import (
+	"net/http"
+	"time"
+)
+
+// aeClient outside the classic appengine SDK,
+// i.e. for command line tools built with plain go build.
+// Their requests never carry an appengine context;
+// should one turn up, net/http fetches directly.
+func aeClient(gaeReq *http.Request) *http.Client {
+	return &http.Client{Timeout: 20 * time.Second}
+}
@@ -0,0 +1,501 @@
+// Package frontier keeps the URLs of a crawl,
+// which are yet to be fetched, in any fsi.FileSystem.
+//
+// Each host has its own queue; Pop serves the hosts round robin,
+// and within a host the highest priority first.
+// A seen-set prevents URLs from being queued twice.
+//
+// Popped URLs are in flight until Done or Fail is called.
+// A checkpoint saves queued and in-flight URLs alike,
+// so that a crawl, which was interrupted by a request timeout,
+// resumes with the URLs it did not finish.
+package frontier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Entry is a URL with crawl metadata.
+type Entry struct {
+	URL      string
+	Priority int    // higher first
+	Depth    int    // link distance from the seed
+	Source   string // where the URL was found, i.e. the referring page or "sitemap"
+	Added    time.Time
+	LastMod  time.Time // as announced by the source; may be zero
+	Attempts int       `json:",omitempty"` // failed fetches so far
+}
+
+// Stats describes a host queue - or all of them.
+type Stats struct {
+	Host     string
+	Queued   int
+	InFlight int
+	Seen     int
+}
+
+const (
+	fnState  = "frontier.json"
+	fnSeen   = "seen.txt"
+	fnPaused = "paused" // exists while paused
+)
+
+type state struct {
+	Checkpoint time.Time
+	Queues     map[string][]Entry // per host
+}
+
+type Frontier struct {
+	fs  fsi.FileSystem
+	dir string
+
+	// Automatic checkpoint after as many changes; zero disables.
+	CheckpointEvery int
+
+	mu       sync.Mutex
+	st       state
+	paused   bool
+	inFlight map[string]Entry
+	seen     map[string]bool
+	seenNew  []string // not yet in seen.txt
+	hosts    []string // round robin order
+	next     int
+	changes  int
+}
+
+// Open loads the last checkpoint from dir - or starts empty.
+// URLs that were in flight at the checkpoint are queued again.
+func Open(fs fsi.FileSystem, dir string) (*Frontier, error) {
+
+	f := &Frontier{
+		fs:              fs,
+		dir:             dir,
+		CheckpointEvery: 50,
+		st:              state{Queues: map[string][]Entry{}},
+		inFlight:        map[string]Entry{},
+		seen:            map[string]bool{},
+	}
+
+	bts, err := fs.ReadFile(path.Join(dir, fnState))
+	if err == nil {
+		err = json.Unmarshal(bts, &f.st)
+		if err != nil {
+			return nil, err
+		}
+		if f.st.Queues == nil {
+			f.st.Queues = map[string][]Entry{}
+		}
+	}
+
+	bts, err = fs.ReadFile(path.Join(dir, fnSeen))
+	if err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(bts))
+		for sc.Scan() {
+			if k := sc.Text(); k != "" {
+				f.seen[k] = true
+			}
+		}
+	}
+
+	f.paused = IsPaused(fs, dir)
+
+	for host, q := range f.st.Queues {
+		if len(q) == 0 {
+			delete(f.st.Queues, host)
+			continue
+		}
+		sort.Stable(byPriority(q))
+		f.hosts = append(f.hosts, host)
+	}
+	sort.Strings(f.hosts)
+
+	return f, nil
+}
+
+// Key is the dedup key of a URL: its urlnorm form without scheme.
+func Key(surl string) (key, host string) {
+	if !strings.Contains(surl, "://") {
+		surl = "http://" + surl
+	}
+	u, err := url.Parse(surl)
+	if err != nil {
+		return surl, ""
+	}
+	u = urlnorm.Std.NormalizeURL(u)
+	key = strings.TrimPrefix(u.String(), u.Scheme+"://")
+	return key, u.Host
+}
+
+// Push queues e, unless its URL was seen before.
+func (f *Frontier) Push(e Entry) bool {
+	k, host := Key(e.URL)
+	if host == "" {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.seen[k] {
+		return false
+	}
+	f.seen[k] = true
+	f.seenNew = append(f.seenNew, k)
+
+	if e.Added.IsZero() {
+		e.Added = time.Now()
+	}
+	f.enqueue(host, e)
+	f.changed()
+	return true
+}
+
+// Revisit queues e again, even though its URL was seen.
+// It is meant for re-crawl schedules.
+// URLs, which are still queued or in flight, are not queued twice.
+func (f *Frontier) Revisit(e Entry) bool {
+	k, host := Key(e.URL)
+	if host == "" {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.inFlight[k]; ok {
+		return false
+	}
+	for _, q := range f.st.Queues[host] {
+		if qk, _ := Key(q.URL); qk == k {
+			return false
+		}
+	}
+	if !f.seen[k] {
+		f.seen[k] = true
+		f.seenNew = append(f.seenNew, k)
+	}
+	if e.Added.IsZero() {
+		e.Added = time.Now()
+	}
+	f.enqueue(host, e)
+	f.changed()
+	return true
+}
+
+// enqueue keeps the host queue sorted; equal priorities stay in order of arrival.
+func (f *Frontier) enqueue(host string, e Entry) {
+	q, ok := f.st.Queues[host]
+	if !ok {
+		f.hosts = append(f.hosts, host)
+	}
+	i := sort.Search(len(q), func(i int) bool { return q[i].Priority < e.Priority })
+	q = append(q, Entry{})
+	copy(q[i+1:], q[i:])
+	q[i] = e
+	f.st.Queues[host] = q
+}
+
+// Pop returns the next URL and marks it in flight.
+// It returns false, if the frontier is empty or paused.
+func (f *Frontier) Pop() (Entry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.paused || len(f.hosts) == 0 {
+		return Entry{}, false
+	}
+	if f.next >= len(f.hosts) {
+		f.next = 0
+	}
+	host := f.hosts[f.next]
+	q := f.st.Queues[host]
+	e := q[0]
+	if len(q) == 1 {
+		delete(f.st.Queues, host)
+		f.hosts = append(f.hosts[:f.next], f.hosts[f.next+1:]...)
+	} else {
+		f.st.Queues[host] = q[1:]
+		f.next++
+	}
+
+	k, _ := Key(e.URL)
+	f.inFlight[k] = e
+	f.changed()
+	return e, true
+}
+
+// Done removes a popped URL for good.
+func (f *Frontier) Done(surl string) {
+	k, _ := Key(surl)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.inFlight[k]; ok {
+		delete(f.inFlight, k)
+		f.changed()
+	}
+}
+
+// Retry queues a popped URL again, i.e. after a transient error.
+func (f *Frontier) Retry(surl string) {
+	k, host := Key(surl)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.inFlight[k]; ok {
+		delete(f.inFlight, k)
+		f.enqueue(host, e)
+		f.changed()
+	}
+}
+
+// Fail queues a popped URL again after a failed fetch,
+// unless it has failed max times already; then it is dropped like Done.
+// Fail tells, whether the URL was queued again.
+func (f *Frontier) Fail(surl string, max int) bool {
+	k, host := Key(surl)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.inFlight[k]
+	if !ok {
+		return false
+	}
+	delete(f.inFlight, k)
+	e.Attempts++
+	if e.Attempts < max {
+		f.enqueue(host, e)
+	}
+	f.changed()
+	return e.Attempts < max
+}
+
+// Seen tells, whether the URL was ever pushed.
+func (f *Frontier) Seen(surl string) bool {
+	k, _ := Key(surl)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seen[k]
+}
+
+// Len is the number of queued URLs, without those in flight.
+func (f *Frontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, q := range f.st.Queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Pause stops Pop from serving URLs; Push still works.
+// Pausing and resuming are saved at once.
+func (f *Frontier) Pause() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = true
+	if err := SetPaused(f.fs, f.dir, true); err != nil {
+		return err
+	}
+	return f.checkpoint()
+}
+
+func (f *Frontier) Resume() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = false
+	if err := SetPaused(f.fs, f.dir, false); err != nil {
+		return err
+	}
+	return f.checkpoint()
+}
+
+func (f *Frontier) Paused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.paused
+}
+
+// RefreshPaused re-reads the paused flag,
+// which SetPaused may have changed from outside.
+// Checkpoints refresh it as well.
+func (f *Frontier) RefreshPaused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = IsPaused(f.fs, f.dir)
+	return f.paused
+}
+
+// SetPaused pauses or resumes the frontier in dir,
+// while another process may hold it open.
+// That process obeys with its next checkpoint or RefreshPaused.
+func SetPaused(fs fsi.FileSystem, dir string, paused bool) error {
+	fn := path.Join(dir, fnPaused)
+	if paused {
+		return common.WriteFile(fs, fn, []byte(time.Now().Format(time.RFC3339)))
+	}
+	if _, err := fs.Stat(fn); err != nil {
+		return nil // not paused
+	}
+	return fs.Remove(fn)
+}
+
+// IsPaused reads the paused flag of the frontier in dir.
+func IsPaused(fs fsi.FileSystem, dir string) bool {
+	_, err := fs.Stat(path.Join(dir, fnPaused))
+	return err == nil
+}
+
+// Stats returns one line per host, sorted by host,
+// and the total.
+func (f *Frontier) Stats() ([]Stats, Stats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := map[string]*Stats{}
+	get := func(host string) *Stats {
+		if m[host] == nil {
+			m[host] = &Stats{Host: host}
+		}
+		return m[host]
+	}
+	for host, q := range f.st.Queues {
+		get(host).Queued = len(q)
+	}
+	for k := range f.inFlight {
+		get(hostOfKey(k)).InFlight++
+	}
+	for k := range f.seen {
+		get(hostOfKey(k)).Seen++
+	}
+
+	total := Stats{}
+	ret := make([]Stats, 0, len(m))
+	for _, s := range m {
+		ret = append(ret, *s)
+		total.Queued += s.Queued
+		total.InFlight += s.InFlight
+		total.Seen += s.Seen
+	}
+	sort.Sort(byHost(ret))
+	return ret, total
+}
+
+// Peek returns the first n queued entries of host, without popping them.
+func (f *Frontier) Peek(host string, n int) []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.st.Queues[host]
+	if n > len(q) {
+		n = len(q)
+	}
+	return append([]Entry{}, q[:n]...)
+}
+
+// InFlight returns the popped entries, which are not done.
+func (f *Frontier) InFlight() []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ret := make([]Entry, 0, len(f.inFlight))
+	for _, e := range f.inFlight {
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+// LastCheckpoint is zero, if none was written yet.
+func (f *Frontier) LastCheckpoint() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.st.Checkpoint
+}
+
+// changed must be called under f.mu.
+func (f *Frontier) changed() {
+	f.changes++
+	if f.CheckpointEvery > 0 && f.changes >= f.CheckpointEvery {
+		f.checkpoint() // errors surface with the next explicit Checkpoint
+	}
+}
+
+// Checkpoint saves the frontier.
+// In-flight entries are saved as queued.
+func (f *Frontier) Checkpoint() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkpoint()
+}
+
+func (f *Frontier) checkpoint() error {
+
+	f.changes = 0
+
+	// seen.txt only grows; it is rewritten in full,
+	// since fsi has no append
+	if len(f.seenNew) > 0 {
+		keys := make([]string, 0, len(f.seen))
+		for k := range f.seen {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		err := common.WriteFile(f.fs, path.Join(f.dir, fnSeen), []byte(strings.Join(keys, "\n")+"\n"))
+		if err != nil {
+			return err
+		}
+		f.seenNew = f.seenNew[:0]
+	}
+
+	f.paused = IsPaused(f.fs, f.dir) // set from outside perhaps
+
+	st := state{Checkpoint: time.Now(), Queues: map[string][]Entry{}}
+	for host, q := range f.st.Queues {
+		st.Queues[host] = q
+	}
+	for k, e := range f.inFlight {
+		host := hostOfKey(k)
+		q := append([]Entry{}, st.Queues[host]...)
+		q = append(q, e)
+		sort.Stable(byPriority(q))
+		st.Queues[host] = q
+	}
+
+	bts, err := json.MarshalIndent(st, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = common.WriteFile(f.fs, path.Join(f.dir, fnState), bts)
+	if err != nil {
+		return err
+	}
+	f.st.Checkpoint = st.Checkpoint
+	return nil
+}
+
+func hostOfKey(k string) string {
+	if i := strings.IndexAny(k, "/?"); i >= 0 {
+		return k[:i]
+	}
+	return k
+}
+
+type byPriority []Entry
+
+func (s byPriority) Len() int           { return len(s) }
+func (s byPriority) Less(i, j int) bool { return s[i].Priority > s[j].Priority }
+func (s byPriority) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type byHost []Stats
+
+func (s byHost) Len() int           { return len(s) }
+func (s byHost) Less(i, j int) bool { return s[i].Host < s[j].Host }
+func (s byHost) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
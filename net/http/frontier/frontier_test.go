@@ -0,0 +1,153 @@
+package frontier
+
+import (
+	"testing"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestFrontier(t *testing.T) {
+
+	fs := memfs.New()
+	f, err := Open(fs, "/crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.CheckpointEvery = 0
+
+	pushes := []Entry{
+		{URL: "www.a.com/news/1", Priority: 1},
+		{URL: "www.a.com/news/2", Priority: 5},
+		{URL: "http://WWW.A.COM/news/2/#top", Priority: 9}, // dup
+		{URL: "www.b.com/x", Priority: 0},
+		{URL: "www.a.com/news/3", Priority: 5},
+	}
+	n := 0
+	for _, e := range pushes {
+		if f.Push(e) {
+			n++
+		}
+	}
+	if n != 4 || f.Len() != 4 {
+		t.Fatalf("want 4 queued; pushed %v, len %v", n, f.Len())
+	}
+
+	// round robin over hosts, priority within host, fifo on equal priority
+	want := []string{"www.a.com/news/2", "www.b.com/x", "www.a.com/news/3"}
+	for _, w := range want {
+		e, ok := f.Pop()
+		if !ok || e.URL != w {
+			t.Errorf("want %v; got %v %v", w, e.URL, ok)
+		}
+	}
+	f.Done("www.a.com/news/2")
+	f.Retry("www.b.com/x")
+
+	// "news/3" is in flight, "news/1" and "x" are queued
+	if err := f.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Pop(); ok {
+		t.Errorf("paused frontier served")
+	}
+
+	// resume from checkpoint
+	g, err := Open(fs, "/crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Paused() {
+		t.Errorf("pause not persisted")
+	}
+	g.Resume()
+	_, total := g.Stats()
+	if total.Queued != 3 || total.Seen != 4 || total.InFlight != 0 {
+		t.Errorf("after reopen: %+v", total)
+	}
+	if g.Push(Entry{URL: "https://www.a.com/news/2"}) {
+		t.Errorf("seen-set not persisted")
+	}
+	got := map[string]bool{}
+	for {
+		e, ok := g.Pop()
+		if !ok {
+			break
+		}
+		got[e.URL] = true
+	}
+	if len(got) != 3 || !got["www.a.com/news/3"] || !got["www.b.com/x"] {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	cases := [][2]string{
+		{"www.a.com", "www.a.com/"},
+		{"https://www.a.com/", "www.a.com/"},
+		{"http://www.A.com/b/?q=1#f", "www.a.com/b?q=1"},
+	}
+	for _, c := range cases {
+		if k, _ := Key(c[0]); k != c[1] {
+			t.Errorf("%v: want %v; got %v", c[0], c[1], k)
+		}
+	}
+}
+
+func TestRevisit(t *testing.T) {
+	f, _ := Open(memfs.New(), "/crawl")
+	f.Push(Entry{URL: "www.a.com/news"})
+	if f.Revisit(Entry{URL: "www.a.com/news"}) {
+		t.Errorf("queued twice")
+	}
+	f.Pop()
+	if f.Revisit(Entry{URL: "www.a.com/news"}) {
+		t.Errorf("queued while in flight")
+	}
+	f.Done("www.a.com/news")
+	if !f.Revisit(Entry{URL: "www.a.com/news"}) || f.Len() != 1 {
+		t.Errorf("revisit refused")
+	}
+}
+
+func TestFail(t *testing.T) {
+	f, _ := Open(memfs.New(), "/crawl")
+	f.Push(Entry{URL: "www.a.com/news"})
+	for i := 1; i <= 3; i++ {
+		e, ok := f.Pop()
+		if !ok || e.Attempts != i-1 {
+			t.Fatalf("attempt %v: got %+v %v", i, e, ok)
+		}
+		if requeued := f.Fail(e.URL, 3); requeued != (i < 3) {
+			t.Errorf("attempt %v: requeued %v", i, requeued)
+		}
+	}
+	if _, total := f.Stats(); total.Queued != 0 || total.InFlight != 0 {
+		t.Errorf("not dropped after 3 attempts: %+v", total)
+	}
+}
+
+func TestPauseFromOutside(t *testing.T) {
+	fs := memfs.New()
+	f, _ := Open(fs, "/crawl")
+	f.Push(Entry{URL: "www.a.com/1"})
+	f.Push(Entry{URL: "www.a.com/2"})
+
+	// the admin page, without opening the frontier
+	if err := SetPaused(fs, "/crawl", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Pop(); ok {
+		t.Errorf("checkpoint overrode the pause")
+	}
+
+	SetPaused(fs, "/crawl", false)
+	if f.RefreshPaused() {
+		t.Errorf("still paused")
+	}
+	if _, ok := f.Pop(); !ok {
+		t.Errorf("resume ignored")
+	}
+}
@@ -0,0 +1,385 @@
+// Package hostconfig loads per-host crawl settings
+// from JSON or TOML files in a directory of any fsi.FileSystem.
+//
+// Each file holds one or more hosts. A host inherits unset settings
+// from its parent - "unspecified" unless named by inherit.
+// RSS mappings and urlnorm rules are merged along the chain; the child wins.
+//
+//	[unspecified]
+//	depth_tolerance = 1
+//	desired_number  = 5
+//
+//	["www.economist.com"]
+//	depth_tolerance = 2
+//	["www.economist.com".rss]
+//	"/news/europe" = "/sections/europe/rss.xml"
+//	["www.economist.com".urlnorm]
+//	strip_params = ["utm_*", "fsrc"]
+//	slash = "strip"
+//
+// The same as JSON:
+//
+//	{
+//		"unspecified":       {"depth_tolerance": 1, "desired_number": 5},
+//		"www.economist.com": {"depth_tolerance": 2, "rss": {"/news/europe": "/sections/europe/rss.xml"},
+//			"urlnorm": {"strip_params": ["utm_*", "fsrc"], "slash": "strip"}}
+//	}
+//
+// Host names may carry a port, i.e. "localhost:8085".
+//
+// A Store re-reads the directory, when files change;
+// an invalid change is reported, while the previous settings stay in effect.
+package hostconfig
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Root is the host, all others inherit from.
+const Root = "unspecified"
+
+var hostRe = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)*(:[0-9]+)?$`)
+
+// Host are the settings of one host, as written in a file.
+// Nil pointers are inherited.
+type Host struct {
+	Name    string
+	Inherit string // empty => Root
+
+	DesiredNumber        *int
+	CondenseTrailingDirs *int
+	DepthTolerance       *int
+	RSS                  map[string]string      // SearchPrefix => RSS URI
+	URLNorm              map[string]interface{} // urlnorm rule => value; see urlnormKeys
+
+	File string // where it was defined
+}
+
+func (h *Host) parent() string {
+	if h.Name == Root {
+		return ""
+	}
+	if h.Inherit == "" {
+		return Root
+	}
+	return h.Inherit
+}
+
+// Effective are the resolved settings of a host.
+type Effective struct {
+	Host  string
+	Chain []string // the host and its ancestors, as far as configured
+
+	DesiredNumber        int
+	CondenseTrailingDirs int
+	DepthTolerance       int
+	RSS                  map[string]string
+	URLNorm              urlnorm.Rules // urlnorm.Default, overridden along the chain
+
+	// field => host it was taken from
+	Sources map[string]string
+}
+
+// Config is a validated set of hosts.
+type Config struct {
+	Hosts  map[string]*Host
+	Loaded time.Time
+}
+
+// Effective resolves the settings of host;
+// unknown hosts get those of Root.
+func (c *Config) Effective(host string) Effective {
+
+	eff := Effective{Host: host, RSS: map[string]string{}, URLNorm: urlnorm.Default, Sources: map[string]string{}}
+
+	name := host
+	if _, ok := c.Hosts[name]; !ok {
+		name = Root
+	}
+	var chain []*Host
+	for name != "" {
+		h, ok := c.Hosts[name]
+		if !ok {
+			break
+		}
+		chain = append(chain, h)
+		eff.Chain = append(eff.Chain, name)
+		name = h.parent()
+	}
+
+	setInt := func(field string, dst *int, get func(*Host) *int) {
+		for _, h := range chain {
+			if v := get(h); v != nil {
+				*dst = *v
+				eff.Sources[field] = h.Name
+				return
+			}
+		}
+	}
+	setInt("DesiredNumber", &eff.DesiredNumber, func(h *Host) *int { return h.DesiredNumber })
+	setInt("CondenseTrailingDirs", &eff.CondenseTrailingDirs, func(h *Host) *int { return h.CondenseTrailingDirs })
+	setInt("DepthTolerance", &eff.DepthTolerance, func(h *Host) *int { return h.DepthTolerance })
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].RSS {
+			eff.RSS[k] = v
+			eff.Sources["RSS "+k] = chain[i].Name
+		}
+		for k, v := range chain[i].URLNorm {
+			setURLNorm(&eff.URLNorm, k, v)
+			eff.Sources["URLNorm "+k] = chain[i].Name
+		}
+	}
+	return eff
+}
+
+// URLNorm returns the urlnorm rules of all configured hosts;
+// those of Root are the default for hosts without config.
+func (c *Config) URLNorm() (urlnorm.Rules, map[string]urlnorm.Rules) {
+	hosts := map[string]urlnorm.Rules{}
+	for n := range c.Hosts {
+		if n != Root {
+			hosts[n] = c.Effective(n).URLNorm
+		}
+	}
+	return c.Effective(Root).URLNorm, hosts
+}
+
+// Names returns the configured hosts, sorted, Root first.
+func (c *Config) Names() []string {
+	names := []string{}
+	for n := range c.Hosts {
+		if n != Root {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := c.Hosts[Root]; ok {
+		names = append([]string{Root}, names...)
+	}
+	return names
+}
+
+// Store keeps the config of a directory
+// and reloads it, when its files change.
+type Store struct {
+	Dir        string
+	CheckEvery time.Duration // directory listings are spared in between
+
+	// OnLoad is called with each config taken into effect, the seed included.
+	OnLoad func(*Config)
+
+	seedName string
+	seed     []byte
+
+	mu      sync.Mutex
+	cfg     *Config
+	errs    []error
+	stamp   string // names, sizes and mod times of the files last loaded
+	checked time.Time
+}
+
+// NewStore reads config files from dir.
+// If dir has none, seed is written there as seedName,
+// so that it can be edited.
+// The seed also applies, while no valid config was loaded.
+func NewStore(dir, seedName string, seed []byte) *Store {
+	return &Store{Dir: dir, CheckEvery: 30 * time.Second, seedName: seedName, seed: seed}
+}
+
+// Config returns the current config, reloading changed files.
+// fs is passed on each call, since some file systems are bound to a request.
+func (s *Store) Config(fs fsi.FileSystem) *Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg == nil || time.Since(s.checked) > s.CheckEvery {
+		s.reload(fs, false)
+	}
+	return s.cfg
+}
+
+// Reload re-reads all files, changed or not.
+func (s *Store) Reload(fs fsi.FileSystem) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload(fs, true)
+	return s.errs
+}
+
+// Errors of the last load; while there are any,
+// the previous config remains in effect.
+func (s *Store) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errs
+}
+
+func isConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".toml")
+}
+
+func (s *Store) reload(fs fsi.FileSystem, force bool) {
+
+	s.checked = time.Now()
+
+	if s.cfg == nil {
+		cfg, errs := Load(map[string][]byte{s.seedName: s.seed})
+		if len(errs) > 0 {
+			panic(fmt.Sprintf("invalid seed config: %v", errs))
+		}
+		s.apply(cfg)
+	}
+
+	fis, _ := fs.ReadDir(s.Dir)
+	names := []string{}
+	stamp := ""
+	for _, fi := range fis {
+		if fi.IsDir() || !isConfigFile(fi.Name()) {
+			continue
+		}
+		names = append(names, fi.Name())
+		stamp += fmt.Sprintf("%v %v %v;", fi.Name(), fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	if len(names) == 0 {
+		err := common.WriteFile(fs, path.Join(s.Dir, s.seedName), s.seed)
+		if err != nil {
+			s.errs = []error{fmt.Errorf("seeding %v: %v", s.seedName, err)}
+		}
+		return // the seed is in effect
+	}
+
+	if stamp == s.stamp && !force {
+		return
+	}
+	s.stamp = stamp
+
+	files := map[string][]byte{}
+	var errs []error
+	for _, name := range names {
+		b, err := fs.ReadFile(path.Join(s.Dir, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", name, err))
+			continue
+		}
+		files[name] = b
+	}
+	cfg, lerrs := Load(files)
+	errs = append(errs, lerrs...)
+	s.errs = errs
+	if len(errs) == 0 {
+		s.apply(cfg)
+	}
+}
+
+func (s *Store) apply(cfg *Config) {
+	s.cfg = cfg
+	if s.OnLoad != nil {
+		s.OnLoad(cfg)
+	}
+}
+
+// Load parses and validates files; the keys are file names,
+// whose extension decides between JSON and TOML.
+func Load(files map[string][]byte) (*Config, []error) {
+
+	cfg := &Config{Hosts: map[string]*Host{}, Loaded: time.Now()}
+	var errs []error
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		hosts, perrs := Parse(name, files[name])
+		errs = append(errs, perrs...)
+		for _, h := range hosts {
+			if prev, ok := cfg.Hosts[h.Name]; ok {
+				errs = append(errs, fmt.Errorf("%v: host %q is already defined in %v", name, h.Name, prev.File))
+				continue
+			}
+			cfg.Hosts[h.Name] = h
+		}
+	}
+	errs = append(errs, validate(cfg)...)
+	return cfg, errs
+}
+
+func validate(cfg *Config) []error {
+
+	var errs []error
+	names := make([]string, 0, len(cfg.Hosts))
+	for n := range cfg.Hosts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	if _, ok := cfg.Hosts[Root]; !ok {
+		errs = append(errs, fmt.Errorf("no %q host; all hosts inherit from it", Root))
+	}
+
+	for _, n := range names {
+		h := cfg.Hosts[n]
+		pfx := fmt.Sprintf("%v: host %q:", h.File, n)
+
+		if n != strings.ToLower(n) || !hostRe.MatchString(n) {
+			errs = append(errs, fmt.Errorf("%v must be a lower case host name with optional port, like www.economist.com or localhost:8085", pfx))
+		}
+		if h.Name == Root && h.Inherit != "" {
+			errs = append(errs, fmt.Errorf("%v cannot inherit", pfx))
+		}
+
+		// the chain must end at Root
+		seen := map[string]bool{}
+		for p := h.parent(); p != ""; {
+			if seen[p] || p == n {
+				errs = append(errs, fmt.Errorf("%v inheritance cycle via %q", pfx, p))
+				break
+			}
+			seen[p] = true
+			ph, ok := cfg.Hosts[p]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%v inherits from %q, which is not defined", pfx, p))
+				break
+			}
+			p = ph.parent()
+		}
+
+		checkRange := func(field string, v *int, min, max int) {
+			if v != nil && (*v < min || *v > max) {
+				errs = append(errs, fmt.Errorf("%v %v is %v; must be between %v and %v", pfx, field, *v, min, max))
+			}
+		}
+		checkRange("desired_number", h.DesiredNumber, 1, 1000)
+		checkRange("depth_tolerance", h.DepthTolerance, 0, 10)
+		checkRange("condense_trailing_dirs", h.CondenseTrailingDirs, 0, 2)
+
+		for prefix, uri := range h.RSS {
+			if !strings.HasPrefix(prefix, "/") {
+				errs = append(errs, fmt.Errorf("%v rss key %q must be a path, starting with /", pfx, prefix))
+			}
+			if !strings.HasPrefix(uri, "/") && !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+				errs = append(errs, fmt.Errorf("%v rss uri %q must be a path or an absolute http url", pfx, uri))
+			}
+		}
+	}
+
+	if root, ok := cfg.Hosts[Root]; ok {
+		if root.DesiredNumber == nil || root.DepthTolerance == nil || root.CondenseTrailingDirs == nil {
+			errs = append(errs, fmt.Errorf("%v: host %q must set desired_number, depth_tolerance and condense_trailing_dirs", root.File, Root))
+		}
+	}
+	return errs
+}
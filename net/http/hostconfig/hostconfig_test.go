@@ -0,0 +1,191 @@
+package hostconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+const seed = `{
+	"unspecified": {"desired_number": 5, "condense_trailing_dirs": 0, "depth_tolerance": 0,
+		"rss": {"/": "/rss.xml"}}
+}`
+
+// economist and its test clone
+const economist = `{
+	"unspecified": {"desired_number": 5, "depth_tolerance": 0, "condense_trailing_dirs": 0,
+		"rss": {"/": "/rss.xml"}},
+	"www.economist.com": {"condense_trailing_dirs": 2, "depth_tolerance": 1,
+		"rss": {"/news/europe": "/sections/europe/rss.xml", "/news/business": "/sections/business/rss.xml"},
+		"urlnorm": {"keep_params": ["page"], "slash": "add"}},
+	"test.economist.com": {"inherit": "www.economist.com", "desired_number": 3,
+		"urlnorm": {"scheme": "https"}}
+}`
+
+// the same as TOML
+const economistTOML = `
+# economist and its test clone
+[unspecified]
+desired_number = 5
+depth_tolerance = 0
+condense_trailing_dirs = 0
+["unspecified".rss]
+"/" = "/rss.xml"
+
+["www.economist.com"]
+condense_trailing_dirs = 2   # /news/europe/2015/07/01/... => /news/europe
+depth_tolerance = 1
+["www.economist.com".rss]
+"/news/europe" = "/sections/europe/rss.xml"
+"/news/business" = "/sections/business/rss.xml"
+["www.economist.com".urlnorm]
+keep_params = ["page"]
+slash = "add"
+
+["test.economist.com"]
+inherit = "www.economist.com"
+desired_number = 3
+["test.economist.com".urlnorm]
+scheme = "https"
+`
+
+func TestEffective(t *testing.T) {
+	for _, fn := range []string{"economist.json", "economist.toml"} {
+		content := economist
+		if fn == "economist.toml" {
+			content = economistTOML
+		}
+		cfg, errs := Load(map[string][]byte{fn: []byte(content)})
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		testEffective(t, cfg)
+	}
+}
+
+func testEffective(t *testing.T, cfg *Config) {
+
+	e := cfg.Effective("test.economist.com")
+	if e.DesiredNumber != 3 || e.DepthTolerance != 1 || e.CondenseTrailingDirs != 2 {
+		t.Errorf("%+v", e)
+	}
+	if strings.Join(e.Chain, ",") != "test.economist.com,www.economist.com,unspecified" {
+		t.Errorf("chain %v", e.Chain)
+	}
+	if len(e.RSS) != 3 || e.RSS["/news/europe"] != "/sections/europe/rss.xml" {
+		t.Errorf("rss %v", e.RSS)
+	}
+	if e.Sources["DepthTolerance"] != "www.economist.com" || e.Sources["RSS /"] != Root {
+		t.Errorf("sources %v", e.Sources)
+	}
+
+	if r := e.URLNorm; r.Scheme != "https" || r.Slash != urlnorm.AddSlash || len(r.KeepParams) != 1 ||
+		len(r.IndexFiles) != len(urlnorm.Default.IndexFiles) {
+		t.Errorf("urlnorm %+v", r)
+	}
+
+	e = cfg.Effective("www.welt.de")
+	if e.DesiredNumber != 5 || len(e.Chain) != 1 || e.Chain[0] != Root {
+		t.Errorf("unknown host %+v", e)
+	}
+
+	def, hosts := cfg.URLNorm()
+	if def.Slash != urlnorm.StripSlash || len(hosts) != 2 || hosts["www.economist.com"].Scheme != "" {
+		t.Errorf("urlnorm of all hosts %+v %+v", def, hosts)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	cases := []struct {
+		name, content string
+		want          []string
+	}{
+		{"a.toml", "[www.welt.de]\ndesired_number = 3\n", []string{"a.toml:1", "quote host names"}},
+		{"a.toml", "[\"www.welt.de\"]\ndesired_numbr = 3\n", []string{"a.toml:2", `did you mean "desired_number"`}},
+		{"a.toml", "[\"www.welt.de\"]\ndepth_tolerance = \"two\"\n", []string{"a.toml:2", "must be an integer"}},
+		{"a.toml", "[\"www.welt.de\".urlnorm]\nstrip_params = [\"a\" \"b\"]\n", []string{"a.toml:2", "want , or ]"}},
+		{"a.toml", "[\"www.welt.de\".urlnorm]\ndrop_query = \"yes\"\n", []string{"a.toml:2", "must be true or false"}},
+		{"b.json", `{"localhost:8085": {"desired_number": 3}, "www.welt.de/x": {}}`, []string{`"www.welt.de/x": must be a lower case host name`}},
+		{"b.json", `{"www.welt.de": {"desired_numbr": 3}}`, []string{"b.json", `did you mean "desired_number"`}},
+		{"b.json", `{"www.welt.de": {"depth_tolerance": "two"}}`, []string{"b.json", "must be an integer"}},
+		{"b.json", `{"www.welt.de": {"inherit": "www.welt.com"}}`, []string{`inherits from "www.welt.com"`}},
+		{"b.json", `{"a.de": {"inherit": "b.de"}, "b.de": {"inherit": "a.de"}}`, []string{"cycle"}},
+		{"a.json", `{"www.welt.de": {"desired_number": 0}}`, []string{"desired_number is 0; must be between 1 and 1000"}},
+		{"a.json", `{"www.welt.de": {"rss": {"news": "rss.xml"}}}`, []string{"must be a path", "absolute http url"}},
+		{"a.json", "{\n\"www.welt.de\": {,}}", []string{"a.json:2"}},
+		{"a.json", `{"www.welt.de": {"urlnorm": {"strip_param": ["x"]}}}`, []string{`did you mean "strip_params"`}},
+		{"a.json", `{"www.welt.de": {"urlnorm": {"slash": "both"}}}`, []string{"must be one of strip, keep, add"}},
+		{"b.json", `{"unspecified": {}}`, []string{"already defined in a.json"}},
+	}
+
+	for i, c := range cases {
+		files := map[string][]byte{"a.json": []byte(seed), c.name: []byte(c.content)}
+		if c.name == "a.json" {
+			files["root.json"] = []byte(seed)
+		}
+		_, errs := Load(files)
+		all := ""
+		for _, err := range errs {
+			all += err.Error() + "\n"
+		}
+		for _, w := range c.want {
+			if !strings.Contains(all, w) {
+				t.Errorf("case %v: want %q in\n%v", i, w, all)
+			}
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+
+	fs := memfs.New()
+	s := NewStore("/config", "default.json", []byte(seed))
+	s.CheckEvery = 0
+
+	if cfg := s.Config(fs); cfg.Effective("www.welt.de").DesiredNumber != 5 {
+		t.Fatal("seed not in effect")
+	}
+	if _, err := fs.ReadFile("/config/default.json"); err != nil {
+		t.Fatalf("seed not written: %v", err)
+	}
+
+	fs.WriteFile("/config/welt.json", []byte(`{"www.welt.de": {"desired_number": 7}}`), 0644)
+	if n := s.Config(fs).Effective("www.welt.de").DesiredNumber; n != 7 {
+		t.Errorf("not reloaded: %v", n)
+	}
+	fs.WriteFile("/config/local.toml", []byte("[\"localhost:8085\"]\ndesired_number = 2\n"), 0644)
+	if n := s.Config(fs).Effective("localhost:8085").DesiredNumber; n != 2 {
+		t.Errorf("toml file ignored: %v", n)
+	}
+
+	// an invalid edit keeps the previous config
+	time.Sleep(2 * time.Millisecond)
+	fs.WriteFile("/config/welt.json", []byte(`{"www.welt.de": {"desired_number": 7000}}`), 0644)
+	if n := s.Config(fs).Effective("www.welt.de").DesiredNumber; n != 7 {
+		t.Errorf("invalid config applied: %v", n)
+	}
+	if len(s.Errors()) != 1 {
+		t.Errorf("errors %v", s.Errors())
+	}
+}
+
+func TestOnLoad(t *testing.T) {
+
+	fs := memfs.New()
+	s := NewStore("/config", "default.json", []byte(seed))
+	s.CheckEvery = 0
+	loads := 0
+	s.OnLoad = func(*Config) { loads++ }
+
+	s.Config(fs)
+	fs.WriteFile("/config/welt.json", []byte(`{"www.welt.de": {"urlnorm": {"drop_query": true}}}`), 0644)
+	s.Config(fs)
+	s.Config(fs) // unchanged
+	if loads != 2 {
+		t.Errorf("want seed and one reload; got %v loads", loads)
+	}
+}
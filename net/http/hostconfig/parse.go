@@ -0,0 +1,484 @@
+package hostconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ls_core "github.com/pbberlin/tools/text/levenshtein"
+	ls_rune "github.com/pbberlin/tools/text/levenshtein/rune"
+)
+
+var knownKeys = []string{"inherit", "desired_number", "condense_trailing_dirs", "depth_tolerance", "rss", "urlnorm"}
+
+// entry is a key value pair of a host, from either format.
+type entry struct {
+	key  string
+	val  interface{} // string, int, bool, []string or map[string]interface{} of these
+	line int         // zero for JSON
+}
+
+// Parse reads the hosts of one file.
+// Files ending in .toml are read as TOML, all others as JSON.
+func Parse(name string, b []byte) ([]*Host, []error) {
+
+	var order []string
+	var byHost map[string][]entry
+	var errs []error
+	if strings.HasSuffix(name, ".toml") {
+		order, byHost, errs = parseTOML(name, b)
+	} else {
+		order, byHost, errs = parseJSON(name, b)
+	}
+
+	hosts := []*Host{}
+	for _, hn := range order {
+		h := &Host{Name: hn, File: name}
+		for _, e := range byHost[hn] {
+			if err := h.set(e); err != nil {
+				errs = append(errs, fmt.Errorf("%v: host %q: %v", pos(name, e.line), hn, err))
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, errs
+}
+
+func pos(name string, line int) string {
+	if line == 0 {
+		return name
+	}
+	return fmt.Sprintf("%v:%v", name, line)
+}
+
+func (h *Host) set(e entry) error {
+
+	intVal := func() (*int, error) {
+		i, ok := e.val.(int)
+		if !ok {
+			return nil, fmt.Errorf("%v must be an integer; got %v", e.key, describe(e.val))
+		}
+		return &i, nil
+	}
+
+	var err error
+	switch e.key {
+	case "inherit":
+		s, ok := e.val.(string)
+		if !ok {
+			return fmt.Errorf("inherit must be a host name string; got %v", describe(e.val))
+		}
+		h.Inherit = s
+	case "desired_number":
+		h.DesiredNumber, err = intVal()
+	case "condense_trailing_dirs":
+		h.CondenseTrailingDirs, err = intVal()
+	case "depth_tolerance":
+		h.DepthTolerance, err = intVal()
+	case "rss":
+		mp, ok := e.val.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("rss must map search prefixes to rss uris; got %v", describe(e.val))
+		}
+		if h.RSS == nil {
+			h.RSS = map[string]string{}
+		}
+		for k, v := range mp {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("rss uri of %q must be a string; got %v", k, describe(v))
+			}
+			h.RSS[k] = s
+		}
+	case "urlnorm":
+		mp, ok := e.val.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("urlnorm must be a table of rules; got %v", describe(e.val))
+		}
+		if h.URLNorm == nil {
+			h.URLNorm = map[string]interface{}{}
+		}
+		for k, v := range mp {
+			if err := checkURLNorm(k, v); err != nil {
+				return fmt.Errorf("urlnorm %v", err)
+			}
+			h.URLNorm[k] = v
+		}
+	default:
+		msg := fmt.Sprintf("unknown key %q", e.key)
+		if s := suggest(e.key, knownKeys); s != "" {
+			msg += fmt.Sprintf("; did you mean %q?", s)
+		}
+		return fmt.Errorf("%v", msg)
+	}
+	return err
+}
+
+func describe(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case int:
+		return fmt.Sprintf("integer %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []string:
+		return "a list"
+	case map[string]interface{}:
+		return "a table"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// suggest returns the known key closest to a misspelled one.
+func suggest(key string, known []string) string {
+	k := strings.Replace(strings.ToLower(key), "-", "_", -1)
+	best, bestDist := "", 4
+	for _, cand := range known {
+		if d := levenshtein(k, cand); d < bestDist {
+			best, bestDist = cand, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	mx := ls_core.New(runeTokens(a), runeTokens(b), ls_core.DefaultOptions)
+	d, _ := mx.Distance()
+	return d
+}
+
+func runeTokens(s string) []ls_core.Equaler {
+	toks := []ls_rune.Token{}
+	for _, r := range s {
+		toks = append(toks, ls_rune.Token(r))
+	}
+	return ls_rune.WrapAsEqualer(toks)
+}
+
+//
+// JSON
+//
+
+func parseJSON(name string, b []byte) ([]string, map[string][]entry, []error) {
+
+	raw := map[string]map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		if se, ok := err.(*json.SyntaxError); ok {
+			line := 1 + bytes.Count(b[:se.Offset], []byte("\n"))
+			return nil, nil, []error{fmt.Errorf("%v: %v", pos(name, line), err)}
+		}
+		return nil, nil, []error{fmt.Errorf("%v: want an object of hosts, each an object of settings: %v", name, err)}
+	}
+
+	var errs []error
+	order := []string{}
+	byHost := map[string][]entry{}
+	for hn, kv := range raw {
+		order = append(order, hn)
+		keys := []string{}
+		for k := range kv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, err := jsonValue(kv[k])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: host %q: %v: %v", name, hn, k, err))
+				continue
+			}
+			byHost[hn] = append(byHost[hn], entry{key: k, val: v})
+		}
+	}
+	sort.Strings(order)
+	return order, byHost, errs
+}
+
+func jsonValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case bool:
+		return t, nil
+	case json.Number:
+		i, err := strconv.Atoi(t.String())
+		if err != nil {
+			return nil, fmt.Errorf("%v is no integer", t)
+		}
+		return i, nil
+	case []interface{}:
+		l := []string{}
+		for _, v := range t {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("lists may only hold strings; got %v", v)
+			}
+			l = append(l, s)
+		}
+		return l, nil
+	case map[string]interface{}:
+		mp := map[string]interface{}{}
+		for k, v := range t {
+			if _, ok := v.(map[string]interface{}); ok {
+				return nil, fmt.Errorf("value of %q: tables cannot be nested", k)
+			}
+			val, err := jsonValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("value of %q: %v", k, err)
+			}
+			mp[k] = val
+		}
+		return mp, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", v)
+}
+
+//
+// TOML
+//
+
+// tomlTables are the sub tables of a host, i.e. [host.rss].
+var tomlTables = map[string]bool{"rss": true, "urlnorm": true}
+
+// parseTOML reads the subset of TOML we need:
+// [host], [host.rss] and [host.urlnorm] tables,
+// key = "string", integer, true, false or ["list", "of strings"] on one line,
+// and # comments. Host names containing dots must be quoted.
+func parseTOML(name string, b []byte) ([]string, map[string][]entry, []error) {
+
+	var errs []error
+	order := []string{}
+	byHost := map[string][]entry{}
+	tables := map[string]bool{} // headers seen
+
+	host, sub := "", ""
+	skip := false // keys below a broken header
+	for i, line := range strings.Split(string(b), "\n") {
+		ln := i + 1
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("%v: %v", pos(name, ln), fmt.Sprintf(format, args...)))
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			parts, rest, err := tomlKeyPath(line[1:])
+			rest = stripComment(rest)
+			if err == nil && rest != "]" {
+				err = fmt.Errorf("table header must end with ]")
+			}
+			if err != nil {
+				fail("%v", err)
+				host, skip = "", true
+				continue
+			}
+			skip = false
+			switch {
+			case len(parts) == 1:
+				host, sub = parts[0], ""
+			case len(parts) == 2 && tomlTables[parts[1]]:
+				host, sub = parts[0], parts[1]
+			default:
+				fail("unexpected table [%v]; quote host names containing dots, like [\"www.economist.com\"]", strings.Join(parts, "."))
+				host, skip = "", true
+				continue
+			}
+			if _, ok := byHost[host]; !ok {
+				order = append(order, host)
+				byHost[host] = nil
+			}
+			if tables[strings.Join(parts, ".")] {
+				fail("table [%v] defined twice", strings.Join(parts, "."))
+			}
+			tables[strings.Join(parts, ".")] = true
+			continue
+		}
+
+		if skip {
+			continue
+		}
+		if host == "" {
+			fail("key outside of a host table")
+			continue
+		}
+		parts, rest, err := tomlKeyPath(line)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+		if len(parts) != 1 {
+			fail("dotted key %v; quote keys containing dots", strings.Join(parts, "."))
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
+			fail("expected = after key %q", parts[0])
+			continue
+		}
+		val, err := tomlValue(stripComment(rest[1:]))
+		if err != nil {
+			fail("%v: %v", parts[0], err)
+			continue
+		}
+
+		if sub != "" {
+			byHost[host] = append(byHost[host], entry{key: sub, val: map[string]interface{}{parts[0]: val}, line: ln})
+			continue
+		}
+		byHost[host] = append(byHost[host], entry{key: parts[0], val: val, line: ln})
+	}
+	return order, byHost, errs
+}
+
+// tomlKeyPath reads dotted, bare or quoted keys from the start of s.
+func tomlKeyPath(s string) ([]string, string, error) {
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return nil, "", fmt.Errorf("missing key")
+		}
+		var key string
+		if s[0] == '"' {
+			var err error
+			key, s, err = tomlString(s)
+			if err != nil {
+				return nil, "", err
+			}
+		} else {
+			i := 0
+			for i < len(s) && isBare(s[i]) {
+				i++
+			}
+			if i == 0 {
+				return nil, "", fmt.Errorf("invalid key at %q", s)
+			}
+			key, s = s[:i], s[i:]
+		}
+		parts = append(parts, key)
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ".") {
+			return parts, s, nil
+		}
+		s = s[1:]
+	}
+}
+
+func isBare(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// tomlString reads a basic "string" from the start of s.
+func tomlString(s string) (string, string, error) {
+	var b bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			default:
+				return "", "", fmt.Errorf("unsupported escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+func tomlValue(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	switch s[0] {
+	case '"':
+		v, rest, err := tomlString(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after string", rest)
+		}
+		return v, nil
+	case '[':
+		return tomlList(s)
+	case '{':
+		return nil, fmt.Errorf("inline tables are not supported; use a [host.rss] or [host.urlnorm] table")
+	}
+	if s == "true" || s == "false" {
+		return s == "true", nil
+	}
+	i, err := strconv.Atoi(strings.Replace(s, "_", "", -1))
+	if err != nil {
+		return nil, fmt.Errorf("want a \"string\", an integer, true, false or a [list]; got %v", s)
+	}
+	return i, nil
+}
+
+// tomlList reads a list of strings on one line, i.e. ["utm_*", "fsrc"].
+func tomlList(s string) ([]string, error) {
+	l := []string{}
+	s = strings.TrimSpace(s[1:])
+	for {
+		if strings.HasPrefix(s, "]") {
+			if strings.TrimSpace(s[1:]) != "" {
+				return nil, fmt.Errorf("unexpected %q after list", s[1:])
+			}
+			return l, nil
+		}
+		if !strings.HasPrefix(s, "\"") {
+			return nil, fmt.Errorf("lists may only hold \"strings\" on one line; got %v", s)
+		}
+		v, rest, err := tomlString(s)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, v)
+		s = strings.TrimSpace(rest)
+		if strings.HasPrefix(s, ",") {
+			s = strings.TrimSpace(s[1:])
+		} else if !strings.HasPrefix(s, "]") {
+			return nil, fmt.Errorf("want , or ] after %q", v)
+		}
+	}
+}
+
+// stripComment removes a trailing # comment outside of strings.
+func stripComment(s string) string {
+	inStr := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inStr {
+				i++
+			}
+		case '"':
+			inStr = !inStr
+		case '#':
+			if !inStr {
+				return strings.TrimSpace(s[:i])
+			}
+		}
+	}
+	return strings.TrimSpace(s)
+}
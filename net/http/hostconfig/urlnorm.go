@@ -0,0 +1,69 @@
+package hostconfig
+
+import (
+	"fmt"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+)
+
+// urlnormKeys are the rules of urlnorm.Rules, as named in the files.
+var urlnormKeys = []string{"strip_params", "keep_params", "drop_query", "index_files", "slash", "scheme"}
+
+var slashRules = map[string]urlnorm.SlashRule{
+	"strip": urlnorm.StripSlash,
+	"keep":  urlnorm.KeepSlash,
+	"add":   urlnorm.AddSlash,
+}
+
+// checkURLNorm validates one urlnorm rule.
+func checkURLNorm(k string, v interface{}) error {
+	wantList := func() error {
+		if _, ok := v.([]string); !ok {
+			return fmt.Errorf("%v must be a list of strings; got %v", k, describe(v))
+		}
+		return nil
+	}
+	switch k {
+	case "strip_params", "keep_params", "index_files":
+		return wantList()
+	case "drop_query":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%v must be true or false; got %v", k, describe(v))
+		}
+	case "slash":
+		s, _ := v.(string)
+		if _, ok := slashRules[s]; !ok {
+			return fmt.Errorf("slash must be one of strip, keep, add; got %v", describe(v))
+		}
+	case "scheme":
+		s, _ := v.(string)
+		if s != "" && s != "http" && s != "https" {
+			return fmt.Errorf("scheme must be empty, http or https; got %v", describe(v))
+		}
+	default:
+		msg := fmt.Sprintf("unknown rule %q", k)
+		if s := suggest(k, urlnormKeys); s != "" {
+			msg += fmt.Sprintf("; did you mean %q?", s)
+		}
+		return fmt.Errorf("%v", msg)
+	}
+	return nil
+}
+
+// setURLNorm applies a rule, which passed checkURLNorm.
+func setURLNorm(r *urlnorm.Rules, k string, v interface{}) {
+	switch k {
+	case "strip_params":
+		r.StripParams = v.([]string)
+	case "keep_params":
+		r.KeepParams = v.([]string)
+	case "drop_query":
+		r.DropQuery = v.(bool)
+	case "index_files":
+		r.IndexFiles = v.([]string)
+	case "slash":
+		r.Slash = slashRules[v.(string)]
+	case "scheme":
+		r.Scheme = v.(string)
+	}
+}
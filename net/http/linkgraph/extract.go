@@ -0,0 +1,106 @@
+package linkgraph
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Extract returns the http links of a page, resolved against base
+// or a <base href>. Links to javascript:, mailto: and the like are dropped.
+func Extract(page []byte, base *url.URL) []Link {
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+
+	var links []Link
+	var fr func(n *html.Node, outline []int)
+	fr = func(n *html.Node, outline []int) {
+
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Base:
+				if u, err := resolve(base, dom.Attr(n.Attr, "href")); err == nil && base != nil {
+					base = u
+				}
+			case atom.A, atom.Area:
+				if u, err := resolve(base, dom.Attr(n.Attr, "href")); err == nil {
+					links = append(links, Link{
+						To:      u.String(),
+						Text:    text(n),
+						Rel:     strings.ToLower(strings.TrimSpace(dom.Attr(n.Attr, "rel"))),
+						Outline: outlineString(outline),
+					})
+				}
+			}
+		}
+
+		// the outline starts below <body>
+		inBody := len(outline) > 0 || n.DataAtom == atom.Body
+		cnt := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !inBody || c.Type != html.ElementNode {
+				fr(c, outline)
+				continue
+			}
+			cnt++
+			fr(c, append(outline[:len(outline):len(outline)], cnt))
+		}
+	}
+	fr(doc, nil)
+	return links
+}
+
+func resolve(base *url.URL, href string) (*url.URL, error) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return nil, fmt.Errorf("no link")
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("no http link: %v", href)
+	}
+	return u, nil
+}
+
+func outlineString(outline []int) string {
+	s := make([]string, len(outline))
+	for i, v := range outline {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ".")
+}
+
+// text of an anchor; images contribute their alt text.
+func text(n *html.Node) string {
+	var b bytes.Buffer
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		if n.DataAtom == atom.Img {
+			b.WriteString(dom.Attr(n.Attr, "alt"))
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,327 @@
+// Package linkgraph keeps the links between crawled pages.
+//
+// The outbound links of each page are stored with anchor text,
+// rel attribute and the outline position of the anchor.
+// An inbound index is maintained alongside;
+// it answers queries like the most linked articles
+// below /news/europe within the last week.
+//
+// Pages and link targets are identified by their urlnorm form without scheme,
+// i.e. www.economist.com/news/europe/21661810-journey.
+//
+// Each page is stored in a file of its own, named by the hash of its key,
+// so that no file grows with the crawl;
+// Save writes only the pages changed since Open.
+package linkgraph
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+type Link struct {
+	From    string `json:",omitempty"` // only set in query results
+	To      string
+	Text    string `json:",omitempty"`
+	Rel     string `json:",omitempty"` // i.e. nofollow
+	Outline string `json:",omitempty"` // position of the anchor below <body>, i.e. 1.3.2
+}
+
+// Page is a crawled page with its outbound links.
+type Page struct {
+	URL     string
+	Fetched time.Time
+	Links   []Link
+}
+
+// Rank is a result of MostLinked.
+type Rank struct {
+	URL     string
+	Pages   int // distinct linking pages
+	Anchors []string
+}
+
+type Graph struct {
+	fs  fsi.FileSystem
+	dir string
+
+	mu      sync.Mutex
+	pages   map[string]*Page
+	inbound map[string]map[string]bool // to => from
+	dirty   map[string]bool            // pages to save
+	legacy  bool                       // loaded from dir + ".json"
+}
+
+// Open loads the pages from dir - or starts empty.
+// A graph, which earlier versions saved in one file dir + ".json",
+// is read instead and split into pages on the next Save.
+func Open(fs fsi.FileSystem, dir string) (*Graph, error) {
+	g := &Graph{
+		fs:      fs,
+		dir:     dir,
+		pages:   map[string]*Page{},
+		inbound: map[string]map[string]bool{},
+		dirty:   map[string]bool{},
+	}
+
+	fis, _ := fs.ReadDir(dir)
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		fn := path.Join(dir, fi.Name())
+		bts, err := fs.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fn, err)
+		}
+		p := &Page{}
+		err = json.Unmarshal(bts, p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", fn, err)
+		}
+		g.pages[p.URL] = p
+		g.index(p)
+	}
+	if len(g.pages) > 0 {
+		return g, nil
+	}
+
+	bts, err := fs.ReadFile(dir + ".json")
+	if err != nil {
+		return g, nil
+	}
+	pages := []*Page{}
+	err = json.Unmarshal(bts, &pages)
+	if err != nil {
+		return nil, fmt.Errorf("%v.json: %v", dir, err)
+	}
+	for _, p := range pages {
+		g.pages[p.URL] = p
+		g.index(p)
+		g.dirty[p.URL] = true
+	}
+	g.legacy = true
+	return g, nil
+}
+
+// file of page key
+func (g *Graph) file(key string) string {
+	return path.Join(g.dir, fmt.Sprintf("%x.json", sha1.Sum([]byte(key))))
+}
+
+// Save writes the pages changed since Open or the previous Save;
+// the inbound index is rebuilt on Open.
+// Pages failing to save remain to be saved.
+func (g *Graph) Save() error {
+	g.mu.Lock()
+	bts := map[string][]byte{}
+	for k := range g.dirty {
+		b, err := json.MarshalIndent(g.pages[k], "", "\t")
+		if err != nil {
+			g.mu.Unlock()
+			return err
+		}
+		bts[k] = b
+	}
+	g.dirty = map[string]bool{}
+	legacy := g.legacy
+	g.mu.Unlock()
+
+	var errs []string
+	for k, b := range bts {
+		if err := common.WriteFile(g.fs, g.file(k), b); err != nil {
+			errs = append(errs, err.Error())
+			g.mu.Lock()
+			g.dirty[k] = true
+			g.mu.Unlock()
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("saving %v of %v pages: %v", len(errs), len(bts), errs[0])
+	}
+
+	if legacy {
+		g.mu.Lock()
+		g.legacy = false
+		g.mu.Unlock()
+		return g.fs.Remove(g.dir + ".json")
+	}
+	return nil
+}
+
+// Key is the identity of a URL in the graph.
+func Key(surl string) string {
+	n, err := urlnorm.Normalize(surl)
+	if err != nil {
+		return surl
+	}
+	if i := strings.Index(n, "://"); i >= 0 {
+		n = n[i+3:]
+	}
+	return n
+}
+
+// SetLinks replaces the outbound links of page surl.
+func (g *Graph) SetLinks(surl string, fetched time.Time, links []Link) {
+	from := Key(surl)
+	p := &Page{URL: from, Fetched: fetched, Links: make([]Link, 0, len(links))}
+	for _, l := range links {
+		l.From = ""
+		l.To = Key(l.To)
+		if l.To == from {
+			continue // self links, i.e. #comments
+		}
+		p.Links = append(p.Links, l)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if old, ok := g.pages[from]; ok {
+		g.unindex(old)
+	}
+	g.pages[from] = p
+	g.index(p)
+	g.dirty[from] = true
+}
+
+func (g *Graph) index(p *Page) {
+	for _, l := range p.Links {
+		if g.inbound[l.To] == nil {
+			g.inbound[l.To] = map[string]bool{}
+		}
+		g.inbound[l.To][p.URL] = true
+	}
+}
+
+func (g *Graph) unindex(p *Page) {
+	for _, l := range p.Links {
+		delete(g.inbound[l.To], p.URL)
+		if len(g.inbound[l.To]) == 0 {
+			delete(g.inbound, l.To)
+		}
+	}
+}
+
+// Outbound returns the links of page surl.
+func (g *Graph) Outbound(surl string) []Link {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, ok := g.pages[Key(surl)]
+	if !ok {
+		return nil
+	}
+	ret := make([]Link, len(p.Links))
+	for i, l := range p.Links {
+		l.From = p.URL
+		ret[i] = l
+	}
+	return ret
+}
+
+// Inbound returns the links pointing to surl, ordered by source.
+func (g *Graph) Inbound(surl string) []Link {
+	to := Key(surl)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	froms := make([]string, 0, len(g.inbound[to]))
+	for from := range g.inbound[to] {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	ret := []Link{}
+	for _, from := range froms {
+		for _, l := range g.pages[from].Links {
+			if l.To == to {
+				l.From = from
+				ret = append(ret, l)
+			}
+		}
+	}
+	return ret
+}
+
+// MostLinked ranks the targets below prefix - i.e. www.economist.com/news/europe -
+// by the number of pages linking to them,
+// counting only pages fetched since.
+// n <= 0 returns all.
+func (g *Graph) MostLinked(prefix string, since time.Time, n int) []Rank {
+	prefix = Key(prefix)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ranks := ranking{}
+	for to, froms := range g.inbound {
+		if !underPrefix(to, prefix) {
+			continue
+		}
+		rk := Rank{URL: to}
+		for from := range froms {
+			p := g.pages[from]
+			if p.Fetched.Before(since) {
+				continue
+			}
+			rk.Pages++
+			for _, l := range p.Links {
+				if l.To == to && l.Text != "" {
+					rk.Anchors = appendUnique(rk.Anchors, l.Text)
+				}
+			}
+		}
+		if rk.Pages > 0 {
+			sort.Strings(rk.Anchors)
+			ranks = append(ranks, rk)
+		}
+	}
+	sort.Sort(ranks)
+	if n > 0 && len(ranks) > n {
+		ranks = ranks[:n]
+	}
+	return ranks
+}
+
+// underPrefix matches whole path segments;
+// /news/europe does not contain /news/europeans.
+func underPrefix(key, prefix string) bool {
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	rest := key[len(prefix):]
+	return rest == "" || strings.HasSuffix(prefix, "/") || rest[0] == '/' || rest[0] == '?'
+}
+
+// Len is the number of pages.
+func (g *Graph) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.pages)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, x := range list {
+		if x == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+type ranking []Rank
+
+func (s ranking) Len() int      { return len(s) }
+func (s ranking) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ranking) Less(i, j int) bool {
+	if s[i].Pages != s[j].Pages {
+		return s[i].Pages > s[j].Pages
+	}
+	return s[i].URL < s[j].URL
+}
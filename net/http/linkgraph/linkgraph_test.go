@@ -0,0 +1,133 @@
+package linkgraph
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestExtract(t *testing.T) {
+	page := []byte(`<html><body>
+		<div><a href="/news/europe/a1?fsrc=rss">Refugees</a></div>
+		<div><p>see <a href="http://other.com/x" rel="nofollow">elsewhere</a>
+			<a href="#comments">comments</a> <a href="mailto:x@y.z">mail</a>
+			<a href="a2"><img alt="Journey" src="j.jpg"></a></p></div>
+		</body></html>`)
+	base, _ := url.Parse("http://www.economist.com/news/europe/a0")
+
+	links := Extract(page, base)
+	if len(links) != 3 {
+		t.Fatalf("want 3 links; got %+v", links)
+	}
+	if l := links[0]; l.To != "http://www.economist.com/news/europe/a1?fsrc=rss" || l.Text != "Refugees" || l.Outline != "1.1" {
+		t.Errorf("%+v", l)
+	}
+	if l := links[1]; l.Rel != "nofollow" || l.Outline != "2.1.1" {
+		t.Errorf("%+v", l)
+	}
+	if l := links[2]; l.To != "http://www.economist.com/news/europe/a2" || l.Text != "Journey" {
+		t.Errorf("%+v", l)
+	}
+}
+
+func TestGraph(t *testing.T) {
+
+	fs := memfs.New()
+	g, err := Open(fs, "/links/www.economist.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	old := now.Add(-30 * 24 * time.Hour)
+	l := func(to, text string) Link { return Link{To: to, Text: text} }
+
+	g.SetLinks("http://www.economist.com/", now, []Link{
+		l("http://www.economist.com/news/europe/a1", "Refugees"),
+		l("http://www.economist.com/news/europe/a2", "Journey"),
+		l("http://www.economist.com/news/europeans", "Europeans"),
+	})
+	g.SetLinks("www.economist.com/news/europe", now, []Link{
+		l("http://www.economist.com/news/europe/a1?utm_source=x", "Migrants"),
+		l("http://www.economist.com/news/europe", "self"),
+	})
+	g.SetLinks("www.economist.com/archive", old, []Link{
+		l("http://www.economist.com/news/europe/a2", "Old"),
+		l("http://www.economist.com/news/europe/a2", "Older"),
+	})
+
+	week := now.Add(-7 * 24 * time.Hour)
+	ranks := g.MostLinked("www.economist.com/news/europe", week, 0)
+	if len(ranks) != 2 || ranks[0].URL != "www.economist.com/news/europe/a1" || ranks[0].Pages != 2 || ranks[1].Pages != 1 {
+		t.Errorf("this week: %+v", ranks)
+	}
+	ranks = g.MostLinked("www.economist.com/news/europe", time.Time{}, 1)
+	if len(ranks) != 1 || ranks[0].Pages != 2 {
+		t.Errorf("ever: %+v", ranks)
+	}
+
+	if in := g.Inbound("www.economist.com/news/europe/a2"); len(in) != 3 || in[0].From != "www.economist.com/" {
+		t.Errorf("inbound: %+v", in)
+	}
+	if out := g.Outbound("http://www.economist.com/news/europe#top"); len(out) != 1 {
+		t.Errorf("self link kept: %+v", out)
+	}
+
+	// replacing links updates the inbound index
+	g.SetLinks("www.economist.com/archive", now, nil)
+	if in := g.Inbound("www.economist.com/news/europe/a2"); len(in) != 1 {
+		t.Errorf("stale inbound: %+v", in)
+	}
+
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+	g2, err := Open(fs, "/links/www.economist.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2.Len() != 3 || len(g2.Inbound("www.economist.com/news/europe/a1")) != 2 {
+		t.Errorf("reloaded: %v pages", g2.Len())
+	}
+	if fis, _ := fs.ReadDir("/links/www.economist.com"); len(fis) != 3 {
+		t.Errorf("want one file per page; got %v", len(fis))
+	}
+
+	// only changed pages are written
+	fn := g2.file(Key("www.economist.com/archive"))
+	fs.Remove(fn)
+	g2.SetLinks("www.economist.com/news/europe", now, nil)
+	if err := g2.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(fn); err == nil {
+		t.Errorf("unchanged page written")
+	}
+}
+
+func TestLegacyFile(t *testing.T) {
+
+	fs := memfs.New()
+	fs.MkdirAll("/links", 0755)
+	fs.WriteFile("/links/www.a.com.json", []byte(`[{"URL": "www.a.com/", "Links": [{"To": "www.a.com/b"}]}]`), 0644)
+
+	g, err := Open(fs, "/links/www.a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Inbound("www.a.com/b")) != 1 {
+		t.Errorf("legacy file not read")
+	}
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/links/www.a.com.json"); err == nil {
+		t.Errorf("legacy file kept")
+	}
+	g, _ = Open(fs, "/links/www.a.com")
+	if g.Len() != 1 {
+		t.Errorf("not split into pages: %v", g.Len())
+	}
+}
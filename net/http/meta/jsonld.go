@@ -0,0 +1,139 @@
+package meta
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// articleTypes are the schema.org types we take metadata from.
+var articleTypes = map[string]bool{
+	"article":              true,
+	"newsarticle":          true,
+	"reportagenewsarticle": true,
+	"analysisnewsarticle":  true,
+	"opinionnewsarticle":   true,
+	"blogposting":          true,
+}
+
+func isArticleType(v interface{}) bool {
+	for _, t := range strs(v) {
+		t = strings.ToLower(t)
+		t = t[strings.LastIndex(t, "/")+1:] // http://schema.org/NewsArticle
+		if articleTypes[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonLD fills m from the first article object of a ld+json script.
+// The script may contain an object, an array or a @graph.
+func jsonLD(b []byte, m *Meta) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return
+	}
+	obj := findArticle(v)
+	if obj == nil || m.Title != "" {
+		return
+	}
+
+	m.Title = str(obj["headline"])
+	if m.Title == "" {
+		m.Title = str(obj["name"])
+	}
+	m.Subtitle = str(obj["alternativeHeadline"])
+	if m.Subtitle == "" {
+		m.Subtitle = str(obj["description"])
+	}
+	m.Authors = names(obj["author"])
+	m.Published = parseTime(str(obj["datePublished"]))
+	m.Updated = parseTime(str(obj["dateModified"]))
+	m.Canonical = str(obj["url"])
+	if m.Canonical == "" {
+		m.Canonical = str(obj["mainEntityOfPage"])
+	}
+	m.Language = str(obj["inLanguage"])
+	m.Section = str(obj["articleSection"])
+	m.Image = str(obj["image"])
+	if kws := str(obj["keywords"]); strings.Contains(kws, ",") {
+		for _, kw := range strings.Split(kws, ",") {
+			m.Keywords = appendUnique(m.Keywords, strings.TrimSpace(kw))
+		}
+	} else {
+		m.Keywords = strs(obj["keywords"])
+	}
+}
+
+func findArticle(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if obj := findArticle(e); obj != nil {
+				return obj
+			}
+		}
+	case map[string]interface{}:
+		if isArticleType(t["@type"]) {
+			return t
+		}
+		if g, ok := t["@graph"]; ok {
+			return findArticle(g)
+		}
+	}
+	return nil
+}
+
+// str returns the first string of v.
+// For objects, it tries url, @id and name -
+// i.e. for image and mainEntityOfPage.
+func str(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case []interface{}:
+		for _, e := range t {
+			if s := str(e); s != "" {
+				return s
+			}
+		}
+	case map[string]interface{}:
+		for _, k := range []string{"url", "@id", "name"} {
+			if s := str(t[k]); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func strs(v interface{}) []string {
+	var ret []string
+	switch t := v.(type) {
+	case string:
+		ret = appendUnique(ret, strings.TrimSpace(t))
+	case []interface{}:
+		for _, e := range t {
+			ret = appendUnique(ret, str(e))
+		}
+	}
+	return ret
+}
+
+// names of persons or organizations
+func names(v interface{}) []string {
+	var ret []string
+	switch t := v.(type) {
+	case string:
+		ret = appendUnique(ret, strings.TrimSpace(t))
+	case map[string]interface{}:
+		ret = appendUnique(ret, str(t["name"]))
+	case []interface{}:
+		for _, e := range t {
+			for _, n := range names(e) {
+				ret = appendUnique(ret, n)
+			}
+		}
+	}
+	return ret
+}
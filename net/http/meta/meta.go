@@ -0,0 +1,311 @@
+// Package meta extracts article metadata from a HTML page:
+// title, subtitle, authors, publication and update time,
+// canonical URL, language, section, lead image and keywords.
+//
+// The sources are, by precedence:
+// JSON-LD (NewsArticle and its kin), OpenGraph, microdata,
+// Twitter cards, plain <meta> tags and finally <title>, <html lang>.
+// For each field, Meta.Sources records where it came from.
+package meta
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Sources of a field
+const (
+	SrcJSONLD    = "jsonld"
+	SrcOpenGraph = "og"
+	SrcMicrodata = "microdata"
+	SrcTwitter   = "twitter"
+	SrcMeta      = "meta"
+	SrcHTML      = "html" // <title>, <html lang>, <link rel=canonical>
+)
+
+var precedence = []string{SrcJSONLD, SrcOpenGraph, SrcMicrodata, SrcTwitter, SrcMeta, SrcHTML}
+
+type Meta struct {
+	Title     string
+	Subtitle  string
+	Authors   []string
+	Published time.Time
+	Updated   time.Time
+	Canonical string
+	Language  string
+	Section   string
+	Image     string
+	Keywords  []string
+
+	// field name => source, i.e. "Title" => "og"
+	Sources map[string]string
+}
+
+// Mod is the update time, else the publication time.
+func (m *Meta) Mod() time.Time {
+	if m.Updated.IsZero() {
+		return m.Published
+	}
+	return m.Updated
+}
+
+// Extract parses page and merges the metadata of all sources.
+// base resolves relative URLs of canonical and image; it may be nil.
+func Extract(page []byte, base *url.URL) *Meta {
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return &Meta{Sources: map[string]string{}}
+	}
+
+	cands := map[string]*Meta{}
+	for _, src := range precedence {
+		cands[src] = &Meta{}
+	}
+	if c, ok := urlnorm.Canonical(page, base); ok {
+		cands[SrcHTML].Canonical = c
+	}
+	walk(doc, cands, &base)
+	microdata(doc, cands[SrcMicrodata])
+
+	m := merge(cands)
+	m.Canonical = absolute(base, m.Canonical)
+	m.Image = absolute(base, m.Image)
+	return m
+}
+
+// merge takes each field from the first source having it.
+func merge(cands map[string]*Meta) *Meta {
+	m := &Meta{Sources: map[string]string{}}
+	for _, src := range precedence {
+		c := cands[src]
+		setStr(m, "Title", &m.Title, c.Title, src)
+		setStr(m, "Subtitle", &m.Subtitle, c.Subtitle, src)
+		setStr(m, "Canonical", &m.Canonical, c.Canonical, src)
+		setStr(m, "Language", &m.Language, c.Language, src)
+		setStr(m, "Section", &m.Section, c.Section, src)
+		setStr(m, "Image", &m.Image, c.Image, src)
+		setTime(m, "Published", &m.Published, c.Published, src)
+		setTime(m, "Updated", &m.Updated, c.Updated, src)
+		setList(m, "Authors", &m.Authors, c.Authors, src)
+		setList(m, "Keywords", &m.Keywords, c.Keywords, src)
+	}
+	return m
+}
+
+func setStr(m *Meta, field string, dst *string, v, src string) {
+	if *dst == "" && v != "" {
+		*dst = v
+		m.Sources[field] = src
+	}
+}
+
+func setTime(m *Meta, field string, dst *time.Time, v time.Time, src string) {
+	if dst.IsZero() && !v.IsZero() {
+		*dst = v
+		m.Sources[field] = src
+	}
+}
+
+func setList(m *Meta, field string, dst *[]string, v []string, src string) {
+	if len(*dst) == 0 && len(v) > 0 {
+		*dst = v
+		m.Sources[field] = src
+	}
+}
+
+// walk collects <head> style metadata from the whole document;
+// some sites put their <meta> tags into the body.
+func walk(n *html.Node, cands map[string]*Meta, base **url.URL) {
+
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Html:
+			cands[SrcHTML].Language = dom.Attr(n.Attr, "lang")
+		case atom.Title:
+			if cands[SrcHTML].Title == "" {
+				cands[SrcHTML].Title = text(n)
+			}
+		case atom.Base:
+			if href := dom.Attr(n.Attr, "href"); href != "" && *base != nil {
+				if u, err := (*base).Parse(href); err == nil {
+					*base = u
+				}
+			}
+		case atom.Meta:
+			metaTag(n, cands)
+		case atom.Script:
+			if strings.Contains(strings.ToLower(dom.Attr(n.Attr, "type")), "ld+json") {
+				jsonLD([]byte(text(n)), cands[SrcJSONLD])
+			}
+			return
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, cands, base)
+	}
+}
+
+func metaTag(n *html.Node, cands map[string]*Meta) {
+
+	if dom.Attr(n.Attr, "itemprop") != "" {
+		return // microdata
+	}
+
+	content := strings.TrimSpace(dom.Attr(n.Attr, "content"))
+	if content == "" {
+		return
+	}
+
+	if equiv := strings.ToLower(dom.Attr(n.Attr, "http-equiv")); equiv == "content-language" {
+		first(&cands[SrcMeta].Language, content)
+		return
+	}
+
+	// OpenGraph uses property, but name is common too
+	key := strings.ToLower(dom.Attr(n.Attr, "property"))
+	if key == "" {
+		key = strings.ToLower(dom.Attr(n.Attr, "name"))
+	}
+
+	og := cands[SrcOpenGraph]
+	tw := cands[SrcTwitter]
+	mt := cands[SrcMeta]
+
+	switch key {
+	case "og:title":
+		first(&og.Title, content)
+	case "og:description":
+		first(&og.Subtitle, content)
+	case "og:url":
+		first(&og.Canonical, content)
+	case "og:image", "og:image:url", "og:image:secure_url":
+		first(&og.Image, content)
+	case "og:locale":
+		first(&og.Language, strings.Replace(content, "_", "-", -1))
+	case "article:published_time":
+		firstTime(&og.Published, content)
+	case "article:modified_time", "og:updated_time":
+		firstTime(&og.Updated, content)
+	case "article:section":
+		first(&og.Section, content)
+	case "article:author":
+		og.Authors = appendUnique(og.Authors, content)
+	case "article:tag":
+		og.Keywords = appendUnique(og.Keywords, content)
+
+	case "twitter:title":
+		first(&tw.Title, content)
+	case "twitter:description":
+		first(&tw.Subtitle, content)
+	case "twitter:image", "twitter:image:src":
+		first(&tw.Image, content)
+	case "twitter:creator":
+		tw.Authors = appendUnique(tw.Authors, content)
+
+	case "title", "dc.title":
+		first(&mt.Title, content)
+	case "description", "dc.description":
+		first(&mt.Subtitle, content)
+	case "author", "dc.creator":
+		mt.Authors = appendUnique(mt.Authors, content)
+	case "keywords", "news_keywords":
+		for _, kw := range strings.Split(content, ",") {
+			mt.Keywords = appendUnique(mt.Keywords, strings.TrimSpace(kw))
+		}
+	case "date", "pubdate", "publish-date", "dc.date", "dc.date.issued", "dcterms.created":
+		firstTime(&mt.Published, content)
+	case "last-modified", "dcterms.modified":
+		firstTime(&mt.Updated, content)
+	case "language", "dc.language":
+		first(&mt.Language, content)
+	case "section":
+		first(&mt.Section, content)
+	}
+}
+
+func first(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
+
+func firstTime(dst *time.Time, v string) {
+	if dst.IsZero() {
+		*dst = parseTime(v)
+	}
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+var layouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseTime understands ISO 8601 variants and RFC 1123.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func absolute(base *url.URL, s string) string {
+	if s == "" || base == nil {
+		return s
+	}
+	u, err := base.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.String()
+}
+
+// text returns the concatenated text of n, with whitespace condensed.
+func text(n *html.Node) string {
+	var b bytes.Buffer
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(n)
+	if n.DataAtom == atom.Script {
+		return b.String()
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,91 @@
+package meta
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const page = `<!DOCTYPE html>
+<html lang="de">
+<head>
+	<title>Flüchtlinge - Handelsblatt</title>
+	<meta name="description" content="Plain description">
+	<meta name="keywords" content="Europa, Migration">
+	<meta name="author" content="Meta Author">
+	<meta property="og:title" content="OG Title">
+	<meta property="og:image" content="/img/lead.jpg">
+	<meta property="article:section" content="Politik">
+	<meta property="article:published_time" content="2015-10-04T10:00:00+02:00">
+	<meta name="twitter:title" content="Twitter Title">
+	<meta name="twitter:creator" content="@hb">
+	<link rel="canonical" href="/politik/fluechtlinge">
+	<script type="application/ld+json">
+	{"@context": "http://schema.org", "@graph": [
+		{"@type": "WebSite", "name": "Handelsblatt"},
+		{"@type": ["NewsArticle"], "headline": "LD Headline",
+		 "author": [{"@type": "Person", "name": "Anna A"}, {"@type": "Person", "name": "Bert B"}],
+		 "dateModified": "2015-10-05T08:00:00Z"}
+	]}
+	</script>
+</head>
+<body>
+	<article itemscope itemtype="http://schema.org/NewsArticle">
+		<h1 itemprop="headline">Micro Headline</h1>
+		<p itemprop="alternativeHeadline">Micro Subtitle</p>
+		<span itemprop="author" itemscope itemtype="http://schema.org/Person">
+			von <span itemprop="name">Micro Author</span>
+		</span>
+		<time itemprop="datePublished" datetime="2015-10-01">1. Oktober</time>
+	</article>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+
+	base, _ := url.Parse("http://www.handelsblatt.com/politik/fluechtlinge?fsrc=rss")
+	m := Extract([]byte(page), base)
+
+	if m.Title != "LD Headline" || m.Sources["Title"] != SrcJSONLD {
+		t.Errorf("title %q from %v", m.Title, m.Sources["Title"])
+	}
+	if !reflect.DeepEqual(m.Authors, []string{"Anna A", "Bert B"}) {
+		t.Errorf("authors %q", m.Authors)
+	}
+	if m.Subtitle != "Micro Subtitle" || m.Sources["Subtitle"] != SrcMicrodata {
+		t.Errorf("subtitle %q from %v", m.Subtitle, m.Sources["Subtitle"])
+	}
+	if want := time.Date(2015, 10, 4, 8, 0, 0, 0, time.UTC); !m.Published.Equal(want) || m.Sources["Published"] != SrcOpenGraph {
+		t.Errorf("published %v from %v", m.Published, m.Sources["Published"])
+	}
+	if !m.Mod().Equal(time.Date(2015, 10, 5, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("mod %v", m.Mod())
+	}
+	if m.Canonical != "http://www.handelsblatt.com/politik/fluechtlinge" || m.Sources["Canonical"] != SrcHTML {
+		t.Errorf("canonical %q from %v", m.Canonical, m.Sources["Canonical"])
+	}
+	if m.Image != "http://www.handelsblatt.com/img/lead.jpg" {
+		t.Errorf("image %q", m.Image)
+	}
+	if m.Language != "de" || m.Section != "Politik" {
+		t.Errorf("language %q, section %q", m.Language, m.Section)
+	}
+	if !reflect.DeepEqual(m.Keywords, []string{"Europa", "Migration"}) || m.Sources["Keywords"] != SrcMeta {
+		t.Errorf("keywords %q from %v", m.Keywords, m.Sources["Keywords"])
+	}
+}
+
+func TestMicrodataAlone(t *testing.T) {
+	m := Extract([]byte(`<div itemscope itemtype="http://schema.org/Article">
+		<span itemprop="author" itemscope itemtype="http://schema.org/Person">Solo Author</span>
+		<meta itemprop="datePublished" content="2015-10-01T12:00:00Z">
+		<img itemprop="image" src="a.jpg">
+		</div>`), nil)
+	if !reflect.DeepEqual(m.Authors, []string{"Solo Author"}) || m.Image != "a.jpg" || m.Published.IsZero() {
+		t.Errorf("%+v", m)
+	}
+	if m.Sources["Authors"] != SrcMicrodata {
+		t.Errorf("sources %v", m.Sources)
+	}
+}
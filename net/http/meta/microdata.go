@@ -0,0 +1,146 @@
+package meta
+
+import (
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// microdata fills m from the first itemscope of an article type.
+func microdata(doc *html.Node, m *Meta) {
+	scope := findScope(doc)
+	if scope == nil {
+		return
+	}
+	for c := scope.FirstChild; c != nil; c = c.NextSibling {
+		itemprops(c, m)
+	}
+}
+
+func findScope(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && hasAttr(n, "itemscope") {
+		for _, t := range strings.Fields(dom.Attr(n.Attr, "itemtype")) {
+			if isArticleType(t) {
+				return n
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if s := findScope(c); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// itemprops collects the properties of the scope;
+// nested scopes only contribute their name, i.e. an author.
+func itemprops(n *html.Node, m *Meta) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	for _, prop := range strings.Fields(dom.Attr(n.Attr, "itemprop")) {
+		if hasAttr(n, "itemscope") {
+			if prop == "author" || prop == "creator" {
+				m.Authors = appendUnique(m.Authors, scopeName(n))
+			}
+			continue
+		}
+		v := itemValue(n)
+		switch prop {
+		case "headline", "name":
+			first(&m.Title, v)
+		case "alternativeHeadline", "description":
+			first(&m.Subtitle, v)
+		case "author", "creator":
+			m.Authors = appendUnique(m.Authors, v)
+		case "datePublished", "dateCreated":
+			firstTime(&m.Published, v)
+		case "dateModified":
+			firstTime(&m.Updated, v)
+		case "url", "mainEntityOfPage":
+			first(&m.Canonical, v)
+		case "inLanguage":
+			first(&m.Language, v)
+		case "articleSection":
+			first(&m.Section, v)
+		case "image", "thumbnailUrl":
+			first(&m.Image, v)
+		case "keywords":
+			for _, kw := range strings.Split(v, ",") {
+				m.Keywords = appendUnique(m.Keywords, strings.TrimSpace(kw))
+			}
+		}
+	}
+
+	if hasAttr(n, "itemscope") {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		itemprops(c, m)
+	}
+}
+
+// scopeName returns the itemprop=name of a nested scope, else its text.
+func scopeName(n *html.Node) string {
+	var name string
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if name != "" {
+			return
+		}
+		if n.Type == html.ElementNode && dom.HasToken(dom.Attr(n.Attr, "itemprop"), "name") {
+			name = itemValue(n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(n)
+	if name == "" {
+		name = text(n)
+	}
+	return name
+}
+
+// itemValue follows the microdata spec on property values.
+func itemValue(n *html.Node) string {
+	var v string
+	switch n.DataAtom {
+	case atom.Meta:
+		v = dom.Attr(n.Attr, "content")
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe, atom.Embed:
+		v = dom.Attr(n.Attr, "src")
+	case atom.A, atom.Link, atom.Area:
+		v = dom.Attr(n.Attr, "href")
+	case atom.Object:
+		v = dom.Attr(n.Attr, "data")
+	case atom.Time:
+		v = dom.Attr(n.Attr, "datetime")
+		if v == "" {
+			v = text(n)
+		}
+	case atom.Data, atom.Meter:
+		v = dom.Attr(n.Attr, "value")
+	default:
+		if c := dom.Attr(n.Attr, "content"); c != "" {
+			v = c
+		} else {
+			v = text(n)
+		}
+	}
+	return strings.TrimSpace(v)
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
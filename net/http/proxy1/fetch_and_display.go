@@ -17,9 +17,11 @@ import (
 	"github.com/pbberlin/tools/net/http/domclean2"
 	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/repo"
 	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/net/http/tplx"
 	"golang.org/x/net/html"
+	"google.golang.org/appengine"
 )
 
 var insertNewlines = strings.NewReplacer(
@@ -163,6 +165,15 @@ func handleFetchURL(w http.ResponseWriter, r *http.Request, m map[string]interfa
 			opts.ProxyHost = fetch.HostFromReq(r)
 		}
 
+		// assets=1 serves images and stylesheets from the repo
+		if r.FormValue("assets") != "" {
+			opts.Assets, err = repo.AssetLocalizer(r, repo.GetFS(appengine.NewContext(r)))
+			lg(err)
+			if opts.Assets != nil {
+				defer func() { lg(opts.Assets.Save()) }()
+			}
+		}
+
 		doc, err := domclean2.DomClean(bts, opts)
 
 		var bufRend bytes.Buffer
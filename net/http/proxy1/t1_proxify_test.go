@@ -0,0 +1,58 @@
+// +build proxy1
+// go test -tags=proxy1 -run Test1
+
+package proxy1
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/pbberlin/tools/net/http/domclean2"
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/routes"
+	"github.com/pbberlin/tools/os/fsi/osfs"
+	"golang.org/x/net/html"
+)
+
+// go test -tags=proxy1 -run Test1 -record
+var record = flag.Bool("record", false, "record http responses into testdata/recordings")
+
+// Test1 runs the cleansing of handleFetchURL against a fixture page.
+func Test1(t *testing.T) {
+
+	fetch.UseRecordings(osfs.New(), "testdata/recordings", *record, "testdata/fixtures")
+
+	rURL := "www.economist.com/news/europe/21672271-refugee-crisis-tests-schengen-borders-within"
+	bts, _, err := fetch.UrlGetter(nil, fetch.Options{URL: rURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := domclean2.CleaningOptions{Proxify: true}
+	opts.Beautify = true
+	opts.RemoteHost = fetch.HostFromStringUrl(rURL)
+	opts.ProxyHost = "localhost:8085"
+
+	doc, err := domclean2.DomClean(bts, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = html.Render(&b, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := b.String()
+
+	if strings.Contains(s, "<script") {
+		t.Errorf("scripts not removed")
+	}
+	if !strings.Contains(s, routes.ProxifyURI+"?"+routes.URLParamKey+"=") {
+		t.Errorf("links not proxified:\n%s", s)
+	}
+	if !strings.Contains(s, "passport-free travel zone") {
+		t.Errorf("article text lost:\n%s", s)
+	}
+}
@@ -0,0 +1,292 @@
+// Package readability finds the main content of a single html document -
+// for pages, which have no similar siblings to be deduplicated against.
+//
+// It expects the output of domclean2.DomClean with AddOutline:
+// class and id attributes are gone by then;
+// the scoring rests on text density, link density
+// and the outline attribute "ol", i.e. ol="1.3.2".
+//
+// Text blocks score points for their length and commas,
+// which go to their parent and - halved - to their grandparent.
+// The candidate with most points, discounted by its link density
+// and by its outline depth, is the main node.
+package readability
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	minBlockLen     = 25 // chars of a text block to be counted
+	minBlockDensity = 10 // chars per element; lower are menus
+	maxLinkDensity  = 0.5
+)
+
+// Result of Extract.
+type Result struct {
+	Node    *html.Node // the main node; still part of the document
+	Outline string     // its ol attribute, if any
+	Title   string
+	Text    string // plain text of Node, blocks separated by newlines
+	Score   float64
+}
+
+// blocks, which receive the text of their inline children
+var blockAtoms = map[atom.Atom]bool{
+	atom.P: true, atom.Pre: true, atom.Blockquote: true, atom.Td: true,
+	atom.Div: true, atom.Li: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Ul: true, atom.Ol: true,
+	atom.Table: true, atom.Tr: true, atom.Br: true,
+	atom.Form: true, atom.Header: true, atom.Footer: true, atom.Section: true, atom.Article: true,
+}
+
+// Extract returns the main node of doc, or nil, if no block has enough text.
+func Extract(doc *html.Node) *Result {
+
+	scores := map[*html.Node]float64{}
+	var order []*html.Node // candidates in document order, for stable ties
+
+	add := func(n *html.Node, pts float64) {
+		if n == nil || n.Type != html.ElementNode || n.DataAtom == atom.Body || n.DataAtom == atom.Html {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			order = append(order, n)
+		}
+		scores[n] += pts
+	}
+
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if n.Type == html.ElementNode && isTextBlock(n) {
+			s := ownText(n)
+			l := utf8.RuneCountInString(s)
+			if l >= minBlockLen && density(n) >= minBlockDensity {
+				pts := 1 + float64(strings.Count(s, ",")) + minF(float64(l)/100, 3)
+				add(n.Parent, pts)
+				if n.Parent != nil {
+					add(n.Parent.Parent, pts/2)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(doc)
+
+	var top *html.Node
+	var topScore float64
+	for _, n := range order {
+		ld := linkDensity(n)
+		if ld > maxLinkDensity {
+			continue
+		}
+		sc := scores[n] * (1 - ld) * depthFactor(n)
+		if sc > topScore {
+			top, topScore = n, sc
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	return &Result{
+		Node:    top,
+		Outline: dom.Attr(top.Attr, "ol"),
+		Title:   title(doc, top),
+		Text:    Text(top),
+		Score:   topScore,
+	}
+}
+
+// isTextBlock are nodes whose own text children
+// make up a paragraph.
+func isTextBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Blockquote, atom.Td:
+		return true
+	case atom.Div, atom.Li:
+		// DomClean condenses many <p> into <div> holding text directly
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// depthFactor discounts shallow nodes:
+// an outline of depth one wraps the entire body - navigation included.
+// Nodes without outline are not discounted.
+func depthFactor(n *html.Node) float64 {
+	ol := dom.Attr(n.Attr, "ol")
+	if ol == "" {
+		return 1
+	}
+	switch strings.Count(ol, ".") {
+	case 0:
+		return 0.5
+	case 1:
+		return 0.8
+	}
+	return 1
+}
+
+// density is chars per element of the subtree.
+func density(n *html.Node) float64 {
+	chars, elems := 0, 0
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			chars += utf8.RuneCountInString(strings.TrimSpace(n.Data))
+		case html.ElementNode:
+			elems++
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(n)
+	return float64(chars) / float64(elems)
+}
+
+// linkDensity is the share of text inside anchors.
+func linkDensity(n *html.Node) float64 {
+	all := utf8.RuneCountInString(textOf(n, false))
+	if all == 0 {
+		return 0
+	}
+	return float64(utf8.RuneCountInString(textOf(n, true))) / float64(all)
+}
+
+// ownText are the text nodes and inline elements below n,
+// not descending into nested blocks.
+func ownText(n *html.Node) string {
+	var b bytes.Buffer
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				b.WriteString(c.Data)
+			}
+			if c.Type == html.ElementNode && !blockAtoms[c.DataAtom] {
+				fr(c)
+			}
+		}
+	}
+	fr(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// textOf returns all text below n, or only the text inside anchors.
+func textOf(n *html.Node, anchorsOnly bool) string {
+	var b bytes.Buffer
+	var fr func(*html.Node, bool)
+	fr = func(n *html.Node, inA bool) {
+		if n.Type == html.TextNode && (inA || !anchorsOnly) {
+			b.WriteString(strings.TrimSpace(n.Data))
+		}
+		inA = inA || n.DataAtom == atom.A
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c, inA)
+		}
+	}
+	fr(n, false)
+	return b.String()
+}
+
+// Text renders n as plain text; blocks end with a newline.
+func Text(n *html.Node) string {
+	var b bytes.Buffer
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+		if n.Type == html.ElementNode && blockAtoms[n.DataAtom] {
+			b.WriteString("\n")
+		}
+	}
+	fr(n)
+
+	lines := []string{}
+	for _, l := range strings.Split(b.String(), "\n") {
+		l = strings.Join(strings.Fields(l), " ")
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// title is the first h1 of the main node,
+// else the last h1 or h2 preceding it,
+// else the <title> of the document.
+func title(doc, main *html.Node) string {
+
+	if h := find(main, atom.H1); h != nil {
+		return Text(h)
+	}
+
+	var preceding, titleTag *html.Node
+	passed := false
+	var fr func(*html.Node)
+	fr = func(n *html.Node) {
+		if passed {
+			return
+		}
+		if n == main {
+			passed = true
+			return
+		}
+		switch n.DataAtom {
+		case atom.H1, atom.H2:
+			preceding = n
+		case atom.Title:
+			titleTag = n
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			fr(c)
+		}
+	}
+	fr(doc)
+
+	if preceding != nil {
+		return Text(preceding)
+	}
+	if titleTag != nil {
+		return Text(titleTag)
+	}
+	return ""
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if f := find(c, a); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func minF(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package readability
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// shaped like domclean2 output: no class, no id, outline attributes
+const cleaned = `<html><head><title>Girl power | The Economist</title></head><body>
+<div ol="1">
+	<div ol="1.1">
+		<ul ol="1.1.1">
+			<li ol="1.1.1.1"><a href="/news">News and more news from all over the world</a></li>
+			<li ol="1.1.1.2"><a href="/blogs">Blogs, opinions, comments and debates</a></li>
+			<li ol="1.1.1.3"><a href="/economics">Economics, finance and the markets today</a></li>
+		</ul>
+	</div>
+	<div ol="1.2">
+		<h1 ol="1.2.1">Girl power</h1>
+		<div ol="1.2.2">
+			<p ol="1.2.2.1">Gender equality is good for economic growth, and, in Latin America, it has risen.</p>
+			<p ol="1.2.2.2">Women, who entered the labour market in great numbers, account for a third of the growth.</p>
+			<p ol="1.2.2.3">More <a href="/x">on this</a> in our special report, which was published last week, on Tuesday.</p>
+		</div>
+	</div>
+	<div ol="1.3">Copyright, The Economist Newspaper Limited 2015, all rights reserved.</div>
+</div>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+
+	doc, err := html.Parse(strings.NewReader(cleaned))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Extract(doc)
+	if res == nil {
+		t.Fatal("nothing found")
+	}
+	if res.Outline != "1.2.2" {
+		t.Errorf("main node %v", res.Outline)
+	}
+	if res.Title != "Girl power" {
+		t.Errorf("title %q", res.Title)
+	}
+	lines := strings.Split(res.Text, "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "More on this in our") {
+		t.Errorf("text %q", res.Text)
+	}
+	if strings.Contains(res.Text, "Copyright") || strings.Contains(res.Text, "Blogs") {
+		t.Errorf("boilerplate in text %q", res.Text)
+	}
+}
+
+func TestExtractNothing(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<body><div><a href="/">Home</a></div></body>`))
+	if res := Extract(doc); res != nil {
+		t.Errorf("want nil; got %+v", res)
+	}
+}
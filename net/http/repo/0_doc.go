@@ -44,6 +44,12 @@ curl --data url-x=http://www.economist.com/news/americas/21661804-gender-equalit
 
 curl --data "cnt=1&url-x=http://www.economist.com/news/americas/21661804-gender-equality-good-economic-growth-girl-power"  localhost:8085/fetch/similar
 
+
+Without appengine, from the command line - see ./crawl:
+
+crawl --host www.economist.com --prefix /news/business-and-finance --number 10
+crawl --commands commands.json --fs memfs --summary summary.json
+
 */
 
 var pf = fmt.Printf
@@ -1,6 +1,10 @@
 package repo
 
-import "time"
+import (
+	"time"
+
+	"github.com/pbberlin/tools/net/http/meta"
+)
 
 // FullArticle is the main struct passed
 // between the pipeline stages
@@ -8,6 +12,7 @@ type FullArticle struct {
 	Url  string
 	Mod  time.Time
 	Body []byte
+	Meta *meta.Meta // nil, if not extracted
 }
 
 // FetchCommand contains a RSS location
@@ -5,6 +5,7 @@ import (
 
 	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
 	"github.com/pbberlin/tools/os/fsi/httpfs"
 	"github.com/pbberlin/tools/os/fsi/memfs"
 )
@@ -12,11 +13,51 @@ import (
 // parallel fetchers routines
 const numWorkers = 3
 
+// failed fetches of a frontier URL before it is given up
+const maxFetchAttempts = 3
+
 var docRoot = ""  // no relative path, 'cause working dir too flippant
 var whichType = 0 // which type of filesystem, default is dsfs
 
-var memMapFileSys = memfs.New(memfs.DirSort("byDateDesc"))             // package variable required as "persistence"
-var httpFSys = &httpfs.HttpFs{SourceFs: fsi.FileSystem(memMapFileSys)} // memMap is always ready
+// http responses with headers, for revalidation; below docRoot
+const cacheDir = "_httpcache"
+
+// crawl frontiers, one per host; below docRoot
+const frontierDir = "_frontier"
+
+// change histories for re-crawls, one per host; below docRoot
+const scheduleDir = "_schedule"
+
+// extracted article metadata, mirroring the article files; below docRoot
+const metaDir = "_meta"
+
+// requested paths of articles stored under their canonical path; below docRoot
+const aliasDir = "_alias"
+
+// WARC files of all fetches, per host and crawl; below docRoot
+const warcDir = "_warc"
+
+// link graphs, one dir per host, one file per page; below docRoot
+const linksDir = "_links"
+
+// images, stylesheets and icons of articles, content addressed; below docRoot
+const assetsDir = "_assets"
+
+// time of the last fetch or revalidation of articles; below docRoot
+const checkedDir = "_checked"
+
+// articles referencing the local assets, same paths as the originals; below docRoot
+const localizedDir = "_localized"
+
+// per host defaults for fetch commands, *.json or *.toml; below docRoot
+const hostConfigDir = "_config"
+
+// product token, matched against the groups of robots.txt;
+// it is what net/http sends as user agent
+const crawlerAgent = "Go-http-client"
+
+var memMapFileSys = memfs.New(memfs.DirSort(common.Desc(common.ByModTime))) // package variable required as "persistence"
+var httpFSys = &httpfs.HttpFs{SourceFs: fsi.FileSystem(memMapFileSys)}      // memMap is always ready
 var fileserver1 = http.FileServer(httpFSys.Dir(docRoot))
 
 const mountName = "mntftch"
@@ -27,6 +68,13 @@ const UriMountNameY = "/" + mountName + "/serve-file/"
 const uriFetchCommandReceiver = "/fetch/command-receive"
 const uriFetchCommandSender = "/fetch/command-send"
 
+const uriFrontier = "/fetch/frontier"
+const uriSchedule = "/fetch/schedule"
+const uriWarcRestore = "/fetch/warc-restore"
+const uriLinks = "/fetch/links"
+const uriHostConfig = "/fetch/host-config"
+const uriLocalize = "/fetch/localize-assets"
+
 var RepoURL = routes.AppHost() + UriMountNameY
 
 var msg = []byte(`<p>This is an embedded static http server.</p>
@@ -49,52 +97,3 @@ var testCommands = []FetchCommand{
 		SearchPrefix: "/news/europe/aa",
 	},
 }
-
-// ConfigDefaults are default values for FetchCommands
-var ConfigDefaults = map[string]FetchCommand{
-	"unspecified": FetchCommand{
-		RssXMLURI:            map[string]string{},
-		CondenseTrailingDirs: 0,
-		DepthTolerance:       1,
-		DesiredNumber:        5,
-	},
-	// "www.handelsblatt.com": FetchCommand{
-	// 	RssXMLURI: map[string]string{
-	// 		"/":                      "/contentexport/feed/schlagzeilen",
-	// 		"/politik":               "/contentexport/feed/schlagzeilen",
-	// 		"/politik/international": "/contentexport/feed/schlagzeilen",
-	// 		"/politik/deutschland":   "/contentexport/feed/schlagzeilen",
-	// 	},
-	// 	CondenseTrailingDirs: 2,
-	// 	DepthTolerance:       1,
-	// 	DesiredNumber:        5,
-	// },
-	"www.economist.com": FetchCommand{
-		RssXMLURI: map[string]string{
-			"/news/europe":               "/sections/europe/rss.xml",
-			"/news/business-and-finance": "/sections/business-finance/rss.xml",
-		},
-		CondenseTrailingDirs: 0,
-		DepthTolerance:       2,
-		DesiredNumber:        5,
-	},
-	"test.economist.com": FetchCommand{
-		RssXMLURI: map[string]string{
-			"/news/business-and-finance": "/sections/business-finance/rss.xml",
-		},
-		CondenseTrailingDirs: 0,
-		DepthTolerance:       2,
-		DesiredNumber:        5,
-	},
-	"www.welt.de": FetchCommand{
-		RssXMLURI: map[string]string{
-			"/wirtschaft/deutschland":   "/wirtschaft/?service=Rss",
-			"/wirtschaft/international": "/wirtschaft/?service=Rss",
-		},
-
-		// CondenseTrailingDirs: 2,
-		CondenseTrailingDirs: 0,
-		DepthTolerance:       1,
-		DesiredNumber:        5,
-	},
-}
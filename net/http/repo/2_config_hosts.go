@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pbberlin/tools/net/http/hostconfig"
+	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"google.golang.org/appengine"
+)
+
+// hostConfigSeed is written to hostConfigDir, if it holds no config files.
+// Edit it there, or add *.toml files; changes are picked up within half a minute.
+// Hosts inherit unset values from "unspecified",
+// or from the host named by "inherit".
+// Handelsblatt used to be configured with
+//
+//	"www.handelsblatt.com": {"condense_trailing_dirs": 2,
+//		"rss": {"/": "/contentexport/feed/schlagzeilen", "/politik": "/contentexport/feed/schlagzeilen"}}
+var hostConfigSeed = []byte(`{
+	"unspecified": {
+		"desired_number": 5,
+		"depth_tolerance": 1,
+		"condense_trailing_dirs": 0
+	},
+	"www.economist.com": {
+		"depth_tolerance": 2,
+		"rss": {
+			"/news/europe": "/sections/europe/rss.xml",
+			"/news/business-and-finance": "/sections/business-finance/rss.xml"
+		}
+	},
+	"test.economist.com": {
+		"depth_tolerance": 2,
+		"rss": {
+			"/news/business-and-finance": "/sections/business-finance/rss.xml"
+		}
+	},
+	"www.welt.de": {
+		"rss": {
+			"/wirtschaft/deutschland": "/wirtschaft/?service=Rss",
+			"/wirtschaft/international": "/wirtschaft/?service=Rss"
+		}
+	}
+}
+`)
+
+// one store per filesystem type, since docRoot differs among them
+var hostConfigStores = map[int]*hostconfig.Store{}
+var hostConfigMu sync.Mutex
+
+// hostConfigs returns the config store of the current filesystem type.
+// Call it after GetFS, which sets docRoot.
+func hostConfigs() *hostconfig.Store {
+	hostConfigMu.Lock()
+	defer hostConfigMu.Unlock()
+	s, ok := hostConfigStores[whichType]
+	if !ok {
+		s = hostconfig.NewStore(path.Join(docRoot, hostConfigDir), "hosts.json", hostConfigSeed)
+		s.OnLoad = func(cfg *hostconfig.Config) {
+			urlnorm.Std.SetAll(cfg.URLNorm()) // the frontier, link graph and meta normalize with Std
+		}
+		hostConfigStores[whichType] = s
+	}
+	return s
+}
+
+// hostConfigAdmin shows the effective defaults of each configured host,
+// and where each value was inherited from.
+// Validation errors are listed on top; ?reload=1 re-reads all files.
+func hostConfigAdmin(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	wpf(w, tplx.ExecTplHelper(tplx.Head, map[string]interface{}{"HtmlTitle": "Host configuration"}))
+	defer wpf(w, tplx.Foot)
+
+	fs := GetFS(appengine.NewContext(r))
+	store := hostConfigs()
+
+	if r.FormValue("reload") == "1" {
+		store.Reload(fs)
+	}
+	cfg := store.Config(fs)
+
+	wpf(w, "files in %v - loaded %v - <a href='%v?reload=1'>reload</a><br>\n",
+		store.Dir, cfg.Loaded.Format("15:04:05"), uriHostConfig)
+	if errs := store.Errors(); len(errs) > 0 {
+		wpf(w, "<b>invalid config - the previous one remains in effect</b><pre>\n")
+		for _, err := range errs {
+			wpf(w, "%v\n", html.EscapeString(err.Error()))
+		}
+		wpf(w, "</pre>\n")
+	}
+
+	names := cfg.Names()
+	if host := r.FormValue("host"); host != "" {
+		names = []string{host}
+	}
+
+	wpf(w, "<table>\n<tr><th>host</th><th>inherits</th><th>desired</th><th>depth tol.</th><th>condense</th><th>rss</th><th>urlnorm</th></tr>\n")
+	for _, name := range names {
+		e := cfg.Effective(name)
+		src := func(field string, v int) string {
+			if s := e.Sources[field]; s != name {
+				return fmt.Sprintf("%v (%v)", v, html.EscapeString(s))
+			}
+			return fmt.Sprintf("<b>%v</b>", v)
+		}
+		prefixes := make([]string, 0, len(e.RSS))
+		for k := range e.RSS {
+			prefixes = append(prefixes, k)
+		}
+		sort.Strings(prefixes)
+		rss := ""
+		for _, k := range prefixes {
+			rss += html.EscapeString(k + " => " + e.RSS[k])
+			if s := e.Sources["RSS "+k]; s != name {
+				rss += " (" + html.EscapeString(s) + ")"
+			}
+			rss += "<br>"
+		}
+		rules := []string{}
+		for k, s := range e.Sources {
+			if !strings.HasPrefix(k, "URLNorm ") {
+				continue
+			}
+			k = strings.TrimPrefix(k, "URLNorm ")
+			rule := html.EscapeString(fmt.Sprintf("%v = %v", k, cfg.Hosts[s].URLNorm[k]))
+			if s != name {
+				rule += " (" + html.EscapeString(s) + ")"
+			}
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		inherits := ""
+		if len(e.Chain) > 1 {
+			inherits = strings.Join(e.Chain[1:], " < ")
+		}
+		wpf(w, "<tr><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td></tr>\n",
+			html.EscapeString(name), html.EscapeString(inherits),
+			src("DesiredNumber", e.DesiredNumber),
+			src("DepthTolerance", e.DepthTolerance),
+			src("CondenseTrailingDirs", e.CondenseTrailingDirs),
+			rss, strings.Join(rules, "<br>"))
+	}
+	wpf(w, "</table>\n")
+	wpf(w, "bold values are set by the host itself\n")
+}
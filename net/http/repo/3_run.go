@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"path"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// RunResult summarizes the execution of one fetch command.
+type RunResult struct {
+	Command FetchCommand // with host defaults added
+
+	Started time.Time
+	Took    time.Duration
+
+	Fetched int      // articles with content
+	Empty   int      // requested, but without content
+	Urls    []string // of the fetched articles
+}
+
+// Run executes fetch commands without an http request,
+// i.e. from command line tools or cron jobs.
+// Files are stored into fs below the directory set by SetDocRoot.
+// Progress is logged to stderr; progress is called after each command.
+func Run(fs fsi.FileSystem, fcs []FetchCommand, progress func(RunResult)) []RunResult {
+
+	results := []RunResult{}
+	for _, fc := range fcs {
+		res := RunResult{Command: addDefaults(fs, fc), Started: time.Now()}
+		for _, a := range FetchUsingRSS(nil, nil, fs, fc) {
+			if len(a.Body) == 0 {
+				res.Empty++
+				continue
+			}
+			res.Fetched++
+			res.Urls = append(res.Urls, a.Url)
+		}
+		res.Took = time.Now().Sub(res.Started)
+		if progress != nil {
+			progress(res)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Candidates lists the articles, a fetch command would request,
+// based on the directory digest stored by previous runs.
+// Nothing is fetched.
+func Candidates(fs fsi.FileSystem, fc FetchCommand) []FullArticle {
+
+	lg, _ := loghttp.BuffLoggerUniversal(nil, nil)
+
+	fc = addDefaults(fs, fc)
+	dirTree := &DirTree{Name: "/", Dirs: map[string]DirTree{}, EndPoint: true}
+	loadDigest(nil, nil, lg, fs, path.Join(docRoot, fc.Host, "digest2.json"), dirTree)
+
+	subtree, head := DiveToDeepestMatch(dirTree, fc.SearchPrefix)
+	if subtree == nil {
+		return nil
+	}
+	opt := LevelWiseDeeperOptions{}
+	opt.Rump = head
+	opt.MaxDepthDiff = fc.DepthTolerance
+	opt.CondenseTrailingDirs = fc.CondenseTrailingDirs
+	opt.MaxNumber = fc.DesiredNumber
+	arts := LevelWiseDeeper(nil, nil, subtree, opt)
+	for i := range arts {
+		arts[i].Url = fc.Host + arts[i].Url
+	}
+	return arts
+}
@@ -6,11 +6,15 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/frontier"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/schedule"
+	"github.com/pbberlin/tools/net/http/urlnorm"
 	"github.com/pbberlin/tools/os/fsi"
 	"github.com/pbberlin/tools/sort/sortmap"
 	"github.com/pbberlin/tools/stringspb"
@@ -23,9 +27,10 @@ import (
 // and has some rules for conflating URI directories.
 // uriPrefix and config.DesiredNumber tell the func
 // which subdirs of the RSS dir should be fetched - and how many at max.
+// The fetched articles are returned.
 func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 	fs fsi.FileSystem, config FetchCommand,
-) {
+) []FullArticle {
 
 	lg, b := loghttp.BuffLoggerUniversal(w, r)
 	closureOverBuf := func(bUnused *bytes.Buffer) {
@@ -35,10 +40,13 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 
 	if config.Host == "" {
 		lg(" empty host; returning")
-		return
+		return nil
 	}
 
-	config = addDefaults(config)
+	config = addDefaults(fs, config)
+
+	// fetches stop waiting for politeness and retries, before the request is aborted
+	deadline := fetch.RequestDeadline(r)
 
 	// Fetching the rssXML takes time.
 	// We do it before the timouts of the pipeline stages are set off.
@@ -54,9 +62,36 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 	fnDigest := path.Join(docRoot, config.Host, "digest2.json")
 	loadDigest(w, r, lg, fs, fnDigest, dirTree) // previous
 
+	// URLs of earlier, interrupted requests are still queued
+	fr, release, err := openFrontier(fs, config.Host)
+	lg(err)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	// all fetches of this crawl go into a series of WARC files
+	arch := newCrawlArchive(fs, config.Host, "rss")
+	defer func() { lg(arch.close()) }()
+
+	// revisits adapt to the observed changes
+	sched, err := openSchedule(fs, config.Host)
+	lg(err)
+	if err != nil {
+		return nil
+	}
+	defer func() { lg(sched.Save()) }()
+
+	links, err := openLinks(fs, config.Host)
+	lg(err)
+	if err != nil {
+		return nil
+	}
+	defer func() { lg(saveLinks(fs, config.Host, links, deadline)) }()
+
 	age := time.Now().Sub(dirTree.LastFound)
 	lg("DirTree is %5.2v hours old (%v)", age.Hours(), dirTree.LastFound.Format(time.ANSIC))
-	if age.Hours() > 0.001 {
+	if sched.IsDue(config.SearchPrefix, time.Now()) {
 
 		rssUrl := matchingRSSURI(w, r, config)
 		if rssUrl == "" {
@@ -64,20 +99,46 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 			m.r = r
 			m.lg = lg
 			m.fs1 = fs
+			m.arch = arch
+			m.deadline = deadline
 			m.SURL = path.Join(config.Host, config.SearchPrefix)
-			_, _, _, err := fetchSave(m)
+			bts, _, _, err := fetchSave(m)
 			lg(err)
 			if err != nil {
-				return
+				return nil
+			}
+			// <link rel="alternate" type="application/rss+xml" ...>
+			rssUrl = discoverFeed(bts, m.SURL, config.Host)
+			if rssUrl != "" {
+				lg("discovered feed %v", rssUrl)
+				rssDoc, _ := rssXMLFile(w, r, fs, rssUrl, arch)
+				rssDoc2DirTree(w, r, dirTree, rssDoc, config.Host)
 			}
 		} else {
-			rssUrl = path.Join(config.Host, rssUrl)
-			rssDoc, rssUrlObj := rssXMLFile(w, r, fs, rssUrl)
+			if !strings.Contains(rssUrl, "://") { // otherwise a feed on another host
+				rssUrl = path.Join(config.Host, rssUrl)
+			}
+			rssDoc, rssUrlObj := rssXMLFile(w, r, fs, rssUrl, arch)
 			_ = rssUrlObj
 			rssDoc2DirTree(w, r, dirTree, rssDoc, config.Host)
 		}
+		sitemap2DirTree(w, r, dirTree, config.Host)
 
 		saveDigest(lg, fs, fnDigest, dirTree)
+
+		if sub, _ := DiveToDeepestMatch(dirTree, config.SearchPrefix); sub != nil {
+			sched.ObserveDir(config.SearchPrefix, sub.LastFound, time.Now())
+		}
+	} else {
+		lg("%v not due before %v", config.SearchPrefix, nextVisit(sched, config.SearchPrefix))
+	}
+
+	// articles due for a revisit
+	for _, rc := range sched.Due(schedule.KindURL, time.Now(), config.DesiredNumber) {
+		e := frontier.Entry{URL: config.Host + rc.Key, Priority: 1, Source: "schedule", LastMod: rc.Mod}
+		if fr.Revisit(e) {
+			lg("  revisiting %v - changed %v of %v visits", rc.Key, rc.Changes, rc.Visits)
+		}
 	}
 
 	// lg(dirTree.String())
@@ -105,8 +166,21 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 			select {
 
 			case fa := <-out:
-				fullArticles = append(fullArticles, *fa)
 				pth := fetch.PathFromStringUrl(fa.Url)
+				if len(fa.Body) == 0 {
+					// failed or empty; queued again up to maxFetchAttempts
+					if fr.Fail(fa.Url, maxFetchAttempts) {
+						lg("    failed    %v - queued again", stringspb.Ellipsoider(pth, 50))
+					} else {
+						lg("    failed    %v - given up", stringspb.Ellipsoider(pth, 50))
+					}
+					cout = time.After(time.Millisecond * delayRefresh)
+					continue
+				}
+				fr.Done(fa.Url)
+				sched.Observe(fa.Url, mainText(fa.Body, fa.Meta), fa.Mod, time.Now())
+				recordLinks(links, fa.Url, fa.Body)
+				fullArticles = append(fullArticles, *fa)
 				lg("    fetched   %v - %v ", fa.Mod.Format("15:04:05"), stringspb.Ellipsoider(pth, 50))
 				cout = time.After(time.Millisecond * delayRefresh) // refresh timeout
 			case <-cout:
@@ -135,8 +209,17 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 				case a = <-inn:
 					var err error
 					var inf fetch.Info
-					a.Body, inf, err = fetch.UrlGetter(r, fetch.Options{URL: a.Url})
+					a.Body, inf, err = fetch.UrlGetter(r, fetch.Options{URL: a.Url, Retry: fetch.DefaultRetry, Deadline: deadline})
 					lg(err)
+					if err == nil {
+						lg(arch.add(inf))
+					}
+					if len(a.Body) > 0 {
+						a.Meta = extractMeta(a.Body, a.Url)
+					}
+					if a.Mod.IsZero() && a.Meta != nil {
+						a.Mod = a.Meta.Mod()
+					}
 					if a.Mod.IsZero() {
 						a.Mod = inf.Mod
 					}
@@ -165,11 +248,14 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 	//
 	// loading stage 1
 	uriPrefix := config.SearchPrefix
-	found := 0
+	found := fr.Len()
 	uriPrefixExcl := "impossible"
 	for i := 0; i < 15; i++ {
+		if found >= config.DesiredNumber {
+			break
+		}
 		lg("  searching for prefix   %v    - excl %q    - %v of %v", uriPrefix, uriPrefixExcl, found, config.DesiredNumber)
-		found += stuffStage1(w, r, config, inn, fin, dirTree,
+		found += stuffFrontier(w, r, config, fr, -i, dirTree,
 			uriPrefixExcl, uriPrefix, config.DesiredNumber-found)
 
 		if found >= config.DesiredNumber {
@@ -186,12 +272,17 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 		uriPrefix = newPrefix
 	}
 	lg("  found %v of %v", found, config.DesiredNumber)
+	stuffStage1(w, r, fr, inn, fin, config.DesiredNumber)
 
 	//
 	lg("stage3Wait.Wait() before")
 	stage3Wait.Wait()
 	lg("stage3Wait.Wait() after")
 
+	// abandoned URLs remain in flight - and are saved as queued
+	err = fr.Checkpoint()
+	lg(err)
+
 	// workers spin down earlier -
 	// but ae log writer and response writer need some time
 	// to record the spin-down messages
@@ -223,16 +314,22 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 		if len(a.Body) == 0 {
 			continue
 		}
-		u, err := url.Parse(a.Url)
-		u.Fragment = ""
-		u.RawQuery = ""
+		nurl, err := urlnorm.Normalize(a.Url)
 		lg(err)
+		u, err := url.Parse(nurl)
+		lg(err)
+		if err != nil {
+			continue
+		}
+		u.RawQuery = ""
 		semanticUri := condenseTrailingDir(u.RequestURI(), config.CondenseTrailingDirs)
 		p := path.Join(docRoot, semanticUri)
 		err = fs.WriteFile(p, a.Body, 0644)
 		lg(err)
 		err = fs.Chtimes(p, a.Mod, a.Mod)
 		lg(err)
+		err = saveMeta(fs, p, a.Meta)
+		lg(err)
 	}
 
 	{
@@ -248,12 +345,14 @@ func FetchUsingRSS(w http.ResponseWriter, r *http.Request,
 	// 	fsm.Dump()
 	// }
 
+	return fullArticles
 }
 
-// stuffStage1 ranges over the RSS entries and filters out unwanted directories.
-// Wanted urls are sent to the stage one channel.
-func stuffStage1(w http.ResponseWriter, r *http.Request, config FetchCommand,
-	inn chan *FullArticle, fin chan struct{}, dirTree *DirTree,
+// stuffFrontier ranges over the RSS entries and filters out unwanted directories.
+// Wanted urls are queued into the crawl frontier;
+// urls seen by earlier requests are skipped.
+func stuffFrontier(w http.ResponseWriter, r *http.Request, config FetchCommand,
+	fr *frontier.Frontier, prio int, dirTree *DirTree,
 	uriPrefixExcl, uriPrefixIncl string, nWant int) (nFound int) {
 
 	lg, lge := loghttp.Logger(w, r)
@@ -278,14 +377,17 @@ func stuffStage1(w http.ResponseWriter, r *http.Request, config FetchCommand,
 
 			lg("    feed #%02v: %v - %v", nFound, art.Mod.Format("15:04:05"), stringspb.Ellipsoider(art.Url, 50))
 
+			depth := strings.Count(art.Url, "/") - strings.Count(head, "/")
 			art.Url = config.Host + art.Url
 
-			select {
-			case inn <- &art:
-				// stage 1 loading
-			case <-fin:
-				lg("downstream stage has shut down, stop stuffing stage1")
-				return
+			if ok, why := robotsAllowed(r, art.Url); !ok {
+				lg("    skipping %v - %v", stringspb.Ellipsoider(art.Url, 50), why)
+				continue
+			}
+
+			e := frontier.Entry{URL: art.Url, Priority: prio, Depth: depth, Source: "dirtree " + head, LastMod: art.Mod}
+			if !fr.Push(e) {
+				continue // fetched - or queued - before
 			}
 
 			nFound++
@@ -300,3 +402,32 @@ func stuffStage1(w http.ResponseWriter, r *http.Request, config FetchCommand,
 	return
 
 }
+
+// stuffStage1 pops up to nWant urls from the frontier
+// and sends them to the stage one channel.
+func stuffStage1(w http.ResponseWriter, r *http.Request, fr *frontier.Frontier,
+	inn chan *FullArticle, fin chan struct{}, nWant int) {
+
+	lg, _ := loghttp.Logger(w, r)
+
+	for i := 0; i < nWant; i++ {
+		if i%10 == 0 {
+			fr.RefreshPaused() // the admin page may have paused us
+		}
+		e, ok := fr.Pop()
+		if !ok {
+			if fr.Paused() {
+				lg("frontier is paused")
+			}
+			return
+		}
+		select {
+		case inn <- &FullArticle{Url: e.URL, Mod: e.LastMod}:
+			// stage 1 loading
+		case <-fin:
+			fr.Retry(e.URL)
+			lg("downstream stage has shut down, stop stuffing stage1")
+			return
+		}
+	}
+}
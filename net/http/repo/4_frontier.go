@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/frontier"
+	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/os/fsi"
+	"google.golang.org/appengine"
+)
+
+// openFrontier returns the crawl frontier of host.
+// Only one request may crawl a host at a time;
+// the returned func releases the frontier.
+func openFrontier(fs fsi.FileSystem, host string) (*frontier.Frontier, func(), error) {
+	return openFrontierDir(fs, host, "", "crawl")
+}
+
+// openSimilarFrontier returns the frontier of FetchSimilar.
+// It is locked apart from the crawl frontier,
+// so that FetchSimilar and FetchUsingRSS may run alongside.
+func openSimilarFrontier(fs fsi.FileSystem, host string) (*frontier.Frontier, func(), error) {
+	return openFrontierDir(fs, host, "similar", "similar")
+}
+
+func openFrontierDir(fs fsi.FileSystem, host, sub, lockName string) (*frontier.Frontier, func(), error) {
+
+	dir := path.Join(docRoot, frontierDir, host, sub)
+	release := func() {}
+
+	if lckr, ok := fs.(fsi.Locker); ok {
+		err := fs.MkdirAll(dir, 0755)
+		if err != nil && err != fsi.ErrFileExists {
+			return nil, nil, err
+		}
+		lck, err := lckr.TryLock(path.Join(docRoot, frontierDir, host, lockName), fsi.LockExclusive, 2*time.Minute)
+		if err == fsi.ErrLocked {
+			return nil, nil, fmt.Errorf("%v of %v is in progress", lockName, host)
+		}
+		if err == nil {
+			release = func() { lck.Unlock() }
+		}
+	}
+
+	fr, err := frontier.Open(fs, dir)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return fr, release, nil
+}
+
+// hostRe accepts host names with an optional port - nothing to climb up a path with.
+var hostRe = regexp.MustCompile(`^[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*(:[0-9]+)?$`)
+
+// frontierAdmin lists the crawl frontiers of all hosts.
+// With ?host=..., the queue of one host is shown;
+// &action=pause|resume|checkpoint acts upon it.
+func frontierAdmin(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	wpf(w, tplx.ExecTplHelper(tplx.Head, map[string]interface{}{"HtmlTitle": "Crawl frontiers"}))
+	defer wpf(w, tplx.Foot)
+
+	fs := GetFS(appengine.NewContext(r))
+
+	host := r.FormValue("host")
+	if host != "" {
+		if !hostRe.MatchString(host) {
+			wpf(w, "invalid host %q\n", html.EscapeString(host))
+			return
+		}
+		frontierHost(w, r, fs, host)
+		wpf(w, "<br><a href='%v'>all hosts</a>\n", uriFrontier)
+		return
+	}
+
+	fis, err := fs.ReadDir(path.Join(docRoot, frontierDir))
+	if err != nil {
+		wpf(w, "no frontiers: %v\n", err)
+		return
+	}
+	wpf(w, "<table>\n<tr><th>host</th><th>queued</th><th>in flight</th><th>seen</th><th></th><th>checkpoint</th></tr>\n")
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		h := strings.TrimSuffix(fi.Name(), "/")
+		fr, err := frontier.Open(fs, path.Join(docRoot, frontierDir, h))
+		h = html.EscapeString(h)
+		if err != nil {
+			wpf(w, "<tr><td>%v</td><td colspan=5>%v</td></tr>\n", h, err)
+			continue
+		}
+		_, tot := fr.Stats()
+		paused := ""
+		if fr.Paused() {
+			paused = "paused"
+		}
+		wpf(w, "<tr><td><a href='%v?host=%v'>%v</a></td><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td></tr>\n",
+			uriFrontier, h, h, tot.Queued, tot.InFlight, tot.Seen, paused, fr.LastCheckpoint().Format(time.RFC822))
+	}
+	wpf(w, "</table>\n")
+}
+
+// frontierHost shows the last checkpoint of host - without the crawl lock,
+// so that a running crawl can be watched and paused.
+// The crawl obeys pause and resume with its next checkpoint.
+func frontierHost(w http.ResponseWriter, r *http.Request, fs fsi.FileSystem, host string) {
+
+	dir := path.Join(docRoot, frontierDir, host)
+
+	var err error
+	switch r.FormValue("action") {
+	case "pause":
+		err = frontier.SetPaused(fs, dir, true)
+	case "resume":
+		err = frontier.SetPaused(fs, dir, false)
+	case "checkpoint":
+		// only the owner of the lock has the current state
+		fr, release, errOpen := openFrontier(fs, host)
+		if errOpen != nil {
+			err = fmt.Errorf("%v - it checkpoints by itself", errOpen)
+			break
+		}
+		err = fr.Checkpoint()
+		release()
+	}
+	if err != nil {
+		wpf(w, "%v<br>\n", err)
+	}
+
+	fr, err := frontier.Open(fs, dir)
+	if err != nil {
+		wpf(w, "%v\n", err)
+		return
+	}
+
+	_, tot := fr.Stats()
+	wpf(w, "<b>%v</b> - %v queued, %v seen - as of the last checkpoint<br>\n", host, tot.Queued, tot.Seen)
+	if fr.Paused() {
+		wpf(w, "paused - <a href='%v?host=%v&action=resume'>resume</a><br>\n", uriFrontier, host)
+	} else {
+		wpf(w, "<a href='%v?host=%v&action=pause'>pause</a><br>\n", uriFrontier, host)
+	}
+	wpf(w, "<a href='%v?host=%v&action=checkpoint'>checkpoint</a> - last %v<br>\n",
+		uriFrontier, host, fr.LastCheckpoint().Format(time.RFC822))
+
+	wpf(w, "<pre>\n")
+	for _, e := range fr.Peek(host, 100) {
+		wpf(w, "%4v %2v  %-60v  %v\n", e.Priority, e.Depth, html.EscapeString(e.URL), html.EscapeString(e.Source))
+	}
+	wpf(w, "</pre>\n")
+}
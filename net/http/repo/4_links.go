@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/linkgraph"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+)
+
+func openLinks(fs fsi.FileSystem, host string) (*linkgraph.Graph, error) {
+	return linkgraph.Open(fs, path.Join(docRoot, linksDir, host))
+}
+
+// saveLinks writes the link graph of host.
+// FetchUsingRSS and FetchSimilar may run alongside;
+// their saves are serialized by a lock of their own.
+func saveLinks(fs fsi.FileSystem, host string, links *linkgraph.Graph, deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	lck, err := common.Lock(ctx, fs, path.Join(docRoot, linksDir, host+".lock"), fsi.LockExclusive, time.Minute)
+	if err == nil {
+		defer lck.Unlock()
+	} else if err != fsi.NotImplemented {
+		return err
+	}
+	return links.Save()
+}
+
+// recordLinks stores the outbound links of a fetched page.
+func recordLinks(links *linkgraph.Graph, surl string, bts []byte) {
+	if links == nil || len(bts) == 0 {
+		return
+	}
+	base, err := fetch.URLFromString(surl)
+	if err != nil {
+		return
+	}
+	links.SetLinks(surl, time.Now(), linkgraph.Extract(bts, base))
+}
+
+// linksJSON answers link graph queries of a host.
+// With url=..., the inbound and outbound links of that page;
+// otherwise the most linked pages below prefix,
+// linked from pages fetched within since (a duration, default 168h); n limits.
+func linksJSON(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	host := r.FormValue("host")
+	if host == "" {
+		http.Error(w, "host param required", http.StatusBadRequest)
+		return
+	}
+
+	fs := GetFS(appengine.NewContext(r))
+	links, err := openLinks(fs, host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var res interface{}
+	if surl := r.FormValue("url"); surl != "" {
+		res = map[string][]linkgraph.Link{
+			"inbound":  links.Inbound(surl),
+			"outbound": links.Outbound(surl),
+		}
+	} else {
+		since := 7 * 24 * time.Hour
+		if d, err := time.ParseDuration(r.FormValue("since")); err == nil {
+			since = d
+		}
+		n, _ := strconv.Atoi(r.FormValue("n"))
+		res = links.MostLinked(path.Join(host, r.FormValue("prefix")), time.Now().Add(-since), n)
+	}
+
+	bts, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bts)
+}
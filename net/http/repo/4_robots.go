@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/robots"
+)
+
+// robots.txt of all crawled hosts; refreshed daily
+var robotsCache = robots.NewCache(crawlerAgent, 24*time.Hour)
+
+func init() {
+	// Crawl-delay overrides the default politeness of package fetch
+	robotsCache.OnLoad = func(host string, rb *robots.Robots) {
+		if d := rb.CrawlDelay(crawlerAgent); d > 0 {
+			fetch.SetHostLimits(host, fetch.HostLimits{MaxConcurrent: 1, MinDelay: d})
+		}
+	}
+}
+
+// robotsAllowed checks surl - with or without scheme.
+// The second return value is the reason for a refusal.
+func robotsAllowed(r *http.Request, surl string) (bool, string) {
+
+	u, err := fetch.URLFromString(surl)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	get := robotsFetcher(r)
+	ok, rule := robotsCache.Allowed(u, get)
+	if ok {
+		return true, ""
+	}
+	if rb := robotsCache.Robots(u, get); rb == robots.DisallowAll {
+		return false, "robots.txt unreachable"
+	}
+	return false, "robots.txt " + rule.String()
+}
+
+func robotsFetcher(r *http.Request) robots.Fetcher {
+	return func(robotsURL string) ([]byte, int, error) {
+		bts, inf, err := fetch.UrlGetter(r, fetch.Options{URL: robotsURL})
+		if err != nil && inf.Status != 0 {
+			return nil, inf.Status, nil // no network failure
+		}
+		return bts, http.StatusOK, err
+	}
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"github.com/pbberlin/tools/net/http/meta"
+	"github.com/pbberlin/tools/net/http/readability"
+	"github.com/pbberlin/tools/net/http/schedule"
+	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"google.golang.org/appengine"
+)
+
+// openSchedule loads the change history of host.
+// It should be saved under the lock of openFrontier.
+func openSchedule(fs fsi.FileSystem, host string) (*schedule.Scheduler, error) {
+	return schedule.Open(fs, path.Join(docRoot, scheduleDir, host+".json"))
+}
+
+// mainText is the content, which the schedule hashes:
+// the title and the main text of a page.
+// Rotating teasers, ads or tokens in the markup
+// thus do not count as changes.
+func mainText(bts []byte, md *meta.Meta) []byte {
+	doc, err := html.Parse(bytes.NewReader(bts))
+	if err != nil {
+		return bts
+	}
+	removeNoise(doc)
+
+	var b bytes.Buffer
+	if md != nil {
+		b.WriteString(md.Title)
+		b.WriteString("\n")
+	}
+	if res := readability.Extract(doc); res != nil {
+		b.WriteString(res.Text)
+	} else {
+		b.WriteString(readability.Text(doc))
+	}
+	return b.Bytes()
+}
+
+// removeNoise removes the nodes without visible text.
+func removeNoise(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode,
+			c.DataAtom == atom.Script, c.DataAtom == atom.Style, c.DataAtom == atom.Noscript:
+			dom.RemoveNode(c)
+		default:
+			removeNoise(c)
+		}
+		c = next
+	}
+}
+
+func nextVisit(sched *schedule.Scheduler, dir string) string {
+	if rc, ok := sched.Get(schedule.KindDir, dir); ok {
+		return rc.NextVisit.Format(time.RFC822)
+	}
+	return "now"
+}
+
+// scheduleDue returns the due-list of a host as JSON.
+// Params: host, kind=url|dir (default both), n (default all),
+// ahead - a duration like 2h - to look into the future.
+func scheduleDue(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	host := r.FormValue("host")
+	if host == "" {
+		http.Error(w, "host param required", http.StatusBadRequest)
+		return
+	}
+
+	fs := GetFS(appengine.NewContext(r))
+	sched, err := openSchedule(fs, host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	n, _ := strconv.Atoi(r.FormValue("n"))
+	until := time.Now()
+	if d, err := time.ParseDuration(r.FormValue("ahead")); err == nil {
+		until = until.Add(d)
+	}
+
+	due := sched.Due(r.FormValue("kind"), until, n)
+	bts, err := json.MarshalIndent(due, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bts)
+}
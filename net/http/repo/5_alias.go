@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/frontier"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// An article reachable under several URLs is stored once,
+// under the path of its <link rel="canonical">.
+// The other paths get an alias file, containing the canonical path.
+// The aliases live in a tree of their own, like the meta sidecars.
+func aliasFile(semanticUri string) string {
+	return path.Join(docRoot, aliasDir, semanticUri)
+}
+
+// readAlias returns the canonical path, under which
+// the article of semanticUri was stored.
+func readAlias(fs fsi.FileSystem, semanticUri string) (string, bool) {
+	b, err := fs.ReadFile(aliasFile(semanticUri))
+	if err != nil {
+		return "", false
+	}
+	canon := strings.TrimSpace(string(b))
+	return canon, canon != ""
+}
+
+func saveAlias(fs fsi.FileSystem, semanticUri, canon string) error {
+	return common.WriteFile(fs, aliasFile(semanticUri), []byte(canon))
+}
+
+// canonicalUri returns the semantic uri of the canonical URL of a fetch,
+// or false, if it equals surl.
+func canonicalUri(surl string, inf fetch.Info, condense int) (string, bool) {
+	if inf.Canonical == nil {
+		return "", false
+	}
+	canon, _ := frontier.Key(inf.Canonical.String())
+	if key, _ := frontier.Key(surl); key == canon {
+		return "", false
+	}
+	return condenseTrailingDir(canon, condense), true
+}
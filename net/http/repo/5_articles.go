@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/meta"
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Articles returns the n newest articles stored below host/prefix,
+// with their bodies and metadata.
+// Mod is taken from the file, which is stamped with the article date on saving.
+// Url is host and path of the file - without scheme;
+// for hosts with CondenseTrailingDirs, it differs from the original url.
+func Articles(fs fsi.FileSystem, host, prefix string, n int) ([]FullArticle, error) {
+
+	found := []storedFile{}
+
+	var walk func(dir string, lvl int) error
+	walk = func(dir string, lvl int) error {
+		fis, err := fs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			name := strings.TrimSuffix(fi.Name(), "/")
+			fn := path.Join(dir, name)
+			if fi.IsDir() {
+				if lvl < 8 {
+					walk(fn, lvl+1)
+				}
+				continue
+			}
+			if !isArticleFile(name) {
+				continue
+			}
+			found = append(found, storedFile{fn, FullArticle{
+				Url: strings.TrimPrefix(strings.TrimPrefix(fn, docRoot), "/"),
+				Mod: fi.ModTime(),
+			}})
+		}
+		return nil
+	}
+	if err := walk(path.Join(docRoot, host, prefix), 0); err != nil {
+		return nil, err
+	}
+
+	sort.Sort(byModDesc(found))
+	if n > 0 && len(found) > n {
+		found = found[:n]
+	}
+
+	arts := make([]FullArticle, 0, len(found))
+	for _, f := range found {
+		bts, err := fs.ReadFile(f.fn)
+		if err != nil || len(bts) == 0 {
+			continue
+		}
+		f.art.Body = bts
+		f.art.Meta = loadMeta(fs, f.fn)
+		arts = append(arts, f.art)
+	}
+	return arts, nil
+}
+
+// isArticleFile excludes digests, dumped feeds and the like.
+func isArticleFile(name string) bool {
+	for _, suffix := range []string{".json", ".snappy", ".xml"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return name != "msg.html"
+}
+
+type storedFile struct {
+	fn  string
+	art FullArticle
+}
+
+type byModDesc []storedFile
+
+func (s byModDesc) Len() int           { return len(s) }
+func (s byModDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byModDesc) Less(i, j int) bool { return s[i].art.Mod.After(s[j].art.Mod) }
+
+func loadMeta(fs fsi.FileSystem, fn string) *meta.Meta {
+	b, err := fs.ReadFile(metaFile(fn))
+	if err != nil {
+		return nil
+	}
+	md := &meta.Meta{}
+	if err := json.Unmarshal(b, md); err != nil {
+		return nil
+	}
+	return md
+}
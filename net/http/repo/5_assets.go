@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/pbberlin/tools/net/http/assets"
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/html"
+	"google.golang.org/appengine"
+)
+
+// AssetLocalizer stores assets below docRoot/_assets;
+// documents reference them via the file server.
+// Callers must Save() it when done.
+func AssetLocalizer(r *http.Request, fs fsi.FileSystem) (*assets.Localizer, error) {
+	get := func(surl string) ([]byte, string, error) {
+		bts, inf, err := fetch.UrlGetter(r, fetch.Options{URL: surl})
+		if err != nil {
+			return nil, "", err
+		}
+		return bts, inf.Header.Get("Content-Type"), nil
+	}
+	return assets.Open(fs, path.Join(docRoot, assetsDir), "http://"+RepoURL+assetsDir+"/", get)
+}
+
+// localizeStored writes copies of the stored articles below host/prefix,
+// which reference local copies of their images, stylesheets and icons.
+// The copies go below docRoot/_localized; the originals remain untouched,
+// so that re-fetches, dedup and the WARC archive keep seeing the remote references.
+// The copies take the modification times of the originals.
+//
+//	?host=www.economist.com&prefix=/news/europe&n=20
+func localizeStored(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	wpf(w, tplx.ExecTplHelper(tplx.Head, map[string]interface{}{"HtmlTitle": "Localize assets"}))
+	defer wpf(w, tplx.Foot)
+
+	host := r.FormValue("host")
+	if host == "" {
+		wpf(w, "host param required\n")
+		return
+	}
+	n, _ := strconv.Atoi(r.FormValue("n"))
+	if n < 1 {
+		n = 20
+	}
+
+	fs := GetFS(appengine.NewContext(r))
+	arts, err := Articles(fs, host, r.FormValue("prefix"), n)
+	if err != nil {
+		wpf(w, "%v\n", err)
+		return
+	}
+	loc, err := AssetLocalizer(r, fs)
+	if err != nil {
+		wpf(w, "%v\n", err)
+		return
+	}
+
+	var tot assets.Report
+	wpf(w, "<pre>\n")
+	for _, a := range arts {
+		doc, err := html.Parse(bytes.NewReader(a.Body))
+		if err != nil {
+			wpf(w, "%-60v %v\n", a.Url, err)
+			continue
+		}
+		base, err := fetch.URLFromString(a.Url)
+		if err != nil {
+			wpf(w, "%-60v %v\n", a.Url, err)
+			continue
+		}
+		rep := loc.Localize(doc, base)
+		wpf(w, "%-60v %3v localized, %3v stored, %3v failed\n", a.Url, rep.Refs, rep.Stored, len(rep.Failed))
+		tot.Refs += rep.Refs
+		tot.Stored += rep.Stored
+		tot.Failed = append(tot.Failed, rep.Failed...)
+		if rep.Refs == 0 {
+			continue
+		}
+
+		var b bytes.Buffer
+		if err := html.Render(&b, doc); err != nil {
+			wpf(w, "  %v\n", err)
+			continue
+		}
+		fn := path.Join(docRoot, localizedDir, a.Url)
+		if err := common.WriteFile(fs, fn, b.Bytes()); err != nil {
+			wpf(w, "  %v\n", err)
+			continue
+		}
+		fs.Chtimes(fn, a.Mod, a.Mod)
+	}
+	wpf(w, "</pre>\n")
+
+	if err := loc.Save(); err != nil {
+		wpf(w, "%v<br>\n", err)
+	}
+	wpf(w, "%v articles, %v references localized, %v new files, %v assets known<br>\n",
+		len(arts), tot.Refs, tot.Stored, loc.Len())
+	wpf(w, "copies below <a href='%v'>%v</a><br>\n",
+		path.Join(UriMountNameY, localizedDir, host), path.Join(localizedDir, host))
+	for _, surl := range tot.Failed {
+		wpf(w, "failed %v<br>\n", surl)
+	}
+}
@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// The modification time of an article file is the server's Last-Modified.
+// The time of its last fetch or revalidation goes into a sidecar,
+// so that unchanged articles are not revalidated on every crawl.
+func checkedFile(fn string) string {
+	rel := strings.TrimPrefix(fn, docRoot)
+	return path.Join(docRoot, checkedDir, rel)
+}
+
+// readChecked returns the zero time for articles never checked.
+func readChecked(fs fsi.FileSystem, fn string) time.Time {
+	b, err := fs.ReadFile(checkedFile(fn))
+	if err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func saveChecked(fs fsi.FileSystem, fn string, t time.Time) error {
+	return common.WriteFile(fs, checkedFile(fn), []byte(t.UTC().Format(time.RFC3339)))
+}
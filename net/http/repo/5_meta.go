@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/meta"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// extractMeta must run before removeScriptsAndComments,
+// which would take the JSON-LD with it.
+func extractMeta(bts []byte, surl string) *meta.Meta {
+	base, err := fetch.URLFromString(surl)
+	if err != nil {
+		base = nil
+	}
+	return meta.Extract(bts, base)
+}
+
+// metaFile is the sidecar of an article file.
+// The sidecars live in a tree of their own,
+// so that directory listings of articles remain clean.
+func metaFile(fn string) string {
+	rel := strings.TrimPrefix(fn, docRoot)
+	return path.Join(docRoot, metaDir, rel) + ".json"
+}
+
+func saveMeta(fs fsi.FileSystem, fn string, md *meta.Meta) error {
+	if md == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(md, "", "\t")
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(fs, metaFile(fn), b)
+}
@@ -1,38 +1,36 @@
 package repo
 
 import (
-	"bytes"
-	"encoding/xml"
 	"net/http"
 	"net/url"
 	"path"
 	"time"
 
+	"github.com/pbberlin/tools/net/http/feed"
 	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/loghttp"
 	"github.com/pbberlin/tools/os/fsi"
 	"github.com/pbberlin/tools/stringspb"
 )
 
-func rssDoc2DirTree(w http.ResponseWriter, r *http.Request, treeX *DirTree, rssDoc RSS, domain string) {
+func rssDoc2DirTree(w http.ResponseWriter, r *http.Request, treeX *DirTree, rssDoc *feed.Feed, domain string) {
 
-	lg, lge := loghttp.Logger(w, r)
-	_ = lg
+	lg, _ := loghttp.Logger(w, r)
 
 	if treeX == nil {
 		treeX = &DirTree{Name: "root1", Dirs: map[string]DirTree{}, LastFound: time.Now().Truncate(time.Minute)}
 	}
+	if rssDoc == nil {
+		return
+	}
 
 	articleList := []FullArticle{}
 
-	for _, lpItem := range rssDoc.Items.ItemList {
-
-		t, err := time.Parse(time.RFC1123Z, lpItem.Published)
-		//     := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", lpItem.Published)
-		lge(err)
-
-		articleList = append(articleList, FullArticle{Url: lpItem.Link, Mod: t})
-
+	for _, lpItem := range rssDoc.Items {
+		if lpItem.Mod().IsZero() {
+			lg("no date for %v", lpItem.Link)
+		}
+		articleList = append(articleList, FullArticle{Url: lpItem.Link, Mod: lpItem.Mod()})
 	}
 
 	lg1, _ := loghttp.BuffLoggerUniversal(w, r)
@@ -43,19 +41,23 @@ func rssDoc2DirTree(w http.ResponseWriter, r *http.Request, treeX *DirTree, rssD
 
 //
 //
-// Fetches the RSS.xml file.
-func rssXMLFile(w http.ResponseWriter, r *http.Request, fs fsi.FileSystem, rssUrl string) (RSS, *url.URL) {
+// Fetches the feed - RSS 2.0, RSS 1.0 or Atom.
+func rssXMLFile(w http.ResponseWriter, r *http.Request, fs fsi.FileSystem, rssUrl string, arch *crawlArchive) (*feed.Feed, *url.URL) {
 
 	lg, lge := loghttp.Logger(w, r)
 
 	bts, respInf, err := fetch.UrlGetter(r, fetch.Options{URL: rssUrl})
 	lge(err)
+	if err != nil {
+		return nil, respInf.URL
+	}
+	lge(arch.add(respInf))
 
-	bts = bytes.Replace(bts, []byte("content:encoded>"), []byte("content-encoded>S"), -1) // hack
-
-	rssDoc := RSS{}
-	err = xml.Unmarshal(bts, &rssDoc)
+	rssDoc, err := feed.Parse(bts)
 	lge(err)
+	if err != nil {
+		return nil, respInf.URL
+	}
 
 	// save it
 	bdmp := stringspb.IndentedDumpBytes(rssDoc)
@@ -63,7 +65,22 @@ func rssXMLFile(w http.ResponseWriter, r *http.Request, fs fsi.FileSystem, rssUr
 	lge(err)
 	err = fs.WriteFile(path.Join(docRoot, respInf.URL.Host, "outp_rss.xml"), bdmp, 0755)
 	lge(err)
-	lg("RSS resp size %5.2vkB, saved to %v", len(bdmp)/1024, respInf.URL.Host+"/outp_rss.xml")
+	lg("%v resp size %5.2vkB, saved to %v", rssDoc.Format, len(bdmp)/1024, respInf.URL.Host+"/outp_rss.xml")
 
 	return rssDoc, respInf.URL
 }
+
+// discoverFeed returns the first feed announced by a page
+// of domain, or empty string.
+func discoverFeed(bts []byte, pageUrl, domain string) string {
+	base, err := fetch.URLFromString(pageUrl)
+	if err != nil {
+		return ""
+	}
+	for _, lnk := range feed.Discover(bts, base) {
+		if fetch.HostFromStringUrl(lnk.URL) == domain {
+			return lnk.URL
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/sitemap"
+)
+
+// Sitemaps can be huge; economist.com has hundreds of sub sitemaps.
+const (
+	maxSitemaps    = 8
+	maxSitemapURLs = 2000 // the most recent ones
+)
+
+// sitemapLocs are the sitemaps of robots.txt -
+// or the conventional /sitemap.xml.
+func sitemapLocs(r *http.Request, host string) []string {
+	u, err := fetch.URLFromString(host)
+	if err != nil {
+		return nil
+	}
+	rb := robotsCache.Robots(u, robotsFetcher(r))
+	if len(rb.Sitemaps) > 0 {
+		return rb.Sitemaps
+	}
+	return []string{path.Join(host, "sitemap.xml")}
+}
+
+// sitemap2DirTree merges the sitemap URLs of domain into treeX.
+// The newest lastmod below a dir raises its LastFound;
+// for news sitemaps the publication date.
+func sitemap2DirTree(w http.ResponseWriter, r *http.Request, treeX *DirTree, domain string) {
+
+	lg, _ := loghttp.BuffLoggerUniversal(w, r)
+
+	get := func(surl string) ([]byte, error) {
+		bts, inf, err := fetch.UrlGetter(r, fetch.Options{URL: surl, Retry: fetch.DefaultRetry})
+		if err == nil && inf.Status != http.StatusOK && inf.Status != 0 {
+			err = fmt.Errorf("status %v", inf.Status)
+		}
+		return bts, err
+	}
+
+	urls, errs := sitemap.Collect(get, sitemapLocs(r, domain), maxSitemaps)
+	for _, err := range errs {
+		lg("sitemap skipped: %v", err)
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	sort.Sort(byMod(urls))
+	if len(urls) > maxSitemapURLs {
+		urls = urls[:maxSitemapURLs]
+	}
+
+	articleList := make([]FullArticle, 0, len(urls))
+	for _, u := range urls {
+		if ok, _ := robotsAllowed(r, u.Loc); !ok {
+			continue
+		}
+		articleList = append(articleList, FullArticle{Url: u.Loc, Mod: u.Mod()})
+	}
+	lg("sitemaps yielded %v of %v urls", len(articleList), len(urls))
+
+	path2DirTree(lg, treeX, articleList, domain, false)
+}
+
+// byMod sorts recent first; entries without date last.
+type byMod []sitemap.URL
+
+func (s byMod) Len() int      { return len(s) }
+func (s byMod) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byMod) Less(i, j int) bool {
+	mi, mj := s[i].Mod(), s[j].Mod()
+	if mi.IsZero() != mj.IsZero() {
+		return mj.IsZero()
+	}
+	return mi.After(mj)
+}
@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/net/http/warc"
+	"github.com/pbberlin/tools/os/fsi"
+	"google.golang.org/appengine"
+)
+
+// crawlArchive batches the exchanges of one crawl
+// into WARC files below docRoot/_warc/host;
+// a full file is saved while the crawl goes on.
+// Workers add to it concurrently.
+// Stragglers adding after close are not archived.
+type crawlArchive struct {
+	sync.Mutex
+	wr     *warc.Writer
+	closed bool
+}
+
+// newCrawlArchive is named after its start time and kind, i.e. "rss" or "similar".
+func newCrawlArchive(fs fsi.FileSystem, host, kind string) *crawlArchive {
+	now := time.Now()
+	h := sha1.Sum([]byte(host + kind + now.String()))
+	fn := fmt.Sprintf("%v-%v-%x.warc.gz", now.UTC().Format("20060102150405"), kind, h[:4])
+	return &crawlArchive{wr: warc.NewWriter(fs, path.Join(docRoot, warcDir, host, fn))}
+}
+
+// add writes request, response and metadata of a fetch.
+// A revalidated cache entry yields a revisit record instead of the response.
+// A nil archive archives nothing.
+func (a *crawlArchive) add(inf fetch.Info) error {
+
+	if a == nil || inf.URL == nil || inf.Header == nil {
+		return nil // fresh from cache, or redirect cancelled
+	}
+	uri := inf.URL.String()
+	now := time.Now()
+
+	req := warc.NewRequest("GET", uri, inf.ReqHeader, now)
+
+	var resp *warc.Record
+	if inf.Revalidated {
+		resp = warc.NewRevisit(uri, http.StatusNotModified, inf.Header,
+			warc.Digest(inf.Raw), "", inf.CachedAt, now)
+	} else {
+		resp = warc.NewResponse(uri, inf.Status, inf.Header, inf.Raw, now)
+	}
+	resp.Header.Set("WARC-Concurrent-To", req.ID())
+
+	fields := map[string]string{
+		"charset": inf.Charset,
+		"retries": strconv.Itoa(inf.Retries),
+	}
+	if inf.Canonical != nil {
+		fields["canonical"] = inf.Canonical.String()
+	}
+	if !inf.Mod.IsZero() {
+		fields["modified"] = inf.Mod.UTC().Format(time.RFC3339)
+	}
+	md := warc.NewMetadata(uri, resp.ID(), fields, now)
+
+	a.Lock()
+	defer a.Unlock()
+	if a.closed {
+		return nil
+	}
+	return a.wr.Write(req, resp, md)
+}
+
+// close saves the file, if anything was added.
+func (a *crawlArchive) close() error {
+	if a == nil {
+		return nil
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.closed = true
+	return a.wr.Close()
+}
+
+// warcRestore writes the archived responses of a host
+// back into the article files, which the file server serves.
+// Params: host; overwrite=1 replaces existing files.
+func warcRestore(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	wpf(w, tplx.ExecTplHelper(tplx.Head, map[string]interface{}{"HtmlTitle": "Restore from WARC"}))
+	defer wpf(w, tplx.Foot)
+
+	wpf(w, "<pre>")
+	defer wpf(w, "</pre>")
+
+	host := r.FormValue("host")
+	if !hostRe.MatchString(host) {
+		wpf(w, "host param required, like www.economist.com\n")
+		return
+	}
+	overwrite := r.FormValue("overwrite") == "1"
+
+	fs := GetFS(appengine.NewContext(r))
+	dir := path.Join(docRoot, warcDir, host)
+	fis, err := fs.ReadDir(dir)
+	if err != nil {
+		wpf(w, "no archive for %v: %v\n", host, err)
+		return
+	}
+
+	fc := addDefaults(fs, FetchCommand{Host: host})
+	cnt := 0
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.Contains(fi.Name(), ".warc") {
+			continue
+		}
+		recs, err := warc.ReadFile(fs, path.Join(dir, fi.Name()))
+		if err != nil {
+			wpf(w, "%v: %v\n", fi.Name(), err)
+			continue
+		}
+		for _, rec := range recs {
+			if rec.Type() != warc.Response {
+				continue
+			}
+			resp, err := rec.HTTPResponse(nil)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				continue
+			}
+			resp.Body.Close()
+			body, err := rec.Payload()
+			if err != nil {
+				continue
+			}
+			// as UrlGetter delivers it
+			body, _, err = fetch.ToUTF8(body, resp.Header.Get("Content-Type"))
+			if err != nil {
+				wpf(w, "%v: %v\n", rec.TargetURI(), err)
+				continue
+			}
+
+			surl, err := urlnorm.Normalize(rec.TargetURI())
+			if err != nil {
+				continue
+			}
+			surl = surl[strings.Index(surl, "://")+3:]
+			fn := path.Join(docRoot, condenseTrailingDir(surl, fc.CondenseTrailingDirs))
+			if _, err := fs.Stat(fn); err == nil && !overwrite {
+				continue
+			}
+
+			err = fs.MkdirAll(path.Dir(fn), 0755)
+			if err != nil && err != fsi.ErrFileExists {
+				wpf(w, "%v: %v\n", fn, err)
+				continue
+			}
+			err = fs.WriteFile(fn, body, 0644)
+			if err != nil {
+				wpf(w, "%v: %v\n", fn, err)
+				continue
+			}
+			mod := rec.Date()
+			if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+				mod = lm
+			}
+			fs.Chtimes(fn, mod, mod)
+			wpf(w, "restored %v\n", fn)
+			cnt++
+		}
+	}
+	wpf(w, "%v files restored from %v\n", cnt, dir)
+}
@@ -13,7 +13,9 @@ import (
 
 	"github.com/golang/snappy"
 	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/linkgraph"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/urlnorm"
 	"github.com/pbberlin/tools/os/fsi"
 	"github.com/pbberlin/tools/os/osutilpb"
 	"github.com/pbberlin/tools/stringspb"
@@ -54,9 +56,12 @@ func (d DirTree) String() string {
 func switchTData(w http.ResponseWriter, r *http.Request) {
 
 	lg, lge := loghttp.Logger(w, r)
-	_ = lge
 
-	b := fetch.TestData["test.economist.com"]
+	x, b, err := fetch.Fixtures.Get("GET", "test.economist.com")
+	if err != nil {
+		lge(err)
+		return
+	}
 	sub1 := []byte(`<li><a href="/sections/newcontinent">xxx</a></li>`)
 
 	sub2 := []byte(`<li><a href="/sections/asia">Asia</a></li>`)
@@ -75,10 +80,19 @@ func switchTData(w http.ResponseWriter, r *http.Request) {
 		lg("NOT contains %s", sub1)
 	}
 
-	fetch.TestData["test.economist.com"] = b
+	err = fetch.Fixtures.Put(x, b)
+	lge(err)
 
 }
 
+// raiseLastFound only advances LastFound;
+// articles come in any order, and undated ones leave it alone.
+func raiseLastFound(dt *DirTree, found time.Time) {
+	if !found.IsZero() && found.After(dt.LastFound) {
+		dt.LastFound = found
+	}
+}
+
 func path2DirTree(lg loghttp.FuncBufUniv, treeX *DirTree, articles []FullArticle, domain string, IsRSS bool) {
 
 	if treeX == nil {
@@ -92,15 +106,18 @@ func path2DirTree(lg loghttp.FuncBufUniv, treeX *DirTree, articles []FullArticle
 
 	for _, art := range articles {
 		href := art.Url
-		if art.Mod.IsZero() {
-			art.Mod = time.Now()
-		}
+		found := art.Mod.Truncate(time.Minute)
 		href = strings.TrimPrefix(href, pfx1)
 		href = strings.TrimPrefix(href, pfx2)
 		if strings.HasPrefix(href, "/") { // ignore other domains
 			parsed, err := url.Parse(href)
 			lg(err)
-			href = parsed.Path
+			if err != nil {
+				continue
+			}
+			// index.html, trailing slash and escapes
+			// must not produce distinct dirs
+			href = urlnorm.Std.NormalizeURL(parsed).Path
 			// lg("%v", href)
 			trLp = treeX
 			// lg("trLp is %v", trLp.String())
@@ -118,7 +135,7 @@ func path2DirTree(lg loghttp.FuncBufUniv, treeX *DirTree, articles []FullArticle
 				if lvl > 0 {
 					trLp.Name = dir // lvl==0 => root
 				}
-				trLp.LastFound = art.Mod.Truncate(time.Minute)
+				raiseLastFound(trLp, found)
 
 				// lg("   %v, %v", dir, remainder)
 
@@ -134,7 +151,7 @@ func path2DirTree(lg loghttp.FuncBufUniv, treeX *DirTree, articles []FullArticle
 				// We "cannot assign" to map struct directly:
 				// trLp.Dirs[dir].LastFound = art.Mod   // fails with "cannot assign"
 				addressable := trLp.Dirs[dir]
-				addressable.LastFound = art.Mod.Truncate(time.Minute)
+				raiseLastFound(&addressable, found)
 
 				// We can rely that the *last* dir or html is an endpoint.
 				// We cannot tell about higher paths, unless explicitly linked somewhere
@@ -164,7 +181,9 @@ func path2DirTree(lg loghttp.FuncBufUniv, treeX *DirTree, articles []FullArticle
 }
 
 // Append of all links of a DOM to an in-memory dirtree
-func addAnchors(lg loghttp.FuncBufUniv, host string, bts []byte, dirTree *DirTree) {
+// addAnchors grows dirTree by the anchors of page surl;
+// the links themselves go into the link graph, if not nil.
+func addAnchors(lg loghttp.FuncBufUniv, host, surl string, bts []byte, dirTree *DirTree, links *linkgraph.Graph) {
 
 	doc, err := html.Parse(bytes.NewReader(bts))
 	lg(err)
@@ -187,6 +206,7 @@ func addAnchors(lg loghttp.FuncBufUniv, host string, bts []byte, dirTree *DirTre
 	fr(doc)
 	path2DirTree(lg, dirTree, anchors, host, false)
 	lg("\t\tadded %v anchors", len(anchors))
+	recordLinks(links, surl, bts)
 	dirTree.LastFound = time.Now() // Marker for later accumulated saving
 
 }
@@ -11,6 +11,8 @@ import (
 	"github.com/pbberlin/tools/appengine/util_appengine"
 	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/urlnorm"
+	"github.com/pbberlin/tools/os/fsi"
 	"golang.org/x/net/context"
 	"golang.org/x/net/html"
 )
@@ -26,11 +28,17 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 	// r *http.Request,
 
 	// Determine FileName
+	if nurl, err := urlnorm.Normalize(m.SURL); err == nil {
+		m.SURL = nurl
+	}
 	ourl, err := fetch.URLFromString(m.SURL)
 	fc := FetchCommand{}
 	fc.Host = ourl.Host
-	fc = addDefaults(fc)
+	fc = addDefaults(m.fs1, fc)
 	semanticUri := condenseTrailingDir(m.SURL, fc.CondenseTrailingDirs)
+	if canon, ok := readAlias(m.fs1, semanticUri); ok {
+		semanticUri = canon
+	}
 	fn := path.Join(docRoot, semanticUri)
 
 	m.lg("crawlin %q", m.SURL)
@@ -39,6 +47,7 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 	// Open file for age check
 	var bts []byte
 	var mod time.Time
+	stale := false
 	f := func() error {
 		file1, err := m.fs1.Open(fn)
 		// m.lg(err) // file may simply not exist
@@ -59,9 +68,14 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 		}
 
 		mod = fi.ModTime()
-		age := time.Now().Sub(mod)
+		checked := mod
+		if t := readChecked(m.fs1, fn); t.After(checked) {
+			checked = t
+		}
+		age := time.Now().Sub(checked)
 		if age.Hours() > 10 {
 			m.lg("\t\t file %4.2v hours old, refetch ", age.Hours())
+			stale = true
 			return fmt.Errorf("too old: %v", fn)
 		}
 
@@ -78,9 +92,15 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 		return bts, mod, true, err
 	}
 
+	if ok, why := robotsAllowed(m.r, m.SURL); !ok {
+		m.lg("\t\t skipping - %v", why)
+		return []byte{}, time.Time{}, false, fmt.Errorf("%v: %v", why, m.SURL)
+	}
+
 	//
 	// Fetch
-	bts, inf, err := fetch.UrlGetter(m.r, fetch.Options{URL: m.SURL, KnownProtocol: m.Protocol, RedirectHandling: 1})
+	cache := fetch.NewCache(m.fs1, path.Join(docRoot, cacheDir))
+	bts, inf, err := fetch.UrlGetter(m.r, fetch.Options{URL: m.SURL, KnownProtocol: m.Protocol, RedirectHandling: 1, Cache: cache, Retry: fetch.DefaultRetry, Deadline: m.deadline})
 	m.lg(err)
 	if err != nil {
 		if inf.Status != http.StatusNotFound {
@@ -96,6 +116,28 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 		inf.Mod = time.Now().Add(-75 * time.Minute)
 	}
 
+	if err == nil {
+		errArch := m.arch.add(inf)
+		m.lg(errArch)
+	}
+
+	// Same article under another URL? Store it only once.
+	if canon, ok := canonicalUri(m.SURL, inf, fc.CondenseTrailingDirs); ok && err == nil && canon != semanticUri {
+		m.lg("\t\t canonical %v", canon)
+		m.lg(saveAlias(m.fs1, semanticUri, canon))
+		semanticUri = canon
+		fn = path.Join(docRoot, semanticUri)
+	}
+
+	// Unchanged on the server - no need to rewrite.
+	// Touching would falsify the modification time;
+	// the check is recorded in the sidecar instead.
+	if inf.FromCache && stale {
+		m.lg("\t\t unchanged, from http cache (revalidated %v) - %v", inf.Revalidated, fn)
+		m.lg(saveChecked(m.fs1, fn, time.Now()))
+		return bts, inf.Mod, true, nil
+	}
+
 	//
 	//
 	// main request still exists?
@@ -110,6 +152,8 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 
 	m.lg("retrivd+saved %q; %vkB ", inf.URL.Host+inf.URL.Path, len(bts)/1024)
 
+	md := extractMeta(bts, inf.URL.String())
+
 	if len(bts) > 1024*1024-1 {
 		bts = removeScriptsAndComments(m.lg, bts)
 		m.lg("size reduced_1 to %vkB ", len(bts)/1024)
@@ -126,12 +170,32 @@ func fetchSave(m *MyWorker) ([]byte, time.Time, bool, error) {
 	dir := path.Dir(fn)
 	err = m.fs1.MkdirAll(dir, 0755)
 	m.lg(err)
+
+	// Another worker may be writing the same article.
+	// Then we skip writing; the content is the same anyway.
+	// The lock follows MkdirAll; new articles have no dir yet.
+	if lckr, ok := m.fs1.(fsi.Locker); ok {
+		lck, err := lckr.TryLock(fn, fsi.LockExclusive, time.Minute)
+		if err == fsi.ErrLocked {
+			m.lg("\t\t %v is being written by another worker - skipping save", fn)
+			return bts, inf.Mod, false, nil
+		}
+		m.lg(err)
+		if err == nil {
+			defer lck.Unlock()
+		}
+	}
+
 	err = m.fs1.Chtimes(dir, time.Now(), time.Now())
 	m.lg(err)
 	err = m.fs1.WriteFile(fn, bts, 0644)
 	m.lg(err)
 	err = m.fs1.Chtimes(fn, inf.Mod, inf.Mod)
 	m.lg(err)
+	err = saveMeta(m.fs1, fn, md)
+	m.lg(err)
+	err = saveChecked(m.fs1, fn, time.Now())
+	m.lg(err)
 
 	return bts, inf.Mod, false, nil
 
@@ -8,6 +8,7 @@ import (
 	"github.com/pbberlin/tools/net/http/fileserver"
 	"github.com/pbberlin/tools/net/http/tplx"
 	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
 	"github.com/pbberlin/tools/os/fsi/dsfs"
 	"github.com/pbberlin/tools/os/fsi/osfs"
 	"golang.org/x/net/context"
@@ -20,12 +21,12 @@ func GetFS(c context.Context) (fs fsi.FileSystem) {
 	case 0:
 		// must be re-instantiated for each request
 		docRoot = ""
-		dsFileSys := dsfs.New(dsfs.DirSort("byDateDesc"), dsfs.MountName(mountName), dsfs.AeContext(c))
+		dsFileSys := dsfs.New(dsfs.DirSort(common.DirsFirst, common.Desc(common.ByModTime)), dsfs.MountName(mountName), dsfs.AeContext(c))
 		fs = fsi.FileSystem(dsFileSys)
 	case 1:
 		docRoot = "c:/docroot/"
 		os.Chdir(docRoot)
-		osFileSys := osfs.New(osfs.DirSort("byDateDesc"))
+		osFileSys := osfs.New(osfs.DirSort(common.Desc(common.ByModTime)))
 		fs = fsi.FileSystem(osFileSys)
 	case 2:
 		// re-instantiation would delete contents
@@ -38,6 +39,19 @@ func GetFS(c context.Context) (fs fsi.FileSystem) {
 	return
 }
 
+// SetDocRoot sets the directory, below which fetched files are stored.
+// GetFS overrides it; callers bringing their own filesystem,
+// like command line tools, set it instead.
+func SetDocRoot(dir string) {
+	docRoot = dir
+}
+
+// DocRoot is the directory, below which fetched files are stored.
+// Packages keeping sidecars in the repo filesystem resolve them below it.
+func DocRoot() string {
+	return docRoot
+}
+
 // setFSType sets an internal variable, determining what FileSystems
 // should be used. Default is dsfs.
 func setFSType(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/pbberlin/tools/distrib"
 	"github.com/pbberlin/tools/net/http/fetch"
+	"github.com/pbberlin/tools/net/http/frontier"
 	"github.com/pbberlin/tools/net/http/loghttp"
 	"github.com/pbberlin/tools/net/http/routes"
 	"github.com/pbberlin/tools/net/http/tplx"
@@ -28,7 +29,10 @@ type MyWorker struct {
 
 	lg loghttp.FuncBufUniv
 
-	fs1 fsi.FileSystem
+	fs1  fsi.FileSystem
+	arch *crawlArchive // nil => fetches are not archived
+
+	deadline time.Time // of the fetches; zero => fetch.RequestDeadline
 
 	err error
 	FA  *FullArticle
@@ -53,6 +57,7 @@ func (m *MyWorker) Work() {
 	m.FA.Mod = mod
 	m.FA.Body = bts
 	m.FA.Url = m.SURL
+	m.FA.Meta = extractMeta(bts, m.SURL)
 
 }
 
@@ -129,22 +134,44 @@ func FetchSimilar(w http.ResponseWriter, r *http.Request, m map[string]interface
 	cmd := FetchCommand{}
 	cmd.Host = ourl.Host
 	cmd.SearchPrefix = ourl.Path
-	cmd = addDefaults(cmd)
+	cmd = addDefaults(fs1, cmd)
+
+	deadline := fetch.RequestDeadline(r)
 
 	dirTree := &DirTree{Name: "/", Dirs: map[string]DirTree{}, EndPoint: true}
 	fnDigest := path.Join(docRoot, cmd.Host, "digest2.json")
 	loadDigest(w, r, lg, fs1, fnDigest, dirTree) // previous
+
+	// The link graph is shared with FetchUsingRSS;
+	// saveLinks serializes the writes.
+	// The frontier of similar URLs has a lock of its own.
+	graph, err := openLinks(fs1, cmd.Host)
+	lg(err)
+	if err == nil {
+		defer func() { lg(saveLinks(fs1, cmd.Host, graph, deadline)) }()
+	}
+	sfr, release, err := openSimilarFrontier(fs1, cmd.Host)
+	lg(err)
+	if err == nil {
+		defer release()
+		defer func() { lg(sfr.Checkpoint()) }()
+	}
 	lg("dirtree 400 chars is %v end of dirtree\t\t", stringspb.ToLen(dirTree.String(), 400))
 
+	arch := newCrawlArchive(fs1, cmd.Host, "similar")
+	defer func() { lg(arch.close()) }()
+
 	m1 := new(MyWorker)
 	m1.r = r
 	m1.lg = lg
 	m1.fs1 = fs1
+	m1.deadline = deadline
+	m1.arch = arch
 	m1.SURL = path.Join(cmd.Host, ourl.Path)
 	m1.Protocol = knownProtocol
 	btsSrc, modSrc, usedExisting, err := fetchSave(m1)
 	if !usedExisting {
-		addAnchors(lg, cmd.Host, btsSrc, dirTree)
+		addAnchors(lg, cmd.Host, m1.SURL, btsSrc, dirTree, graph)
 	}
 	lg(err)
 	if err != nil {
@@ -194,6 +221,8 @@ MarkOuter:
 			m2.r = r
 			m2.lg = lg
 			m2.fs1 = fs1
+			m2.deadline = deadline
+			m2.arch = arch
 			m2.SURL = path.Join(cmd.Host, treePath)
 			m2.Protocol = knownProtocol
 
@@ -204,7 +233,7 @@ MarkOuter:
 			}
 			alreadyCrawled[treePath] = struct{}{}
 			if !usedExisting {
-				addAnchors(lg, cmd.Host, btsPar, dirTree)
+				addAnchors(lg, cmd.Host, m2.SURL, btsPar, dirTree, graph)
 			}
 
 			if subtree == nil {
@@ -323,14 +352,36 @@ MarkOuter:
 		tried, len(selecteds), len(nonExisting), countSimilar)
 
 	if len(selecteds) < countSimilar {
-		jobs := make([]distrib.Worker, 0, len(nonExisting))
+
+		// The URLs to fetch go through the frontier;
+		// those, which a timed out request did not finish, are fetched along.
+		surls := []string{}
+		wanted := map[string]bool{}
 		for _, art := range nonExisting {
 			surl := path.Join(cmd.Host, art.Url)
+			k, _ := frontier.Key(surl)
+			wanted[k] = true
+			if sfr == nil {
+				surls = append(surls, surl)
+			} else {
+				sfr.Revisit(frontier.Entry{URL: surl, Priority: 1, Source: "similar " + ourl.Path, LastMod: art.Mod})
+			}
+		}
+		if sfr != nil {
+			for e, ok := sfr.Pop(); ok; e, ok = sfr.Pop() {
+				surls = append(surls, e.URL)
+			}
+		}
+
+		jobs := make([]distrib.Worker, 0, len(surls))
+		for _, surl := range surls {
 			wrkr := MyWorker{SURL: surl}
 			wrkr.Protocol = knownProtocol
 			wrkr.r = r
 			wrkr.lg = lg
 			wrkr.fs1 = fs1
+			wrkr.deadline = deadline
+			wrkr.arch = arch
 			job := distrib.Worker(&wrkr)
 			jobs = append(jobs, job)
 		}
@@ -349,8 +400,21 @@ MarkOuter:
 		lg("Distrib returned at %4.2v secs with %v results.", time.Now().Sub(start).Seconds(), len(ret))
 
 		lg("\n" + msg.String())
+		if sfr != nil {
+			// stragglers remain in flight; the checkpoint queues them again
+			for _, v := range ret {
+				if v1 := v.Worker.(*MyWorker); v1.err != nil {
+					sfr.Fail(v1.SURL, maxFetchAttempts)
+				} else {
+					sfr.Done(v1.SURL)
+				}
+			}
+		}
 		for _, v := range ret {
 			v1, _ := v.Worker.(*MyWorker)
+			if k, _ := frontier.Key(v1.SURL); !wanted[k] {
+				continue // left over from an earlier request; saved by fetchSave anyway
+			}
 			if v1.FA != nil {
 				age := time.Now().Sub(v1.FA.Mod)
 				if age.Hours() < 10 {
@@ -374,7 +438,7 @@ MarkOuter:
 		// Extract links
 		for _, v := range nonExistFetched {
 			// lg("links -> memory dirtree for %q", v.Url)
-			addAnchors(lg, cmd.Host, v.Body, dirTree)
+			addAnchors(lg, cmd.Host, v.Url, v.Body, dirTree, graph)
 		}
 
 	}
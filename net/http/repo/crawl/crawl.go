@@ -0,0 +1,201 @@
+// crawl runs fetch commands from the command line,
+// against a local directory or in memory -
+// without the appengine runtime.
+// It builds with plain go build; the urlfetch client
+// of the classic appengine SDK is only compiled with -tags appengine.
+//
+//	crawl --host www.economist.com --prefix /news/europe --number 10
+//	crawl --commands commands.json --root ./docroot --summary summary.json
+//
+// commands.json holds the same JSON as posted to the command receiver:
+//
+//	[{ "Host": "www.welt.de", "SearchPrefix": "/wirtschaft/deutschland" }]
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/pbberlin/tools/net/http/repo"
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+	"github.com/pbberlin/tools/os/fsi/osfs"
+)
+
+// Summary is written after each run.
+type Summary struct {
+	Started  time.Time
+	Took     string
+	FS       string
+	Root     string
+	DryRun   bool
+	Fetched  int
+	Empty    int
+	Commands []repo.RunResult
+}
+
+func main() {
+
+	app := cli.NewApp()
+	app.Name = "crawl"
+	app.Usage = "Fetch articles via RSS and directory digests - into a local directory or into memory."
+	app.Version = "0.1"
+
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "commands, c",
+			Value: "",
+			Usage: "JSON file with an array of fetch commands; overrides host and prefix",
+		},
+		cli.StringFlag{
+			Name:   "host",
+			Value:  "",
+			Usage:  "host to crawl, i.e. www.economist.com",
+			EnvVar: "ENV_VAR_CRAWL_HOST",
+		},
+		cli.StringFlag{
+			Name:  "prefix, p",
+			Value: "/",
+			Usage: "search prefix, i.e. /news/europe",
+		},
+		cli.IntFlag{
+			Name:  "number, n",
+			Value: 0,
+			Usage: "desired number of articles; 0 takes the host config",
+		},
+		cli.StringFlag{
+			Name:  "fs",
+			Value: "osfs",
+			Usage: "'osfs' or 'memfs'; memfs keeps nothing but the summary",
+		},
+		cli.StringFlag{
+			Name:   "root, r",
+			Value:  "./docroot/",
+			Usage:  "directory for fetched files; for osfs",
+			EnvVar: "ENV_VAR_CRAWL_ROOT",
+		},
+		cli.StringFlag{
+			Name:  "summary, s",
+			Value: "",
+			Usage: "file to write the JSON run summary to; default is stdout only",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run, d",
+			Usage: "list the articles, which would be fetched, from the stored digest",
+		},
+	}
+
+	app.Action = func(c *cli.Context) {
+
+		log.SetFlags(log.Lshortfile)
+
+		fcs, err := commands(c)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			os.Exit(1)
+		}
+
+		sm := Summary{Started: time.Now(), FS: c.String("fs"), DryRun: c.Bool("dry-run")}
+
+		var fs fsi.FileSystem
+		switch sm.FS {
+		case "osfs":
+			sm.Root = c.String("root")
+			if err := os.MkdirAll(sm.Root, 0755); err != nil {
+				fmt.Printf("  %v\n", err)
+				os.Exit(1)
+			}
+			fs = osfs.New(osfs.DirSort(common.Desc(common.ByModTime)))
+		case "memfs":
+			fs = memfs.New(memfs.DirSort(common.Desc(common.ByModTime)))
+		default:
+			fmt.Printf("  unknown fs %q; want osfs or memfs\n", sm.FS)
+			os.Exit(1)
+		}
+		repo.SetDocRoot(sm.Root)
+		fmt.Printf("  %v commands, %v, root %q\n", len(fcs), sm.FS, sm.Root)
+
+		if sm.DryRun {
+			for _, fc := range fcs {
+				arts := repo.Candidates(fs, fc)
+				fmt.Printf("  %v%v - %v candidates\n", fc.Host, fc.SearchPrefix, len(arts))
+				res := repo.RunResult{Command: fc, Started: time.Now()}
+				for _, a := range arts {
+					fmt.Printf("    %v\n", a.Url)
+					res.Urls = append(res.Urls, a.Url)
+				}
+				sm.Commands = append(sm.Commands, res)
+			}
+		} else {
+			sm.Commands = repo.Run(fs, fcs, func(res repo.RunResult) {
+				fmt.Printf("  %v%v - %v fetched, %v empty, %v\n",
+					res.Command.Host, res.Command.SearchPrefix, res.Fetched, res.Empty, res.Took)
+			})
+		}
+
+		for _, res := range sm.Commands {
+			sm.Fetched += res.Fetched
+			sm.Empty += res.Empty
+		}
+		sm.Took = time.Now().Sub(sm.Started).String()
+		fmt.Printf("  %v fetched, %v empty, took %v\n", sm.Fetched, sm.Empty, sm.Took)
+
+		bts, err := json.MarshalIndent(sm, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if fn := c.String("summary"); fn != "" {
+			if err := ioutil.WriteFile(fn, bts, 0644); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("  summary written to %v\n", fn)
+		} else {
+			fmt.Printf("%s\n", bts)
+		}
+
+		if !sm.DryRun && sm.Fetched == 0 {
+			os.Exit(2) // signal cron jobs
+		}
+	}
+
+	app.Run(os.Args)
+}
+
+// commands reads the fetch commands from a file,
+// or builds one from the flags.
+func commands(c *cli.Context) ([]repo.FetchCommand, error) {
+
+	if fn := c.String("commands"); fn != "" {
+		bts, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		var fcs []repo.FetchCommand
+		if err := json.Unmarshal(bts, &fcs); err != nil {
+			return nil, fmt.Errorf("%v: %v", fn, err)
+		}
+		if len(fcs) == 0 {
+			return nil, fmt.Errorf("%v: no commands", fn)
+		}
+		return fcs, nil
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(c.String("host"), "http://"), "https://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "" {
+		return nil, fmt.Errorf("either --commands or --host required")
+	}
+	fc := repo.FetchCommand{
+		Host:          host,
+		SearchPrefix:  c.String("prefix"),
+		DesiredNumber: c.Int("number"),
+	}
+	return []repo.FetchCommand{fc}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"path"
 
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/os/fsi"
 	"github.com/pbberlin/tools/runtimepb"
 	"golang.org/x/net/html"
 )
@@ -42,17 +43,11 @@ func condenseTrailingDir(uri string, n int) (ret string) {
 
 }
 
-// Adding preconfigured settings to a fetch command
-func addDefaults(in FetchCommand) FetchCommand {
+// Adding preconfigured settings to a fetch command;
+// they are read from the host config files in fs.
+func addDefaults(fs fsi.FileSystem, in FetchCommand) FetchCommand {
 
-	var preset FetchCommand
-
-	h := in.Host
-	if exactPreset, ok := ConfigDefaults[h]; ok {
-		preset = exactPreset
-	} else {
-		preset = ConfigDefaults["unspecified"]
-	}
+	preset := hostConfigs().Config(fs).Effective(in.Host)
 
 	in.DepthTolerance = preset.DepthTolerance
 	in.CondenseTrailingDirs = preset.CondenseTrailingDirs
@@ -61,12 +56,17 @@ func addDefaults(in FetchCommand) FetchCommand {
 	}
 
 	if in.RssXMLURI == nil || len(in.RssXMLURI) == 0 {
-		in.RssXMLURI = preset.RssXMLURI
+		in.RssXMLURI = preset.RSS
 	}
 
 	return in
 }
 
+// HostDefaults are the preconfigured settings for host.
+func HostDefaults(fs fsi.FileSystem, host string) FetchCommand {
+	return addDefaults(fs, FetchCommand{Host: host})
+}
+
 // Each domain might have *several* RSS URLs.
 // Function matchingRSSURI returns the most fitting RSS URL
 // for a given  SearchPrefix, or empty string.
@@ -27,6 +27,13 @@ func InitHandlers() {
 	http.Handle(routes.FetchSimilarURI, loghttp.Adapter(FetchSimilar))
 	http.Handle("/fetch/similiar/form/", loghttp.Adapter(fetchSimForm))
 
+	http.HandleFunc(uriFrontier, loghttp.Adapter(frontierAdmin))
+	http.HandleFunc(uriSchedule, loghttp.Adapter(scheduleDue))
+	http.HandleFunc(uriWarcRestore, loghttp.Adapter(warcRestore))
+	http.HandleFunc(uriLinks, loghttp.Adapter(linksJSON))
+	http.HandleFunc(uriHostConfig, loghttp.Adapter(hostConfigAdmin))
+	http.HandleFunc(uriLocalize, loghttp.Adapter(localizeStored))
+
 }
 
 // BackendUIRendered returns a userinterface rendered to HTML
@@ -43,6 +50,13 @@ func BackendUIRendered() *bytes.Buffer {
 	htmlfrag.Wb(b1, "get similar", routes.FetchSimilarURI+"?"+routes.URLParamKey+"="+sample+"&cnt=2", "similar to url x")
 	htmlfrag.Wb(b1, "  form", "/fetch/similiar/form/")
 
+	htmlfrag.Wb(b1, "frontier", uriFrontier, "crawl queues per host; pause, resume")
+	htmlfrag.Wb(b1, "due", uriSchedule+"?host=www.economist.com&ahead=2h", "re-crawl due-list, JSON")
+	htmlfrag.Wb(b1, "warc restore", uriWarcRestore+"?host=www.economist.com", "archived responses back into files")
+	htmlfrag.Wb(b1, "links", uriLinks+"?host=www.economist.com&prefix=/news/europe&since=168h&n=20", "most linked articles, JSON")
+	htmlfrag.Wb(b1, "host config", uriHostConfig, "effective fetch defaults per host; reload")
+	htmlfrag.Wb(b1, "localize assets", uriLocalize+"?host=www.economist.com&prefix=/news/europe", "store images and stylesheets of articles")
+
 	htmlfrag.Wb(b1, "recv", uriFetchCommandReceiver, "receive fetch command, takes commands by curl")
 
 	htmlfrag.Wb(b1, "reservoire BOTH", UriMountNameY+"?fmt=html", "browse ANY fsi.FileSystem - human readable with ?fmt=http ")
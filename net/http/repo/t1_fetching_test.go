@@ -4,17 +4,25 @@
 package repo
 
 import (
+	"flag"
 	"testing"
 
+	"github.com/pbberlin/tools/net/http/fetch"
 	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/os/fsi/osfs"
 
 	"appengine/aetest"
 )
 
+// go test -tags=fetch1 -record
+var record = flag.Bool("record", false, "record http responses into testdata/recordings")
+
 func Test1(t *testing.T) {
 
 	lg, lge := loghttp.Logger(nil, nil)
 
+	fetch.UseRecordings(osfs.New(), "testdata/recordings", *record, "testdata/fixtures")
+
 	c, err := aetest.NewContext(nil)
 	if err != nil {
 		lge(err)
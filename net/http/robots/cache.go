@@ -0,0 +1,79 @@
+package robots
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Fetcher gets a robots.txt.
+// Status is the http status; err is for network failures.
+type Fetcher func(robotsURL string) (body []byte, status int, err error)
+
+type entry struct {
+	rb      *Robots
+	fetched time.Time
+}
+
+// Cache holds one Robots per scheme and host.
+type Cache struct {
+	Agent string
+	TTL   time.Duration
+
+	// OnLoad is called after a robots.txt was fetched,
+	// i.e. to apply the crawl delay.
+	OnLoad func(host string, rb *Robots)
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+func NewCache(agent string, ttl time.Duration) *Cache {
+	return &Cache{Agent: agent, TTL: ttl, entries: map[string]entry{}}
+}
+
+// Robots returns the cached robots.txt for the URL's host,
+// or fetches it using get.
+//
+// A missing robots.txt (4xx) allows all.
+// Server errors and network failures disallow all;
+// they are cached only for a minute.
+func (c *Cache) Robots(u *url.URL, get Fetcher) *Robots {
+
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(e.fetched) < c.TTL {
+		return e.rb
+	}
+
+	// Concurrent callers may fetch twice; no harm.
+	body, status, err := get(key + "/robots.txt")
+	e = entry{fetched: time.Now()}
+	switch {
+	case err == nil && status == http.StatusOK:
+		e.rb = Parse(body)
+	case err == nil && status >= 400 && status < 500 && status != http.StatusTooManyRequests:
+		e.rb = AllowAll
+	default:
+		e.rb = DisallowAll
+		e.fetched = e.fetched.Add(time.Minute - c.TTL)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	if c.OnLoad != nil && e.rb != DisallowAll {
+		c.OnLoad(u.Host, e.rb)
+	}
+	return e.rb
+}
+
+// Allowed checks a URL against the robots.txt of its host.
+func (c *Cache) Allowed(u *url.URL, get Fetcher) (bool, Rule) {
+	return c.Robots(u, get).Allowed(c.Agent, u.RequestURI())
+}
@@ -0,0 +1,192 @@
+// Package robots parses robots.txt files
+// and tells, whether a crawler may fetch a URL.
+//
+// Matching follows the Google interpretation:
+// The group with the longest matching user-agent token applies,
+// otherwise the "*" group.
+// Within the group, the longest matching pattern wins;
+// on equal length, Allow wins.
+// Patterns may contain * wildcards and a terminating $.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Rule struct {
+	Allow   bool
+	Pattern string
+}
+
+func (r Rule) String() string {
+	if r.Allow {
+		return "Allow: " + r.Pattern
+	}
+	return "Disallow: " + r.Pattern
+}
+
+type Group struct {
+	Agents     []string // lower case
+	Rules      []Rule
+	CrawlDelay time.Duration
+}
+
+type Robots struct {
+	Groups   []Group
+	Sitemaps []string
+}
+
+// AllowAll is used for hosts without robots.txt.
+var AllowAll = &Robots{}
+
+// DisallowAll is used for hosts, whose robots.txt is unreachable.
+var DisallowAll = &Robots{Groups: []Group{{Agents: []string{"*"}, Rules: []Rule{{Pattern: "/"}}}}}
+
+// Parse never fails; unknown lines are ignored.
+func Parse(b []byte) *Robots {
+
+	rb := &Robots{}
+	var cur *Group
+	lastWasAgent := false
+
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf")) // BOM
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.TrimSpace(kv[1])
+
+		switch key {
+		case "user-agent":
+			// consecutive agent lines share one group
+			if !lastWasAgent {
+				rb.Groups = append(rb.Groups, Group{})
+				cur = &rb.Groups[len(rb.Groups)-1]
+			}
+			cur.Agents = append(cur.Agents, strings.ToLower(val))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if cur != nil && val != "" { // empty Disallow allows all
+				cur.Rules = append(cur.Rules, Rule{Allow: key == "allow", Pattern: val})
+			}
+		case "crawl-delay":
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(val, 64); err == nil && secs > 0 {
+					cur.CrawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			// sitemaps belong to no group
+			rb.Sitemaps = append(rb.Sitemaps, val)
+		}
+		lastWasAgent = false
+	}
+	return rb
+}
+
+// Group returns the group for agent, or nil.
+// Agent is the product token of the crawler, i.e. "Googlebot".
+func (rb *Robots) Group(agent string) *Group {
+	agent = strings.ToLower(agent)
+	var best *Group
+	bestLen := -1
+	for i := range rb.Groups {
+		g := &rb.Groups[i]
+		for _, a := range g.Agents {
+			l := -1
+			if a == "*" {
+				l = 0
+			} else if a != "" && strings.Contains(agent, a) {
+				l = len(a)
+			}
+			if l > bestLen {
+				best, bestLen = g, l
+			}
+		}
+	}
+	return best
+}
+
+// Allowed checks path plus query of a URL, i.e. /search?q=x.
+// The deciding rule is returned for logging;
+// it is zero, if no rule applies.
+func (rb *Robots) Allowed(agent, pathQuery string) (bool, Rule) {
+	if pathQuery == "" {
+		pathQuery = "/"
+	}
+	if pathQuery == "/robots.txt" {
+		return true, Rule{}
+	}
+	g := rb.Group(agent)
+	if g == nil {
+		return true, Rule{}
+	}
+	var best Rule
+	bestLen := -1
+	for _, r := range g.Rules {
+		if !match(r.Pattern, pathQuery) {
+			continue
+		}
+		l := len(r.Pattern)
+		if l > bestLen || l == bestLen && r.Allow {
+			best, bestLen = r, l
+		}
+	}
+	if bestLen < 0 {
+		return true, Rule{}
+	}
+	return best.Allow, best
+}
+
+func (rb *Robots) CrawlDelay(agent string) time.Duration {
+	if g := rb.Group(agent); g != nil {
+		return g.CrawlDelay
+	}
+	return 0
+}
+
+// match is a prefix match with * wildcards and optional $ anchor.
+func match(pattern, pth string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+	parts := strings.Split(pattern, "*")
+
+	// first part is a prefix
+	if !strings.HasPrefix(pth, parts[0]) {
+		return false
+	}
+	pth = pth[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || pth == ""
+	}
+
+	// middle parts anywhere, leftmost
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(pth, p)
+		if i < 0 {
+			return false
+		}
+		pth = pth[i+len(p):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(pth, last)
+	}
+	return strings.Contains(pth, last)
+}
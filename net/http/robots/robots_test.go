@@ -0,0 +1,88 @@
+package robots
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+const txt = `# comment
+User-agent: *
+Disallow: /search
+Disallow: /*.pdf$
+Allow: /search/about
+Crawl-delay: 2.5
+
+User-agent: Googlebot
+User-agent: tools-repo
+Disallow: /private/
+Allow: /private/*/public
+
+Sitemap: https://www.economist.com/sitemap.xml
+`
+
+func TestAllowed(t *testing.T) {
+
+	rb := Parse([]byte(txt))
+
+	cases := []struct {
+		agent, pth string
+		want       bool
+	}{
+		{"Go-http-client", "/", true},
+		{"Go-http-client", "/search", false},
+		{"Go-http-client", "/search?q=x", false},
+		{"Go-http-client", "/search/about", true},
+		{"Go-http-client", "/files/a.pdf", false},
+		{"Go-http-client", "/files/a.pdf?x=1", true},
+		{"Go-http-client", "/robots.txt", true},
+		{"tools-repo/1.0", "/search", true}, // own group; * group not merged
+		{"tools-repo/1.0", "/private/x", false},
+		{"tools-repo/1.0", "/private/a/public/b", true},
+		{"Googlebot", "/private/", false},
+	}
+	for _, tc := range cases {
+		got, rule := rb.Allowed(tc.agent, tc.pth)
+		if got != tc.want {
+			t.Errorf("%v %v: got %v by %q; want %v", tc.agent, tc.pth, got, rule, tc.want)
+		}
+	}
+
+	if d := rb.CrawlDelay("Go-http-client"); d != 2500*time.Millisecond {
+		t.Errorf("crawl delay %v", d)
+	}
+	if len(rb.Sitemaps) != 1 || rb.Sitemaps[0] != "https://www.economist.com/sitemap.xml" {
+		t.Errorf("sitemaps %v", rb.Sitemaps)
+	}
+}
+
+func TestCache(t *testing.T) {
+
+	calls := 0
+	get := func(status int) Fetcher {
+		return func(u string) ([]byte, int, error) {
+			calls++
+			return []byte(txt), status, nil
+		}
+	}
+
+	c := NewCache("Go-http-client", time.Hour)
+	u, _ := url.Parse("https://www.economist.com/search?q=x")
+
+	if ok, _ := c.Allowed(u, get(200)); ok {
+		t.Errorf("disallowed url passes")
+	}
+	c.Allowed(u, get(200))
+	if calls != 1 {
+		t.Errorf("robots.txt fetched %v times; want 1", calls)
+	}
+
+	u, _ = url.Parse("https://www.handelsblatt.com/search")
+	if ok, _ := c.Allowed(u, get(404)); !ok {
+		t.Errorf("missing robots.txt must allow all")
+	}
+	u, _ = url.Parse("https://test.economist.com/search")
+	if ok, _ := c.Allowed(u, get(503)); ok {
+		t.Errorf("unreachable robots.txt must disallow all")
+	}
+}
@@ -37,6 +37,12 @@ func AppHostLive() string {
 	return appID + ".appspot.com"
 }
 
+// SetAppHost is for tests without dev server,
+// replaying recordings of the app's own responses.
+func SetAppHost(host string) {
+	appHost = host
+}
+
 func AppHost() string {
 	return appHost
 }
@@ -0,0 +1,315 @@
+// Package schedule decides, when URLs and directories of a host
+// should be visited again.
+//
+// Each visit is compared to the previous one - by content hash
+// or by modification time. Changes halve the revisit interval,
+// unchanged visits stretch it by half.
+// Thus a section like /news converges to frequent polling,
+// while archive paths drift towards MaxInterval.
+//
+// A directory counts as changed, if any URL below it
+// changed or appeared since its last visit.
+package schedule
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+const (
+	KindURL = "url"
+	KindDir = "dir"
+)
+
+// Record is the change history of a URL or directory.
+type Record struct {
+	Key  string // path, with query for URLs
+	Kind string
+
+	Hash string    // content hash of the last visit; empty for dirs
+	Mod  time.Time // last modification seen
+
+	FirstVisit time.Time
+	LastVisit  time.Time
+	LastChange time.Time
+	NextVisit  time.Time
+	Interval   time.Duration
+
+	Visits  int
+	Changes int
+}
+
+// ChangeRate is the share of visits, which found a change.
+func (rc *Record) ChangeRate() float64 {
+	if rc.Visits < 2 {
+		return 0
+	}
+	return float64(rc.Changes) / float64(rc.Visits-1)
+}
+
+type Scheduler struct {
+	MinInterval     time.Duration
+	MaxInterval     time.Duration
+	InitialInterval time.Duration
+
+	fs fsi.FileSystem
+	fn string
+
+	mu   sync.Mutex
+	recs map[string]*Record // kind + " " + key
+}
+
+// Open loads the history from file fn - or starts empty.
+func Open(fs fsi.FileSystem, fn string) (*Scheduler, error) {
+	s := &Scheduler{
+		MinInterval:     10 * time.Minute,
+		MaxInterval:     30 * 24 * time.Hour,
+		InitialInterval: 6 * time.Hour,
+		fs:              fs,
+		fn:              fn,
+		recs:            map[string]*Record{},
+	}
+	bts, err := fs.ReadFile(fn)
+	if err != nil {
+		return s, nil
+	}
+	recs := []*Record{}
+	err = json.Unmarshal(bts, &recs)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fn, err)
+	}
+	for _, rc := range recs {
+		s.recs[rc.Kind+" "+rc.Key] = rc
+	}
+	return s, nil
+}
+
+// Save writes the history.
+func (s *Scheduler) Save() error {
+	s.mu.Lock()
+	bts, err := json.MarshalIndent(s.sorted(""), "", "\t")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(s.fs, s.fn, bts)
+}
+
+// Key reduces a URL - with or without scheme and host - to path and query.
+func Key(surl string) string {
+	if i := strings.Index(surl, "#"); i >= 0 {
+		surl = surl[:i]
+	}
+	if i := strings.Index(surl, "://"); i >= 0 {
+		surl = surl[i+3:]
+	} else if strings.HasPrefix(surl, "/") {
+		return surl
+	}
+	i := strings.IndexAny(surl, "/?")
+	if i < 0 {
+		return "/"
+	}
+	surl = surl[i:] // strip host
+	if surl[0] == '?' {
+		surl = "/" + surl
+	}
+	return surl
+}
+
+// Observe records a visit of a URL.
+// A body yields a content hash; without body, mod alone decides.
+// Callers pass the extracted main text rather than the markup,
+// which changes with every teaser or token.
+// Observe returns, whether the URL changed since the previous visit.
+// The directories above the URL are updated as well.
+func (s *Scheduler) Observe(surl string, body []byte, mod, now time.Time) bool {
+
+	k := Key(surl)
+	hash := ""
+	if len(body) > 0 {
+		hash = fmt.Sprintf("%x", sha1.Sum(body))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rc, known := s.recs[KindURL+" "+k]
+	if !known {
+		rc = &Record{Key: k, Kind: KindURL}
+		s.recs[KindURL+" "+k] = rc
+	}
+
+	changed := false
+	switch {
+	case !known:
+		changed = true
+	case hash != "" && rc.Hash != "":
+		changed = hash != rc.Hash
+	default:
+		changed = mod.After(rc.Mod)
+	}
+	if hash != "" {
+		rc.Hash = hash
+	}
+	if mod.After(rc.Mod) {
+		rc.Mod = mod
+	}
+	s.visit(rc, changed, now)
+
+	if changed {
+		for d := dirOf(k); ; d = path.Dir(d) {
+			s.touchDir(d, now)
+			if d == "/" {
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// ObserveDir records a visit of a directory, i.e. of its index page,
+// or of a digest of its links.
+// A directory without change below since its last visit is unchanged -
+// unless lastFound is newer than its last modification.
+func (s *Scheduler) ObserveDir(dir string, lastFound, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc := s.dir(dir, now)
+	changed := rc.Visits == 0 || rc.Mod.After(rc.LastVisit) || lastFound.After(rc.Mod)
+	if lastFound.After(rc.Mod) {
+		rc.Mod = lastFound
+	}
+	s.visit(rc, changed, now)
+	return changed
+}
+
+// touchDir notes a change below dir, without counting a visit.
+func (s *Scheduler) touchDir(dir string, now time.Time) {
+	rc := s.dir(dir, now)
+	if now.After(rc.Mod) {
+		rc.Mod = now
+	}
+}
+
+func (s *Scheduler) dir(dir string, now time.Time) *Record {
+	dir = cleanDir(dir)
+	rc, ok := s.recs[KindDir+" "+dir]
+	if !ok {
+		rc = &Record{Key: dir, Kind: KindDir, NextVisit: now}
+		s.recs[KindDir+" "+dir] = rc
+	}
+	return rc
+}
+
+// visit adapts the interval.
+func (s *Scheduler) visit(rc *Record, changed bool, now time.Time) {
+	if rc.Visits == 0 {
+		rc.FirstVisit = now
+		rc.Interval = s.InitialInterval
+	} else if changed {
+		rc.Interval /= 2
+	} else {
+		rc.Interval += rc.Interval / 2
+	}
+	if rc.Interval < s.MinInterval {
+		rc.Interval = s.MinInterval
+	}
+	if rc.Interval > s.MaxInterval {
+		rc.Interval = s.MaxInterval
+	}
+	rc.Visits++
+	if changed {
+		if rc.Visits > 1 {
+			rc.Changes++
+		}
+		rc.LastChange = now
+	}
+	rc.LastVisit = now
+	rc.NextVisit = now.Add(rc.Interval)
+}
+
+// Due returns up to n records of kind, whose NextVisit has passed,
+// most overdue first. Kind "" returns both kinds; n < 1 returns all.
+func (s *Scheduler) Due(kind string, now time.Time, n int) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []Record{}
+	for _, rc := range s.sorted(kind) {
+		if rc.NextVisit.After(now) {
+			break
+		}
+		due = append(due, *rc)
+		if n > 0 && len(due) >= n {
+			break
+		}
+	}
+	return due
+}
+
+// IsDue tells, whether a directory should be visited.
+// Unknown directories are due.
+func (s *Scheduler) IsDue(dir string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.recs[KindDir+" "+cleanDir(dir)]
+	return !ok || !rc.NextVisit.After(now)
+}
+
+// Get returns the record of a URL or directory.
+func (s *Scheduler) Get(kind, key string) (Record, bool) {
+	if kind == KindDir {
+		key = cleanDir(key)
+	} else {
+		key = Key(key)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc, ok := s.recs[kind+" "+key]
+	if !ok {
+		return Record{}, false
+	}
+	return *rc, true
+}
+
+// sorted returns records by NextVisit; must be called under s.mu.
+func (s *Scheduler) sorted(kind string) []*Record {
+	recs := make([]*Record, 0, len(s.recs))
+	for _, rc := range s.recs {
+		if kind == "" || rc.Kind == kind {
+			recs = append(recs, rc)
+		}
+	}
+	sort.Sort(byNextVisit(recs))
+	return recs
+}
+
+func dirOf(key string) string {
+	if i := strings.Index(key, "?"); i >= 0 {
+		key = key[:i]
+	}
+	return path.Dir(key)
+}
+
+func cleanDir(dir string) string {
+	return path.Clean("/" + dir)
+}
+
+type byNextVisit []*Record
+
+func (s byNextVisit) Len() int      { return len(s) }
+func (s byNextVisit) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byNextVisit) Less(i, j int) bool {
+	if s[i].NextVisit.Equal(s[j].NextVisit) {
+		return s[i].Key < s[j].Key
+	}
+	return s[i].NextVisit.Before(s[j].NextVisit)
+}
@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestAdaptive(t *testing.T) {
+
+	fs := memfs.New()
+	s, err := Open(fs, "/sched/www.a.com.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2015, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+
+	// /news changes on every visit, /archive never
+	for i := 0; i < 8; i++ {
+		s.Observe("www.a.com/news/front", []byte{byte(i)}, time.Time{}, now)
+		s.Observe("https://www.a.com/archive/2001/x", []byte("same"), time.Time{}, now)
+		now = now.Add(time.Hour)
+	}
+
+	news, _ := s.Get(KindURL, "/news/front")
+	arch, _ := s.Get(KindURL, "www.a.com/archive/2001/x")
+	if news.Interval != s.MinInterval || news.Changes != 7 || news.ChangeRate() != 1 {
+		t.Errorf("news: %+v", news)
+	}
+	if arch.Interval <= s.InitialInterval || arch.Changes != 0 {
+		t.Errorf("archive: %+v", arch)
+	}
+
+	// dirs above a changed URL are due
+	if !s.IsDue("/news", now) || !s.IsDue("/", now) || !s.IsDue("/unknown", now) {
+		t.Errorf("dirs not due")
+	}
+	s.ObserveDir("/news", time.Time{}, now)
+	if s.IsDue("/news", now) {
+		t.Errorf("visited dir still due")
+	}
+
+	// unchanged by mod time
+	if s.Observe("/archive/2001/x", nil, time.Time{}, now) {
+		t.Errorf("no body, no mod: unchanged expected")
+	}
+
+	due := s.Due(KindURL, now.Add(time.Hour), 0)
+	if len(due) != 1 || due[0].Key != "/news/front" {
+		t.Errorf("due: %+v", due)
+	}
+
+	err = s.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Open(fs, "/sched/www.a.com.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc, ok := s2.Get(KindURL, "/news/front"); !ok || rc.Visits != 8 {
+		t.Errorf("reloaded: %+v", rc)
+	}
+}
+
+func TestKey(t *testing.T) {
+	cases := [][2]string{
+		{"www.a.com", "/"},
+		{"www.a.com?q=1", "/?q=1"},
+		{"https://www.a.com/b/c#f", "/b/c"},
+		{"/b/c?x=1", "/b/c?x=1"},
+	}
+	for _, c := range cases {
+		if k := Key(c[0]); k != c[1] {
+			t.Errorf("%v: want %v; got %v", c[0], c[1], k)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Package sitemap parses sitemap.xml files and sitemap indexes,
+// plain or gzip compressed, including the news extension.
+//
+// See http://www.sitemaps.org/protocol.html
+// and https://support.google.com/news/publisher/answer/74288
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// URL is an entry of a urlset.
+type URL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string  // always, hourly, daily, weekly, monthly, yearly, never
+	Priority   float64 // 0.0 ... 1.0; zero if missing
+	News       *News
+}
+
+// Mod is the best known modification time:
+// lastmod, else the news publication date.
+func (u URL) Mod() time.Time {
+	if u.LastMod.IsZero() && u.News != nil {
+		return u.News.PublicationDate
+	}
+	return u.LastMod
+}
+
+// News holds the news extension of an entry.
+type News struct {
+	PublicationName string
+	Language        string
+	PublicationDate time.Time
+	Title           string
+	Keywords        string
+}
+
+// Ref is an entry of a sitemap index.
+type Ref struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// Sitemap is either a urlset - or an index.
+type Sitemap struct {
+	URLs []URL
+	Refs []Ref
+}
+
+func (sm *Sitemap) IsIndex() bool { return len(sm.Refs) > 0 }
+
+// raw xml
+type xmlURLSet struct {
+	URLs []struct {
+		Loc        string  `xml:"loc"`
+		LastMod    string  `xml:"lastmod"`
+		ChangeFreq string  `xml:"changefreq"`
+		Priority   float64 `xml:"priority"`
+		News       *struct {
+			Publication struct {
+				Name     string `xml:"name"`
+				Language string `xml:"language"`
+			} `xml:"publication"`
+			PublicationDate string `xml:"publication_date"`
+			Title           string `xml:"title"`
+			Keywords        string `xml:"keywords"`
+		} `xml:"news"`
+	} `xml:"url"`
+}
+
+type xmlIndex struct {
+	Refs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Parse reads a urlset or a sitemap index.
+// Gzip is detected by its magic bytes, not by file name.
+func Parse(b []byte) (*Sitemap, error) {
+
+	if bytes.HasPrefix(b, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		b, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := rootElement(b)
+	if err != nil {
+		return nil, err
+	}
+
+	sm := &Sitemap{}
+	switch root {
+	case "urlset":
+		raw := xmlURLSet{}
+		err = xml.Unmarshal(b, &raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, ru := range raw.URLs {
+			u := URL{
+				Loc:        strings.TrimSpace(ru.Loc),
+				LastMod:    parseTime(ru.LastMod),
+				ChangeFreq: strings.ToLower(strings.TrimSpace(ru.ChangeFreq)),
+				Priority:   ru.Priority,
+			}
+			if ru.News != nil {
+				u.News = &News{
+					PublicationName: ru.News.Publication.Name,
+					Language:        ru.News.Publication.Language,
+					PublicationDate: parseTime(ru.News.PublicationDate),
+					Title:           strings.TrimSpace(ru.News.Title),
+					Keywords:        ru.News.Keywords,
+				}
+			}
+			if u.Loc != "" {
+				sm.URLs = append(sm.URLs, u)
+			}
+		}
+	case "sitemapindex":
+		raw := xmlIndex{}
+		err = xml.Unmarshal(b, &raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, rr := range raw.Refs {
+			ref := Ref{Loc: strings.TrimSpace(rr.Loc), LastMod: parseTime(rr.LastMod)}
+			if ref.Loc != "" {
+				sm.Refs = append(sm.Refs, ref)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no sitemap; root element is %q", root)
+	}
+	return sm, nil
+}
+
+func rootElement(b []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
+
+// W3C datetime, in decreasing precision
+var layouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseTime returns zero time for invalid dates.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Fetcher gets a sitemap by URL.
+type Fetcher func(surl string) ([]byte, error)
+
+// Collect fetches the sitemaps and follows indexes.
+// At most maxSitemaps files are fetched;
+// index entries with recent lastmod are preferred.
+// Failing sitemaps are skipped; their errors are returned.
+func Collect(get Fetcher, locs []string, maxSitemaps int) ([]URL, []error) {
+
+	var urls []URL
+	var errs []error
+
+	queue := append([]string{}, locs...)
+	seen := map[string]bool{}
+	fetched := 0
+
+	for len(queue) > 0 && fetched < maxSitemaps {
+
+		loc := queue[0]
+		queue = queue[1:]
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		b, err := get(loc)
+		fetched++
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", loc, err))
+			continue
+		}
+		sm, err := Parse(b)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", loc, err))
+			continue
+		}
+
+		urls = append(urls, sm.URLs...)
+
+		sort.Sort(byLastModDesc(sm.Refs))
+		for _, ref := range sm.Refs {
+			queue = append(queue, ref.Loc)
+		}
+	}
+
+	return urls, errs
+}
+
+type byLastModDesc []Ref
+
+func (s byLastModDesc) Len() int           { return len(s) }
+func (s byLastModDesc) Less(i, j int) bool { return s[i].LastMod.After(s[j].LastMod) }
+func (s byLastModDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
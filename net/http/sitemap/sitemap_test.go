@@ -0,0 +1,120 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">
+	<url>
+		<loc> http://www.example.com/news/europe/a1 </loc>
+		<lastmod>2015-10-04T18:30:02+01:00</lastmod>
+		<changefreq>Daily</changefreq>
+		<priority>0.8</priority>
+	</url>
+	<url>
+		<loc>http://www.example.com/news/europe/a2</loc>
+		<news:news>
+			<news:publication>
+				<news:name>The Example</news:name>
+				<news:language>en</news:language>
+			</news:publication>
+			<news:publication_date>2015-10-05</news:publication_date>
+			<news:title>Refugees</news:title>
+		</news:news>
+	</url>
+	<url>
+		<loc></loc>
+	</url>
+</urlset>`
+
+const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>http://www.example.com/sm-old.xml</loc><lastmod>2014-01-01</lastmod></sitemap>
+	<sitemap><loc>http://www.example.com/sm-new.xml.gz</loc><lastmod>2015-10-01T12:00Z</lastmod></sitemap>
+</sitemapindex>`
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+
+	sm, err := Parse([]byte(urlset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.IsIndex() || len(sm.URLs) != 2 {
+		t.Fatalf("want 2 urls; got %+v", sm)
+	}
+
+	u := sm.URLs[0]
+	if u.Loc != "http://www.example.com/news/europe/a1" || u.ChangeFreq != "daily" || u.Priority != 0.8 {
+		t.Errorf("got %+v", u)
+	}
+	want := time.Date(2015, 10, 4, 17, 30, 2, 0, time.UTC)
+	if !u.Mod().Equal(want) {
+		t.Errorf("lastmod %v; want %v", u.Mod(), want)
+	}
+
+	u = sm.URLs[1]
+	if u.News == nil || u.News.Title != "Refugees" || u.News.PublicationName != "The Example" {
+		t.Fatalf("news missing: %+v", u)
+	}
+	if got := u.Mod().Format("2006-01-02"); got != "2015-10-05" {
+		t.Errorf("publication date as mod; got %v", got)
+	}
+
+	sm, err = Parse(gzipped(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sm.IsIndex() || len(sm.Refs) != 2 || sm.Refs[1].LastMod.IsZero() {
+		t.Errorf("index: got %+v", sm)
+	}
+
+	_, err = Parse([]byte(`<rss><channel></channel></rss>`))
+	if err == nil {
+		t.Errorf("rss accepted as sitemap")
+	}
+}
+
+func TestCollect(t *testing.T) {
+
+	files := map[string][]byte{
+		"http://www.example.com/sitemap.xml":   []byte(index),
+		"http://www.example.com/sm-new.xml.gz": gzipped(urlset),
+		"http://www.example.com/sm-old.xml":    []byte(`<urlset><url><loc>http://www.example.com/old</loc></url></urlset>`),
+	}
+	var fetched []string
+	get := func(surl string) ([]byte, error) {
+		fetched = append(fetched, surl)
+		if b, ok := files[surl]; ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("not found")
+	}
+
+	// newest sub sitemap first; limit prevents the old one
+	urls, errs := Collect(get, []string{"http://www.example.com/sitemap.xml", "http://www.example.com/sitemap.xml"}, 2)
+	if len(errs) > 0 || len(urls) != 2 {
+		t.Errorf("got %v urls, errs %v", len(urls), errs)
+	}
+	if len(fetched) != 2 || fetched[1] != "http://www.example.com/sm-new.xml.gz" {
+		t.Errorf("fetch order %v", fetched)
+	}
+
+	urls, errs = Collect(get, []string{"http://www.example.com/missing.xml", "http://www.example.com/sitemap.xml"}, 10)
+	if len(errs) != 1 || len(urls) != 3 {
+		t.Errorf("got %v urls, errs %v", len(urls), errs)
+	}
+}
@@ -0,0 +1,71 @@
+package urlnorm
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/pbberlin/tools/net/http/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Canonical returns the absolute href of <link rel="canonical">,
+// or false, if the page has none in its head.
+func Canonical(page []byte, base *url.URL) (string, bool) {
+
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return "", false
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		switch tok.DataAtom {
+		case atom.Body:
+			return "", false
+		case atom.Base:
+			if href := dom.Attr(tok.Attr, "href"); href != "" && base != nil {
+				if u, err := base.Parse(href); err == nil {
+					base = u
+				}
+			}
+		case atom.Link:
+			if !dom.HasToken(dom.Attr(tok.Attr, "rel"), "canonical") {
+				continue
+			}
+			href := strings.TrimSpace(dom.Attr(tok.Attr, "href"))
+			if href == "" {
+				continue
+			}
+			u, err := url.Parse(href)
+			if err != nil {
+				return "", false
+			}
+			if base != nil {
+				u = base.ResolveReference(u)
+			}
+			if !u.IsAbs() {
+				return "", false
+			}
+			return u.String(), true
+		}
+	}
+}
+
+// Resolve returns the normalized canonical URL of a page.
+// The canonical link is only trusted on the same host;
+// otherwise - or without one - pageURL itself is normalized.
+func (n *Normalizer) Resolve(pageURL *url.URL, page []byte) *url.URL {
+	if c, ok := Canonical(page, pageURL); ok {
+		if cu, err := url.Parse(c); err == nil && strings.EqualFold(cu.Host, pageURL.Host) {
+			return n.NormalizeURL(cu)
+		}
+	}
+	return n.NormalizeURL(pageURL)
+}
+
+func Resolve(pageURL *url.URL, page []byte) *url.URL { return Std.Resolve(pageURL, page) }
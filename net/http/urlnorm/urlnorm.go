@@ -0,0 +1,318 @@
+// Package urlnorm reduces the many names of a document to one.
+//
+// Normalize lower-cases scheme and host, drops default ports and fragments,
+// normalizes percent-encoding and dot segments,
+// strips tracking params like utm_* or fsrc, sorts the remaining params,
+// and applies trailing-slash and index.html rules.
+// Rules can be set per host.
+//
+// Canonical resolves <link rel="canonical"> of a page.
+//
+// URLs without scheme - as used throughout package repo -
+// are returned without scheme.
+package urlnorm
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type SlashRule int
+
+const (
+	StripSlash SlashRule = iota // /news/ => /news
+	KeepSlash                   // leave as is
+	AddSlash                    // /news => /news/ - except for file names with extension
+)
+
+// Rules for a host.
+type Rules struct {
+	// Params to remove; a trailing * matches prefixes, i.e. utm_*
+	StripParams []string
+	// If not empty, only these params survive; StripParams is ignored.
+	KeepParams []string
+	// Remove the entire query.
+	DropQuery bool
+
+	// Last path segments, which stand for the directory, i.e. index.html
+	IndexFiles []string
+	Slash      SlashRule
+
+	// Empty keeps the scheme; "https" makes all URLs of the host https.
+	Scheme string
+}
+
+// Default applies to hosts without own rules.
+var Default = Rules{
+	StripParams: []string{"utm_*", "fsrc", "fbclid", "gclid", "mc_cid", "mc_eid", "ref_src"},
+	IndexFiles:  []string{"index.html", "index.htm", "index.php", "default.aspx"},
+	Slash:       StripSlash,
+}
+
+// Normalizer holds per-host rules. The zero value is not usable; use New.
+type Normalizer struct {
+	mu    sync.RWMutex
+	def   Rules
+	hosts map[string]Rules
+}
+
+func New(def Rules) *Normalizer {
+	return &Normalizer{def: def, hosts: map[string]Rules{}}
+}
+
+// Std is used by the package level funcs.
+var Std = New(Default)
+
+// SetRules replaces the rules of host; the host is matched lower case,
+// with and without "www."
+func (n *Normalizer) SetRules(host string, r Rules) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.hosts[strings.ToLower(host)] = r
+}
+
+// SetAll replaces the default and the rules of all hosts at once,
+// i.e. when a host config was reloaded.
+func (n *Normalizer) SetAll(def Rules, hosts map[string]Rules) {
+	hs := make(map[string]Rules, len(hosts))
+	for h, r := range hosts {
+		hs[strings.ToLower(h)] = r
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.def = def
+	n.hosts = hs
+}
+
+// Rules returns the rules of host, or the default.
+func (n *Normalizer) Rules(host string) Rules {
+	host = strings.ToLower(host)
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if r, ok := n.hosts[host]; ok {
+		return r
+	}
+	if r, ok := n.hosts[strings.TrimPrefix(host, "www.")]; ok {
+		return r
+	}
+	return n.def
+}
+
+func Normalize(surl string) (string, error) { return Std.Normalize(surl) }
+
+// Normalize returns the normalized form of surl.
+// A missing scheme stays missing.
+func (n *Normalizer) Normalize(surl string) (string, error) {
+	surl = strings.TrimSpace(surl)
+	schemeless := !strings.Contains(surl, "://")
+	if schemeless {
+		surl = "http://" + strings.TrimPrefix(surl, "//")
+	}
+	u, err := url.Parse(surl)
+	if err != nil {
+		return "", err
+	}
+	u = n.NormalizeURL(u)
+	s := u.String()
+	if schemeless {
+		s = strings.TrimPrefix(s, u.Scheme+"://")
+	}
+	return s, nil
+}
+
+// NormalizeURL returns a normalized copy of u.
+func (n *Normalizer) NormalizeURL(u *url.URL) *url.URL {
+
+	nu := *u
+	nu.User = nil
+	nu.Fragment = ""
+	nu.Opaque = ""
+
+	nu.Scheme = strings.ToLower(nu.Scheme)
+	nu.Host = strings.TrimSuffix(strings.ToLower(nu.Host), ".")
+	if host, port := splitPort(nu.Host); port == "80" && nu.Scheme == "http" || port == "443" && nu.Scheme == "https" {
+		nu.Host = host
+	}
+
+	rules := n.Rules(nu.Host)
+	if rules.Scheme != "" {
+		nu.Scheme = rules.Scheme
+	}
+
+	p := normalizeEscapes(nu.EscapedPath())
+	p = cleanPath(p)
+	p = applyIndexFiles(p, rules.IndexFiles)
+	p = applySlash(p, rules.Slash)
+	setPath(&nu, p)
+
+	nu.RawQuery = normalizeQuery(nu.RawQuery, rules)
+	nu.ForceQuery = false
+
+	return &nu
+}
+
+func splitPort(hostport string) (host, port string) {
+	i := strings.LastIndex(hostport, ":")
+	if i < 0 || strings.Contains(hostport[i:], "]") {
+		return hostport, ""
+	}
+	return hostport[:i], hostport[i+1:]
+}
+
+// setPath takes an escaped path;
+// url.Parse keeps RawPath where needed, i.e. for %2F.
+func setPath(u *url.URL, escaped string) {
+	pu, err := url.Parse("http://h" + escaped)
+	if err != nil {
+		return
+	}
+	u.Path, u.RawPath = pu.Path, pu.RawPath
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// normalizeEscapes decodes escaped unreserved chars
+// and upper-cases the hex digits of the others. RFC 3986, 6.2.2
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	const hexUpper = "0123456789ABCDEF"
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			h, ok1 := unhex(s[i+1])
+			l, ok2 := unhex(s[i+2])
+			if ok1 && ok2 {
+				c := h<<4 | l
+				if isUnreserved(c) {
+					b = append(b, c)
+				} else {
+					b = append(b, '%', hexUpper[h], hexUpper[l])
+				}
+				i += 2
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// cleanPath removes dot segments and double slashes,
+// keeping a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	trailing := strings.HasSuffix(p, "/")
+	p = path.Clean("/" + p)
+	if trailing && p != "/" {
+		p += "/"
+	}
+	return p
+}
+
+func applyIndexFiles(p string, indexFiles []string) string {
+	base := path.Base(p)
+	for _, idx := range indexFiles {
+		if strings.EqualFold(base, idx) {
+			return strings.TrimSuffix(p, base)
+		}
+	}
+	return p
+}
+
+func applySlash(p string, rule SlashRule) string {
+	if p == "/" {
+		return p
+	}
+	switch rule {
+	case StripSlash:
+		return strings.TrimSuffix(p, "/")
+	case AddSlash:
+		if !strings.HasSuffix(p, "/") && path.Ext(p) == "" {
+			return p + "/"
+		}
+	}
+	return p
+}
+
+func matchParam(name string, patterns []string) bool {
+	for _, pt := range patterns {
+		if strings.HasSuffix(pt, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pt, "*")) {
+				return true
+			}
+		} else if name == pt {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeQuery strips params and sorts the rest by name.
+// Values of the same name keep their order.
+func normalizeQuery(raw string, rules Rules) string {
+	if raw == "" || rules.DropQuery {
+		return ""
+	}
+	kvs := params{}
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		k, v := part, ""
+		hasV := false
+		if i := strings.Index(part, "="); i >= 0 {
+			k, v, hasV = part[:i], part[i+1:], true
+		}
+		name, err := url.QueryUnescape(k)
+		if err != nil {
+			name = k
+		}
+		if len(rules.KeepParams) > 0 {
+			if !matchParam(name, rules.KeepParams) {
+				continue
+			}
+		} else if matchParam(name, rules.StripParams) {
+			continue
+		}
+		k = normalizeEscapes(k)
+		if hasV {
+			v = "=" + normalizeEscapes(v)
+		}
+		kvs = append(kvs, param{k, v})
+	}
+	sort.Stable(kvs)
+	parts := make([]string, len(kvs))
+	for i, x := range kvs {
+		parts[i] = x.k + x.v
+	}
+	return strings.Join(parts, "&")
+}
+
+type param struct{ k, v string }
+type params []param
+
+func (s params) Len() int           { return len(s) }
+func (s params) Less(i, j int) bool { return s[i].k < s[j].k }
+func (s params) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
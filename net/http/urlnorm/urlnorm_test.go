@@ -0,0 +1,69 @@
+package urlnorm
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+
+	cases := [][2]string{
+		{"HTTP://WWW.Economist.COM:80/news/europe/?fsrc=rss#comments", "http://www.economist.com/news/europe"},
+		{"https://www.economist.com:443/news/a?utm_source=x&b=2&a=1&utm_medium=y", "https://www.economist.com/news/a?a=1&b=2"},
+		{"www.economist.com/news/./europe/../index.html", "www.economist.com/news"},
+		{"www.economist.com/%7euser/a%2fb/%c3%a4", "www.economist.com/~user/a%2Fb/%C3%A4"},
+		{"www.economist.com", "www.economist.com/"},
+		{"http://www.economist.com:8080/x?q=a%2bb", "http://www.economist.com:8080/x?q=a%2Bb"},
+		{"www.economist.com/a?b=2&b=1", "www.economist.com/a?b=2&b=1"},
+	}
+	for _, c := range cases {
+		got, err := Normalize(c[0])
+		if err != nil {
+			t.Errorf("%v: %v", c[0], err)
+			continue
+		}
+		if got != c[1] {
+			t.Errorf("%v\n\twant %v\n\tgot  %v", c[0], c[1], got)
+		}
+	}
+}
+
+func TestHostRules(t *testing.T) {
+	n := New(Default)
+	n.SetRules("handelsblatt.com", Rules{KeepParams: []string{"page"}, Slash: AddSlash, Scheme: "https"})
+
+	got, _ := n.Normalize("http://www.handelsblatt.com/politik?page=2&ref=top")
+	if want := "https://www.handelsblatt.com/politik/?page=2"; got != want {
+		t.Errorf("want %v; got %v", want, got)
+	}
+	got, _ = n.Normalize("http://www.handelsblatt.com/a.html")
+	if want := "https://www.handelsblatt.com/a.html"; got != want {
+		t.Errorf("want %v; got %v", want, got)
+	}
+	got, _ = n.Normalize("http://other.com/a/?fsrc=rss")
+	if want := "http://other.com/a"; got != want {
+		t.Errorf("default rules: want %v; got %v", want, got)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	page := []byte(`<html><head>
+		<link rel="stylesheet" href="/s.css">
+		<link rel="canonical" href="/news/europe/21661810-journey?page=1#x">
+		</head><body></body></html>`)
+	base, _ := url.Parse("http://www.economist.com/news/europe/21661810-journey?fsrc=rss")
+
+	c, ok := Canonical(page, base)
+	if !ok || c != "http://www.economist.com/news/europe/21661810-journey?page=1#x" {
+		t.Errorf("got %v %v", c, ok)
+	}
+
+	if got := Resolve(base, page).String(); got != "http://www.economist.com/news/europe/21661810-journey?page=1" {
+		t.Errorf("resolve: %v", got)
+	}
+
+	foreign := []byte(`<head><link rel="canonical" href="http://syndicator.com/x"></head>`)
+	if got := Resolve(base, foreign).String(); got != "http://www.economist.com/news/europe/21661810-journey" {
+		t.Errorf("foreign canonical trusted: %v", got)
+	}
+}
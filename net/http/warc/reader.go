@@ -0,0 +1,123 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Reader reads records sequentially,
+// from plain or gzipped files - gzipped per record or as a whole.
+type Reader struct {
+	br *bufio.Reader
+}
+
+func NewReader(rd io.Reader) (*Reader, error) {
+	br := bufio.NewReader(rd)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br) // multistream: members are read as one
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{br: br}, nil
+}
+
+// Next returns the next record, or io.EOF.
+func (r *Reader) Next() (*Record, error) {
+
+	// skip blank lines between records
+	var line string
+	for {
+		l, err := r.br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(l) == "" {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading version line: %v", err)
+		}
+		line = strings.TrimSpace(l)
+		if line != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("no WARC record: %q", line)
+	}
+
+	rec := &Record{}
+	for {
+		l, err := r.br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %v", err)
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" {
+			break
+		}
+		i := strings.Index(l, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("malformed header line %q", l)
+		}
+		rec.Header = append(rec.Header, Field{l[:i], strings.TrimSpace(l[i+1:])})
+	}
+
+	n := rec.contentLength()
+	if n < 0 {
+		return nil, fmt.Errorf("record %v without Content-Length", rec.ID())
+	}
+	rec.Block = make([]byte, n)
+	if _, err := io.ReadFull(r.br, rec.Block); err != nil {
+		return nil, fmt.Errorf("reading block of %v: %v", rec.ID(), err)
+	}
+	return rec, nil
+}
+
+// ReadFile returns all records of fn.
+func ReadFile(fs fsi.FileSystem, fn string) ([]*Record, error) {
+	b, err := fs.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	var recs []*Record
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// HTTPResponse parses the block of a response or revisit record.
+// The body of a revisit is empty.
+func (r *Record) HTTPResponse(req *http.Request) (*http.Response, error) {
+	if t := r.Type(); t != Response && t != Revisit {
+		return nil, fmt.Errorf("%v record has no http response", t)
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), req)
+}
+
+// Payload returns the body of a response record.
+func (r *Record) Payload() ([]byte, error) {
+	i := bytes.Index(r.Block, []byte("\r\n\r\n"))
+	if i < 0 {
+		return nil, fmt.Errorf("no http header end in %v", r.ID())
+	}
+	return r.Block[i+4:], nil
+}
@@ -0,0 +1,232 @@
+// Package warc writes and reads WARC/1.1 archives (ISO 28500:2017)
+// into and from any fsi.FileSystem.
+//
+// Each record is gzipped on its own, if the file name ends on .gz;
+// tools can seek to a record and decompress just that member.
+//
+// Record constructors exist for the types a crawler needs:
+// warcinfo, request, response, metadata and revisit.
+package warc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "WARC/1.1"
+
+// Record types
+const (
+	Warcinfo = "warcinfo"
+	Request  = "request"
+	Response = "response"
+	Metadata = "metadata"
+	Revisit  = "revisit"
+	Resource = "resource"
+)
+
+// ProfileIdenticalPayload marks revisits, whose payload equals the one referred to.
+const ProfileIdenticalPayload = "http://netpreserve.org/warc/1.1/revisit/identical-payload-digest"
+
+const (
+	ctHTTPRequest  = "application/http;msgtype=request"
+	ctHTTPResponse = "application/http;msgtype=response"
+	ctFields       = "application/warc-fields"
+)
+
+// Field is a named header field of a record.
+type Field struct {
+	Name, Value string
+}
+
+// Header keeps the fields in order of insertion;
+// names are matched case insensitive.
+type Header []Field
+
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the first field of name or appends it.
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{name, value})
+}
+
+type Record struct {
+	Header Header
+	Block  []byte
+}
+
+func (r *Record) Type() string      { return r.Header.Get("WARC-Type") }
+func (r *Record) ID() string        { return r.Header.Get("WARC-Record-ID") }
+func (r *Record) TargetURI() string { return r.Header.Get("WARC-Target-URI") }
+
+func (r *Record) Date() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, r.Header.Get("WARC-Date"))
+	return t
+}
+
+// New returns a record with ID, date, digest and content type set.
+func New(typ, targetURI string, date time.Time, contentType string, block []byte) *Record {
+	r := &Record{Block: block}
+	r.Header.Set("WARC-Type", typ)
+	r.Header.Set("WARC-Record-ID", NewID())
+	r.Header.Set("WARC-Date", date.UTC().Format(time.RFC3339Nano))
+	if targetURI != "" {
+		r.Header.Set("WARC-Target-URI", targetURI)
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("WARC-Block-Digest", Digest(block))
+	return r
+}
+
+// NewID returns a random urn:uuid in angle brackets.
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Digest is the labelled base32 sha1, customary in WARC files.
+func Digest(b []byte) string {
+	h := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(h[:])
+}
+
+// NewWarcinfo describes the crawl; fields are written sorted by name.
+func NewWarcinfo(filename string, date time.Time, fields map[string]string) *Record {
+	r := New(Warcinfo, "", date, ctFields, encodeFields(fields))
+	r.Header.Set("WARC-Filename", filename)
+	return r
+}
+
+// NewRequest records a request without body.
+func NewRequest(method, targetURI string, hdr http.Header, date time.Time) *Record {
+	var b bytes.Buffer
+	uri, host := targetURI, ""
+	if i := strings.Index(uri, "://"); i >= 0 {
+		uri = uri[i+3:]
+		if j := strings.Index(uri, "/"); j >= 0 {
+			host, uri = uri[:j], uri[j:]
+		} else {
+			host, uri = uri, "/"
+		}
+	}
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", method, uri)
+	if host != "" && hdr.Get("Host") == "" {
+		fmt.Fprintf(&b, "Host: %s\r\n", host)
+	}
+	hdr.Write(&b)
+	b.WriteString("\r\n")
+	return New(Request, targetURI, date, ctHTTPRequest, b.Bytes())
+}
+
+// NewResponse records status, header and the body as received.
+func NewResponse(targetURI string, status int, hdr http.Header, body []byte, date time.Time) *Record {
+	head := httpHead(status, hdr)
+	r := New(Response, targetURI, date, ctHTTPResponse, append(head, body...))
+	r.Header.Set("WARC-Payload-Digest", Digest(body))
+	return r
+}
+
+// NewRevisit records a response, whose payload was archived before;
+// only status and header are kept.
+// refersTo and refersToDate identify the earlier response, if known.
+func NewRevisit(targetURI string, status int, hdr http.Header, payloadDigest string,
+	refersTo string, refersToDate time.Time, date time.Time) *Record {
+
+	r := New(Revisit, targetURI, date, ctHTTPResponse, httpHead(status, hdr))
+	r.Header.Set("WARC-Profile", ProfileIdenticalPayload)
+	r.Header.Set("WARC-Payload-Digest", payloadDigest)
+	if refersTo != "" {
+		r.Header.Set("WARC-Refers-To", refersTo)
+	}
+	r.Header.Set("WARC-Refers-To-Target-URI", targetURI)
+	if !refersToDate.IsZero() {
+		r.Header.Set("WARC-Refers-To-Date", refersToDate.UTC().Format(time.RFC3339))
+	}
+	return r
+}
+
+// NewMetadata records fields about the record concurrentTo,
+// i.e. the canonical URL or the outlinks of a page.
+func NewMetadata(targetURI, concurrentTo string, fields map[string]string, date time.Time) *Record {
+	r := New(Metadata, targetURI, date, ctFields, encodeFields(fields))
+	if concurrentTo != "" {
+		r.Header.Set("WARC-Concurrent-To", concurrentTo)
+	}
+	return r
+}
+
+func httpHead(status int, hdr http.Header) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	hdr.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func encodeFields(fields map[string]string) []byte {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		for _, line := range strings.Split(fields[k], "\n") {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, strings.TrimSpace(line))
+		}
+	}
+	return b.Bytes()
+}
+
+// Fields decodes the block of warcinfo and metadata records.
+// Repeated names are joined by newline.
+func (r *Record) Fields() map[string]string {
+	ret := map[string]string{}
+	for _, line := range strings.Split(string(r.Block), "\n") {
+		line = strings.TrimRight(line, "\r")
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		k, v := line[:i], strings.TrimSpace(line[i+1:])
+		if prev, ok := ret[k]; ok {
+			v = prev + "\n" + v
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+func (r *Record) contentLength() int {
+	n, err := strconv.Atoi(r.Header.Get("Content-Length"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package warc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestWriteRead(t *testing.T) {
+
+	for _, fn := range []string{"/warc/a.warc.gz", "/warc/a.warc"} {
+
+		fs := memfs.New()
+		now := time.Date(2015, 10, 4, 18, 30, 2, 0, time.UTC)
+		uri := "http://www.economist.com/news/europe"
+		body := []byte("<html><body>Refugees</body></html>")
+
+		reqHdr := http.Header{"User-Agent": {"Go-http-client"}}
+		respHdr := http.Header{"Content-Type": {"text/html"}, "Last-Modified": {"Sun, 04 Oct 2015 18:30:02 GMT"}}
+
+		req := NewRequest("GET", uri, reqHdr, now)
+		resp := NewResponse(uri, 200, respHdr, body, now)
+		resp.Header.Set("WARC-Concurrent-To", req.ID())
+		md := NewMetadata(uri, resp.ID(), map[string]string{"canonical": uri, "outlink": "a\nb"}, now)
+		rev := NewRevisit(uri, 304, http.Header{"Etag": {`"x1"`}}, Digest(body), resp.ID(), now, now.Add(time.Hour))
+
+		w := NewWriter(fs, fn)
+		if err := w.Write(NewWarcinfo("a.warc.gz", now, map[string]string{"software": "repo"}), req, resp, md, rev); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		recs, err := ReadFile(fs, fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 5 {
+			t.Fatalf("%v: want 5 records; got %v", fn, len(recs))
+		}
+		types := []string{Warcinfo, Request, Response, Metadata, Revisit}
+		for i, r := range recs {
+			if r.Type() != types[i] {
+				t.Errorf("record %v: want %v; got %v", i, types[i], r.Type())
+			}
+			if r.Header.Get("WARC-Block-Digest") != Digest(r.Block) {
+				t.Errorf("record %v: block digest mismatch", i)
+			}
+		}
+
+		rsp := recs[2]
+		if rsp.ID() != resp.ID() || !rsp.Date().Equal(now) || rsp.TargetURI() != uri {
+			t.Errorf("response header %+v", rsp.Header)
+		}
+		pl, err := rsp.Payload()
+		if err != nil || string(pl) != string(body) || rsp.Header.Get("WARC-Payload-Digest") != Digest(body) {
+			t.Errorf("payload %q %v", pl, err)
+		}
+		hr, err := rsp.HTTPResponse(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hb, _ := ioutil.ReadAll(hr.Body)
+		if hr.StatusCode != 200 || hr.Header.Get("Last-Modified") == "" || string(hb) != string(body) {
+			t.Errorf("http response %v %v %q", hr.StatusCode, hr.Header, hb)
+		}
+
+		if f := recs[3].Fields(); f["outlink"] != "a\nb" || f["canonical"] != uri {
+			t.Errorf("metadata fields %v", f)
+		}
+		if recs[4].Header.Get("WARC-Refers-To") != resp.ID() || recs[4].Header.Get("WARC-Profile") != ProfileIdenticalPayload {
+			t.Errorf("revisit %+v", recs[4].Header)
+		}
+	}
+}
+
+func TestEmptyWriter(t *testing.T) {
+	fs := memfs.New()
+	w := NewWriter(fs, "/warc/empty.warc.gz")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/warc/empty.warc.gz"); err == nil {
+		t.Errorf("empty writer saved a file")
+	}
+}
+
+func TestRollover(t *testing.T) {
+	fs := memfs.New()
+	now := time.Now()
+	w := NewWriter(fs, "/warc/a.warc.gz")
+	w.MaxRecords = 4
+
+	for i := 0; i < 5; i++ {
+		uri := fmt.Sprintf("http://www.a.com/%v", i)
+		req := NewRequest("GET", uri, nil, now)
+		resp := NewResponse(uri, 200, http.Header{}, []byte("body"), now)
+		if err := w.Write(req, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the full files are saved before Close
+	if got := w.Files(); len(got) != 2 || got[1] != "/warc/a-00001.warc.gz" {
+		t.Errorf("files before close %v", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, fn := range w.Files() {
+		recs, err := ReadFile(fs, fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) > 4 || recs[0].Type() != Request {
+			t.Errorf("%v: %v records, first %v", fn, len(recs), recs[0].Type())
+		}
+		total += len(recs)
+	}
+	if total != 10 || w.Len() != 10 || len(w.Files()) != 3 {
+		t.Errorf("%v records in %v", total, w.Files())
+	}
+}
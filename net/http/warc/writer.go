@@ -0,0 +1,156 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Writer collects records into a series of WARC files.
+// Not every fsi.FileSystem can append to a file;
+// therefore a file is written once it is full, and the last one on Close.
+// Records of one Write call stay in the same file.
+//
+// The first file is fn; the following are numbered,
+// i.e. a.warc.gz, a-00001.warc.gz, a-00002.warc.gz.
+type Writer struct {
+	fs   fsi.FileSystem
+	fn   string
+	gzip bool
+
+	// A file is full, when it reaches either limit; zero means no limit.
+	// MaxSize is in bytes - as written, after compression.
+	MaxSize    int
+	MaxRecords int
+
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	cnt   int // records in buf
+	total int
+	files []string // written so far
+}
+
+// Default limits; dsfs does not store files beyond one megabyte.
+const (
+	DefaultMaxSize    = 900 * 1024
+	DefaultMaxRecords = 1000
+)
+
+// NewWriter writes to fn and its successors; records are gzipped, if fn ends on .gz.
+func NewWriter(fs fsi.FileSystem, fn string) *Writer {
+	return &Writer{fs: fs, fn: fn, gzip: strings.HasSuffix(fn, ".gz"),
+		MaxSize: DefaultMaxSize, MaxRecords: DefaultMaxRecords}
+}
+
+// Name is the file currently being filled.
+func (w *Writer) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.name(len(w.files))
+}
+
+// name of the i-th file.
+func (w *Writer) name(i int) string {
+	if i == 0 {
+		return w.fn
+	}
+	dir, base := path.Split(w.fn)
+	ext := ""
+	if j := strings.Index(base, ".warc"); j >= 0 {
+		base, ext = base[:j], base[j:]
+	}
+	return fmt.Sprintf("%v%v-%05d%v", dir, base, i, ext)
+}
+
+// Files returns the names of the files written so far.
+func (w *Writer) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string{}, w.files...)
+}
+
+// Write appends records. Content-Length is set here.
+// If the records do not fit into the current file,
+// it is saved, and they go into the next one.
+func (w *Writer) Write(recs ...*Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var grp bytes.Buffer
+	for _, r := range recs {
+		if r.Type() == "" || r.ID() == "" {
+			return fmt.Errorf("record without WARC-Type or WARC-Record-ID")
+		}
+		r.Header.Set("Content-Length", strconv.Itoa(len(r.Block)))
+
+		var raw bytes.Buffer
+		raw.WriteString(Version + "\r\n")
+		for _, f := range r.Header {
+			fmt.Fprintf(&raw, "%s: %s\r\n", f.Name, f.Value)
+		}
+		raw.WriteString("\r\n")
+		raw.Write(r.Block)
+		raw.WriteString("\r\n\r\n")
+
+		if !w.gzip {
+			grp.Write(raw.Bytes())
+		} else {
+			// one gzip member per record
+			zw := gzip.NewWriter(&grp)
+			if _, err := zw.Write(raw.Bytes()); err != nil {
+				return err
+			}
+			if err := zw.Close(); err != nil {
+				return err
+			}
+		}
+	}
+
+	full := w.MaxSize > 0 && w.buf.Len()+grp.Len() > w.MaxSize ||
+		w.MaxRecords > 0 && w.cnt+len(recs) > w.MaxRecords
+	if full && w.cnt > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.buf.Write(grp.Bytes())
+	w.cnt += len(recs)
+	w.total += len(recs)
+	return nil
+}
+
+// flush saves the current file and starts the next one.
+func (w *Writer) flush() error {
+	fn := w.name(len(w.files))
+	if err := common.WriteFile(w.fs, fn, w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.files = append(w.files, fn)
+	w.buf.Reset()
+	w.cnt = 0
+	return nil
+}
+
+// Len is the number of records written, in all files.
+func (w *Writer) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.total
+}
+
+// Close saves the last file. Without records, nothing is saved.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cnt == 0 {
+		return nil
+	}
+	return w.flush()
+}
@@ -0,0 +1,29 @@
+package fsi
+
+import (
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// Interface FileSystemContext is an optional extension to FileSystem.
+// Its methods respect cancellation and deadlines of the submitted context.
+// A cancelled call returns ctx.Err().
+//
+// Check for it with a type assertion:
+//
+//	fsc, ok := fs.(fsi.FileSystemContext)
+//
+// or use the fallbacks in package common,
+// which check the context themselves, if fs does not implement it.
+//
+// WalkContext is implemented generically in package common,
+// for the same reasons as Walk.
+type FileSystemContext interface {
+	FileSystem
+
+	OpenContext(ctx context.Context, name string) (File, error)
+	LstatContext(ctx context.Context, path string) (os.FileInfo, error)
+	ReadDirContext(ctx context.Context, dirname string) ([]os.FileInfo, error)
+	RemoveAllContext(ctx context.Context, path string) error
+}
@@ -0,0 +1,41 @@
+package fsi
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrLocked      = errors.New("File is locked")
+	ErrLockExpired = errors.New("Lock lease expired")
+)
+
+type LockMode int
+
+const (
+	LockShared    LockMode = iota // many readers
+	LockExclusive                 // one writer
+)
+
+// Lock is held until Unlock() or until its lease expires.
+type Lock interface {
+	Unlock() error
+	// Refresh extends the lease, counting from now.
+	// It returns ErrLockExpired, if the lock was already lost.
+	Refresh(lease time.Duration) error
+}
+
+// Interface Locker is an optional extension to FileSystem.
+// It provides advisory locks on logical file names.
+// Locks do not prevent any file operation;
+// they only coordinate callers, which all ask for them.
+//
+// TryLock never blocks. It returns ErrLocked,
+// if the lock is held incompatibly by someone else.
+// A lease of zero means: no expiry -
+// except for dsfs, which then applies a default lease.
+//
+// A blocking variant, waiting for a context, is common.Lock().
+type Locker interface {
+	TryLock(name string, mode LockMode, lease time.Duration) (Lock, error)
+}
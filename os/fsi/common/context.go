@@ -0,0 +1,88 @@
+package common
+
+import (
+	"os"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/context"
+)
+
+// OpenContext uses fs.OpenContext, if fs implements fsi.FileSystemContext.
+// Otherwise it checks ctx before calling fs.Open.
+func OpenContext(ctx context.Context, fs fsi.FileSystem, name string) (fsi.File, error) {
+	if fsc, ok := fs.(fsi.FileSystemContext); ok {
+		return fsc.OpenContext(ctx, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.Open(name)
+}
+
+// LstatContext - see OpenContext.
+func LstatContext(ctx context.Context, fs fsi.FileSystem, path string) (os.FileInfo, error) {
+	if fsc, ok := fs.(fsi.FileSystemContext); ok {
+		return fsc.LstatContext(ctx, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.Lstat(path)
+}
+
+// ReadDirContext - see OpenContext.
+func ReadDirContext(ctx context.Context, fs fsi.FileSystem, dirname string) ([]os.FileInfo, error) {
+	if fsc, ok := fs.(fsi.FileSystemContext); ok {
+		return fsc.ReadDirContext(ctx, dirname)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(dirname)
+}
+
+// RemoveAllContext - see OpenContext.
+func RemoveAllContext(ctx context.Context, fs fsi.FileSystem, path string) error {
+	if fsc, ok := fs.(fsi.FileSystemContext); ok {
+		return fsc.RemoveAllContext(ctx, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fs.RemoveAll(path)
+}
+
+// RemoveAllByWalk removes path and its subtree,
+// deepest entries first, checking ctx before each removal.
+// Filesystems without native recursive removal
+// can build their RemoveAllContext on it.
+func RemoveAllByWalk(ctx context.Context, fs fsi.FileSystem, path string) error {
+
+	paths := []string{}
+	walkCollect := func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return nil // do nothing; don't break the walk
+		}
+		if f != nil {
+			paths = append(paths, path)
+		}
+		return nil
+	}
+
+	err := WalkContext(ctx, fs, path, walkCollect)
+	if err != nil {
+		return err
+	}
+
+	// Walk crawls directories first, files second.
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fs.Remove(paths[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/context"
+)
+
+// Lock blocks until the lock is acquired, or until ctx is done.
+// It polls fs.TryLock with increasing intervals.
+// It returns fsi.NotImplemented, if fs is no fsi.Locker.
+func Lock(ctx context.Context, fs fsi.FileSystem, name string, mode fsi.LockMode, lease time.Duration) (fsi.Lock, error) {
+
+	lckr, ok := fs.(fsi.Locker)
+	if !ok {
+		return nil, fsi.NotImplemented
+	}
+
+	wait := 5 * time.Millisecond
+	for {
+		lck, err := lckr.TryLock(name, mode, lease)
+		if err != fsi.ErrLocked {
+			return lck, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < 500*time.Millisecond {
+			wait *= 2
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Less is a single sort key for directory listings.
+// It reports whether a sorts before b.
+// Custom comparators are simply further Less funcs.
+type Less func(a, b os.FileInfo) bool
+
+func ByName(a, b os.FileInfo) bool    { return a.Name() < b.Name() }
+func BySize(a, b os.FileInfo) bool    { return a.Size() < b.Size() }
+func ByModTime(a, b os.FileInfo) bool { return a.ModTime().Before(b.ModTime()) }
+
+// DirsFirst puts directories before files.
+func DirsFirst(a, b os.FileInfo) bool { return a.IsDir() && !b.IsDir() }
+
+// ByNaturalName compares digit runs by their numeric value:
+// "page2" sorts before "page10".
+func ByNaturalName(a, b os.FileInfo) bool { return naturalLess(a.Name(), b.Name()) }
+
+// Desc reverses a key.
+func Desc(l Less) Less {
+	return func(a, b os.FileInfo) bool { return l(b, a) }
+}
+
+// Sorter combines keys.
+// Later keys break ties of earlier keys.
+// Remaining ties are broken by name,
+// so that pages of a listing never overlap.
+// A nil Sorter sorts by name.
+type Sorter []Less
+
+func SortBy(keys ...Less) Sorter {
+	return Sorter(keys)
+}
+
+func (s Sorter) Less(a, b os.FileInfo) bool {
+	for _, l := range s {
+		if l(a, b) {
+			return true
+		}
+		if l(b, a) {
+			return false
+		}
+	}
+	return a.Name() < b.Name()
+}
+
+func (s Sorter) Sort(fis []os.FileInfo) {
+	sort.Sort(fileInfos{fis, s})
+}
+
+// fileInfos implements sort.Interface.
+type fileInfos struct {
+	fis []os.FileInfo
+	s   Sorter
+}
+
+func (f fileInfos) Len() int           { return len(f.fis) }
+func (f fileInfos) Less(i, j int) bool { return f.s.Less(f.fis[i], f.fis[j]) }
+func (f fileInfos) Swap(i, j int)      { f.fis[i], f.fis[j] = f.fis[j], f.fis[i] }
+
+var sortKeys = map[string]Less{
+	"name":    ByName,
+	"natural": ByNaturalName,
+	"size":    BySize,
+	"mtime":   ByModTime,
+	"dirs":    DirsFirst,
+}
+
+// Formerly DirSort() options of memfs, osfs and dsfs.
+var legacySorts = map[string]string{
+	"byName":     "name",
+	"byDateAsc":  "mtime",
+	"byDateDesc": "-mtime",
+}
+
+// ParseSort reads a comma separated list of keys,
+// for instance from a config file or from a request param.
+// Keys are name, natural, size, mtime and dirs.
+// A leading "-" reverses a key.
+//
+//	dirs,-mtime,natural
+//
+// The former option strings byName, byDateAsc, byDateDesc are accepted too.
+func ParseSort(spec string) (Sorter, error) {
+	keys, err := SortKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+	s := Sorter{}
+	for _, key := range keys {
+		l := sortKeys[strings.TrimPrefix(key, "-")]
+		if strings.HasPrefix(key, "-") {
+			l = Desc(l)
+		}
+		s = append(s, l)
+	}
+	return s, nil
+}
+
+// SortKeys checks spec and splits it into its keys,
+// i.e. "byDateDesc" into ["-mtime"].
+// Filesystems translate the keys into queries of their own.
+func SortKeys(spec string) ([]string, error) {
+	if legacy, ok := legacySorts[spec]; ok {
+		spec = legacy
+	}
+	keys := []string{}
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if _, ok := sortKeys[strings.TrimPrefix(key, "-")]; !ok {
+			return nil, fmt.Errorf("unknown sort key %q in %q", key, spec)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// DirPager is implemented by filesystems,
+// which page through a directory without reading all of it.
+type DirPager interface {
+	ReadDirPage(name string, spec string, offset, limit int) ([]os.FileInfo, error)
+}
+
+// ReadDirPage returns entries offset through offset+limit-1,
+// sorted by a ParseSort spec.
+// An empty spec keeps the order of the filesystem, set by its DirSort option.
+// limit <= 0 returns all remaining entries.
+// DirPagers, i.e. dsfs, only read the page from their store.
+func ReadDirPage(fs fsi.FileSystem, name string, spec string, offset, limit int) ([]os.FileInfo, error) {
+	if p, ok := fs.(DirPager); ok {
+		return p.ReadDirPage(name, spec, offset, limit)
+	}
+	var s Sorter
+	if spec != "" {
+		var err error
+		s, err = ParseSort(spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fis, err := fs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return Page(fis, s, offset, limit), nil
+}
+
+// Page sorts a listing by s, unless nil, and returns entries offset through offset+limit-1.
+// It serves custom comparators, which have no spec.
+func Page(fis []os.FileInfo, s Sorter, offset, limit int) []os.FileInfo {
+	if s != nil {
+		s.Sort(fis)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(fis) {
+		offset = len(fis)
+	}
+	fis = fis[offset:]
+	if limit > 0 && limit < len(fis) {
+		fis = fis[:limit]
+	}
+	return fis
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// naturalLess compares runs of digits numerically
+// and everything else bytewise.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, ra := digitRun(a)
+			nb, rb := digitRun(b)
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			if len(na) != len(nb) { // "01" before "1"
+				return len(na) > len(nb)
+			}
+			a, b = ra, rb
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitRun(s string) (run, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -12,6 +12,7 @@ import (
 	pth "path"
 
 	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/context"
 )
 
 // SkipDir is an "error", which a walk-function can
@@ -23,7 +24,12 @@ type WalkFunc func(path string, info os.FileInfo, err error) error
 var cntr = 0
 
 // walk recursively descends path, calling walkFn.
-func walk(fs fsi.FileSystem, path string, info os.FileInfo, walkFn WalkFunc) error {
+// It checks ctx before each call to walkFn and before each directory read.
+func walk(ctx context.Context, fs fsi.FileSystem, path string, info os.FileInfo, walkFn WalkFunc) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// cntr++
 	// if cntr > 20 {
@@ -42,12 +48,15 @@ func walk(fs fsi.FileSystem, path string, info os.FileInfo, walkFn WalkFunc) err
 		return nil
 	}
 
-	fis, err := fs.ReadDir(path)
+	fis, err := ReadDirContext(ctx, fs, path)
 	// fnd := ""
 	// for i := 0; i < len(fis); i++ {
 	// 	fnd += fis[i].Name() + ", "
 	// }
 	// log.Printf("readdir of %-26v  => %v, %v", path, len(fis), fnd)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil && err != fsi.EmptyQueryResult {
 		return walkFn(path, info, err)
 	}
@@ -56,13 +65,16 @@ func walk(fs fsi.FileSystem, path string, info os.FileInfo, walkFn WalkFunc) err
 	for _, fi := range fis {
 		filename := pth.Join(path, pth.Base(fi.Name()))
 
-		fileInfo, err := fs.Lstat(filename)
+		fileInfo, err := LstatContext(ctx, fs, filename)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			if err := walkFn(filename, fileInfo, err); err != nil && err != SkipDir {
 				return err
 			}
 		} else {
-			err = walk(fs, filename, fileInfo, walkFn)
+			err = walk(ctx, fs, filename, fileInfo, walkFn)
 			if err != nil {
 				if !fileInfo.IsDir() || err != SkipDir {
 					return err
@@ -87,11 +99,21 @@ func walk(fs fsi.FileSystem, path string, info os.FileInfo, walkFn WalkFunc) err
 //
 // Walk does not follow symbolic links.
 func Walk(fs fsi.FileSystem, root string, walkFn WalkFunc) error {
-	info, err := fs.Lstat(root)
+	return WalkContext(context.Background(), fs, root, walkFn)
+}
+
+// WalkContext is Walk, aborting as soon as ctx is done.
+// It then returns ctx.Err(), without calling walkFn again.
+// Directory reads use fsi.FileSystemContext, if fs implements it.
+func WalkContext(ctx context.Context, fs fsi.FileSystem, root string, walkFn WalkFunc) error {
+	info, err := LstatContext(ctx, fs, root)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		// log.Printf("walk start error %10v %v", root, err)
 		return walkFn(root, nil, err)
 	}
 	// log.Printf("walk start fnd %v", info.Name())
-	return walk(fs, root, info, walkFn)
+	return walk(ctx, fs, root, info, walkFn)
 }
@@ -18,6 +18,7 @@ const (
 	tdir    = "fsd"      // datastory entity type for filesystem directory
 	tdirsep = tdir + "," // nested datastore keys each have this prefix
 	tfil    = "fsf"      // datastory entity type for filesystem file
+	tlck    = "fsl"      // datastory entity type for lock leases
 	sep     = "/"        // no, package path does not provide it; yes, we do need it.
 )
 
@@ -37,4 +38,10 @@ func init() {
 	ifs := fsi.FileSystem(&fs)
 	_ = ifs
 
+	ifsc := fsi.FileSystemContext(&fs)
+	_ = ifsc
+
+	ilck := fsi.Locker(&fs)
+	_ = ilck
+
 }
@@ -15,6 +15,9 @@ type dsFileSys struct {
 	// r *http.Request       `datastore:"-" json:"-"`
 	c context.Context `datastore:"-" json:"-"`
 
+	// bound is the caller context, c was derived from by bind(); if any
+	bound context.Context `datastore:"-" json:"-"`
+
 	mount string // name of mount point, for remount
 
 	dirsorter  func([]os.FileInfo)
@@ -1,10 +1,8 @@
 package dsfs
 
 import (
-	"os"
-	"sort"
-
 	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
 
 	"golang.org/x/net/context"
 	"google.golang.org/appengine/datastore"
@@ -28,22 +26,13 @@ func MountName(mnt string) func(fsi.FileSystem) {
 	}
 }
 
-// Default sort for ReadDir... is ByNameAsc
-// We may want to change this; for instance sort byDate
-func DirSort(srt string) func(fsi.FileSystem) {
+// DirSort is an option func for the order of directory listings.
+// Default is directories first, then by name.
+// Without common.DirsFirst, dirs and files are mixed.
+func DirSort(keys ...common.Less) func(fsi.FileSystem) {
 	return func(fs fsi.FileSystem) {
 		fst := fs.(*dsFileSys)
-		switch srt {
-		case "byDateAsc":
-			fst.dirsorter = func(fis []os.FileInfo) { sort.Sort(FileInfoByDateAsc(fis)) }
-			fst.filesorter = func(fis []DsFile) { sort.Sort(DsFileByDateAsc(fis)) }
-		case "byDateDesc":
-			fst.dirsorter = func(fis []os.FileInfo) { sort.Sort(FileInfoByDateDesc(fis)) }
-			fst.filesorter = func(fis []DsFile) { sort.Sort(DsFileByDateDesc(fis)) }
-		case "byName":
-			fst.dirsorter = func(fis []os.FileInfo) { sort.Sort(FileInfoByName(fis)) }
-			fst.filesorter = func(fis []DsFile) { sort.Sort(DsFileByName(fis)) }
-		}
+		fst.setSorter(common.SortBy(keys...))
 	}
 }
 
@@ -55,8 +44,7 @@ func New(options ...func(fsi.FileSystem)) *dsFileSys {
 
 	fs := dsFileSys{}
 
-	fs.dirsorter = func(fis []os.FileInfo) { sort.Sort(FileInfoByName(fis)) }
-	fs.filesorter = func(fis []DsFile) { sort.Sort(DsFileByName(fis)) }
+	fs.setSorter(common.SortBy(common.DirsFirst, common.ByName))
 
 	for _, option := range options {
 		option(&fs)
@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/appengine/datastore"
 
 	"github.com/pbberlin/tools/os/fsi"
@@ -191,18 +192,17 @@ func (fs *dsFileSys) ReadDir(name string) ([]os.FileInfo, error) {
 	if err != nil && err != fsi.EmptyQueryResult {
 		return nil, err
 	}
-	fs.dirsorter(dirs)
 
 	files, err := fs.filesByPath(name)
 	// fs.Ctx().Infof("dsfs readdir %-20v fils %v %v", name, len(files), err)
 	if err != nil {
 		return nil, err
 	}
-	fs.filesorter(files)
 
 	for _, v := range files {
 		dirs = append(dirs, os.FileInfo(v))
 	}
+	fs.dirsorter(dirs) // dirs and files mixed, i.e. by mtime
 	return dirs, nil
 }
 
@@ -234,6 +234,11 @@ func (fs *dsFileSys) Remove(name string) error {
 }
 
 func (fs *dsFileSys) RemoveAll(path string) error {
+	return fs.removeAll(context.Background(), path)
+}
+
+// removeAll is RemoveAll, checking ctx between datastore operations.
+func (fs *dsFileSys) removeAll(ctx context.Context, path string) error {
 
 	paths := []string{}
 	walkRemove := func(path string, f os.FileInfo, err error) error {
@@ -248,14 +253,21 @@ func (fs *dsFileSys) RemoveAll(path string) error {
 		return nil
 	}
 
-	err := common.Walk(fs, path, walkRemove)
+	err := common.WalkContext(ctx, fs, path, walkRemove)
 	if err != nil {
 		aelog.Errorf(fs.Ctx(), "Error removing %v => %v", path, err)
 	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
 	// Walk crawls directories first, files second.
 	// Intuitively removal in reverse order should always work. Or does it not?
 	for i := 0; i < len(paths); i++ {
+		if ctx.Err() != nil {
+			aelog.Infof(fs.Ctx(), "removal of %v cancelled; %v of %v paths removed", path, i, len(paths))
+			return ctx.Err()
+		}
 		iRev := len(paths) - 1 - i
 		err := fs.Remove(paths[iRev])
 		if err != nil {
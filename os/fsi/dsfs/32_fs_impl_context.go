@@ -0,0 +1,113 @@
+package dsfs
+
+import (
+	"os"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"golang.org/x/net/context"
+)
+
+// The appengine context is captured once in New().
+// The *Context methods derive a context from it,
+// which is cancelled as soon as the caller's ctx is done,
+// and which inherits the caller's deadline.
+// The datastore operations then abort on their own.
+
+// bind returns a shallow copy of fs,
+// whose appengine context is bound to ctx.
+// The returned cancel func must be called to release the watcher.
+func (fs *dsFileSys) bind(ctx context.Context) (*dsFileSys, context.CancelFunc) {
+
+	if fs.bound == ctx {
+		return fs, func() {} // already bound; i.e. nested calls from common.WalkContext
+	}
+	if ctx.Done() == nil {
+		return fs, func() {} // never cancelled, without deadline; i.e. context.Background()
+	}
+
+	var c context.Context
+	var cancel context.CancelFunc
+	if dl, ok := ctx.Deadline(); ok {
+		c, cancel = context.WithDeadline(fs.c, dl)
+	} else {
+		c, cancel = context.WithCancel(fs.c)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-c.Done():
+		}
+	}()
+
+	fsb := *fs
+	fsb.c = c
+	fsb.bound = ctx
+	return &fsb, cancel
+}
+
+func (fs *dsFileSys) OpenContext(ctx context.Context, name string) (fsi.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fsb, cancel := fs.bind(ctx)
+	defer cancel()
+	f, err := fsb.Open(name)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	rebind(f, fs) // the file outlives the call
+	return f, nil
+}
+
+func (fs *dsFileSys) LstatContext(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fsb, cancel := fs.bind(ctx)
+	defer cancel()
+	fi, err := fsb.Lstat(path)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return fi, err
+}
+
+func (fs *dsFileSys) ReadDirContext(ctx context.Context, name string) ([]os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fsb, cancel := fs.bind(ctx)
+	defer cancel()
+	fis, err := fsb.ReadDir(name)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return fis, err
+}
+
+// RemoveAllContext stops between two removals,
+// leaving the subtree partially removed.
+func (fs *dsFileSys) RemoveAllContext(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fsb, cancel := fs.bind(ctx)
+	defer cancel()
+	return fsb.removeAll(ctx, path)
+}
+
+// rebind points an opened file back to the unbound filesystem,
+// so that later Close() and Sync() do not use a cancelled context.
+func rebind(f fsi.File, fs *dsFileSys) {
+	switch ft := f.(type) {
+	case *DsFile:
+		ft.fSys = fs
+	case *DsDir:
+		ft.fSys = fs
+	}
+}
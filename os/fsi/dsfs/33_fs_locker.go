@@ -0,0 +1,156 @@
+package dsfs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/datastore"
+
+	aelog "google.golang.org/appengine/log"
+)
+
+// Locks are lease entities, one per locked path.
+// Each is a root entity - thus its own entity group -
+// and is changed only within transactions.
+//
+// A holder, that dies, blocks others only until its lease expires.
+// Therefore a lease is always applied.
+
+// DefaultLease applies, if TryLock gets a lease of zero.
+var DefaultLease = time.Minute
+
+// Upper case field names sadly
+// inevitable, for ae datastore :(
+// Holders and Expiries are parallel slices,
+// since datastore cannot store maps.
+type dsLease struct {
+	Exclusive bool
+	Holders   []string    `datastore:",noindex"`
+	Expiries  []time.Time `datastore:",noindex"`
+}
+
+type dsLock struct {
+	fSys   *dsFileSys
+	key    *datastore.Key
+	holder string
+}
+
+// prune drops expired holders.
+func (l *dsLease) prune(now time.Time) {
+	hs, es := l.Holders[:0], l.Expiries[:0]
+	for i := range l.Holders {
+		if now.Before(l.Expiries[i]) {
+			hs = append(hs, l.Holders[i])
+			es = append(es, l.Expiries[i])
+		}
+	}
+	l.Holders, l.Expiries = hs, es
+}
+
+func (l *dsLease) index(holder string) int {
+	for i := range l.Holders {
+		if l.Holders[i] == holder {
+			return i
+		}
+	}
+	return -1
+}
+
+func newHolderID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (fs *dsFileSys) TryLock(name string, mode fsi.LockMode, lease time.Duration) (fsi.Lock, error) {
+
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+
+	dir, bname := fs.SplitX(name)
+	key := datastore.NewKey(fs.Ctx(), tlck, dir+common.Filify(bname), 0, nil)
+	holder := newHolderID()
+
+	err := datastore.RunInTransaction(fs.Ctx(), func(tc context.Context) error {
+		l := dsLease{}
+		err := datastore.Get(tc, key, &l)
+		if err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		now := time.Now()
+		l.prune(now)
+		if len(l.Holders) > 0 && (l.Exclusive || mode == fsi.LockExclusive) {
+			return fsi.ErrLocked
+		}
+		l.Exclusive = mode == fsi.LockExclusive
+		l.Holders = append(l.Holders, holder)
+		l.Expiries = append(l.Expiries, now.Add(lease))
+		_, err = datastore.Put(tc, key, &l)
+		return err
+	}, nil)
+
+	if err == fsi.ErrLocked {
+		return nil, err
+	}
+	if err != nil {
+		aelog.Errorf(fs.Ctx(), "Error locking %v => %v", dir+bname, err)
+		return nil, err
+	}
+
+	return &dsLock{fSys: fs, key: key, holder: holder}, nil
+}
+
+func (dl *dsLock) Unlock() error {
+	return datastore.RunInTransaction(dl.fSys.Ctx(), func(tc context.Context) error {
+		l := dsLease{}
+		err := datastore.Get(tc, dl.key, &l)
+		if err == datastore.ErrNoSuchEntity {
+			return fsi.ErrLockExpired
+		}
+		if err != nil {
+			return err
+		}
+		l.prune(time.Now())
+		i := l.index(dl.holder)
+		if i < 0 {
+			return fsi.ErrLockExpired
+		}
+		l.Holders = append(l.Holders[:i], l.Holders[i+1:]...)
+		l.Expiries = append(l.Expiries[:i], l.Expiries[i+1:]...)
+		if len(l.Holders) == 0 {
+			return datastore.Delete(tc, dl.key)
+		}
+		_, err = datastore.Put(tc, dl.key, &l)
+		return err
+	}, nil)
+}
+
+func (dl *dsLock) Refresh(lease time.Duration) error {
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	return datastore.RunInTransaction(dl.fSys.Ctx(), func(tc context.Context) error {
+		l := dsLease{}
+		err := datastore.Get(tc, dl.key, &l)
+		if err == datastore.ErrNoSuchEntity {
+			return fsi.ErrLockExpired
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		l.prune(now)
+		i := l.index(dl.holder)
+		if i < 0 {
+			return fsi.ErrLockExpired
+		}
+		l.Expiries[i] = now.Add(lease)
+		_, err = datastore.Put(tc, dl.key, &l)
+		return err
+	}, nil)
+}
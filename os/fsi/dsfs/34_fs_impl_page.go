@@ -0,0 +1,89 @@
+package dsfs
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Datastore properties of the sort keys, which queries can order by.
+var queryOrders = map[string]string{
+	"name":   "BName",
+	"-name":  "-BName",
+	"mtime":  "ModTime",
+	"-mtime": "-ModTime",
+}
+
+// ReadDirPage implements common.DirPager.
+// The directories are few and small; they are read entirely.
+// The files are read up to the end of the page, ordered by the datastore;
+// with "dirs" as first key, the files before the page are skipped by the query.
+// Specs the datastore cannot order by - natural, size -
+// and an empty spec, which means the DirSort order, read the whole directory.
+// The ordered ancestor queries require the composite indexes of index.yaml;
+// while they are missing or still building, the whole directory is read.
+func (fs *dsFileSys) ReadDirPage(name string, spec string, offset, limit int) ([]os.FileInfo, error) {
+
+	keys, err := common.SortKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := common.ParseSort(spec)
+
+	dirsFirst := len(keys) > 0 && keys[0] == "dirs"
+	if dirsFirst {
+		keys = keys[1:]
+	}
+	order, ok := "", len(keys) == 1
+	if ok {
+		order, ok = queryOrders[keys[0]]
+	}
+	if spec == "" {
+		s = nil
+	}
+	if !ok || limit <= 0 {
+		return fs.readDirPageUnsorted(name, s, offset, limit)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	dirs, err := fs.dirsByPath(name)
+	if err != nil && err != fsi.EmptyQueryResult {
+		return nil, err
+	}
+
+	skip := 0 // files before the page, skipped by the query
+	if dirsFirst && offset > len(dirs) {
+		skip = offset - len(dirs)
+	}
+	files, err := fs.filesPage(name, order, skip, offset+limit-skip)
+	if err != nil && isMissingIndex(err) {
+		return fs.readDirPageUnsorted(name, s, offset, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fis := dirs
+	for i := range files {
+		fis = append(fis, os.FileInfo(&files[i]))
+	}
+	return common.Page(fis, s, offset-skip, limit), nil
+}
+
+// readDirPageUnsorted reads the whole directory and sorts in memory.
+func (fs *dsFileSys) readDirPageUnsorted(name string, s common.Sorter, offset, limit int) ([]os.FileInfo, error) {
+	fis, err := fs.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return common.Page(fis, s, offset, limit), nil
+}
+
+// isMissingIndex recognizes the datastore error for queries without composite index.
+func isMissingIndex(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "no matching index")
+}
@@ -82,6 +82,34 @@ func (fs *dsFileSys) filesByPath(name string) ([]DsFile, error) {
 	return files, err
 }
 
+// filesPage is filesByPath, ordered by a datastore property, and limited.
+func (fs *dsFileSys) filesPage(name, order string, offset, limit int) ([]DsFile, error) {
+
+	dir, bname := fs.SplitX(name)
+
+	var files []DsFile
+
+	foDir, err := fs.dirByPath(dir + common.Filify(bname))
+	if err != nil {
+		return files, err
+	}
+
+	// ties are broken by key, which is the base name, like common.Sorter does
+	q := datastore.NewQuery(tfil).Ancestor(foDir.Key).Order(order).Offset(offset).Limit(limit)
+	keys, err := q.GetAll(fs.Ctx(), &files)
+	if err != nil {
+		aelog.Errorf(fs.Ctx(), "Error fetching files page of %v => %v", foDir.Key, err)
+		return files, err
+	}
+
+	for i := 0; i < len(files); i++ {
+		files[i].Key = keys[i]
+		files[i].fSys = fs
+	}
+
+	return files, nil
+}
+
 //
 //
 // Path is the directory, BName contains the base name.
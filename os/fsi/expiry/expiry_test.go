@@ -0,0 +1,83 @@
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/common"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func TestSweep(t *testing.T) {
+
+	fs := memfs.New()
+
+	now := time.Now()
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"/news/a.html", 100, 50 * time.Hour},
+		{"/news/b.html", 100, 2 * time.Hour},
+		{"/news/c.html", 100, 1 * time.Hour},
+		{"/archive/d.html", 100, 90 * time.Hour},
+		{"/digest2.json.snappy", 100, 300 * time.Hour},
+	}
+	for _, f := range files {
+		err := common.WriteFile(fs, f.name, make([]byte, f.size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(-f.age)
+		err = fs.Chtimes(f.name, mod, mod)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := Policy{
+		MaxAge: 24 * time.Hour,
+		Rules: []Rule{
+			{Pattern: "*.snappy", Keep: true},
+			{Pattern: "/archive/", MaxAge: 0},
+		},
+		MaxTotalSize: 250,
+	}
+
+	rep, err := Sweep(fs, "/", p, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Scanned != len(files) {
+		t.Errorf("scanned %v files; want %v\n%v", rep.Scanned, len(files), rep)
+	}
+
+	// a.html by age; d.html and b.html are the oldest left, to get below 250 bytes
+	want := map[string]bool{"/news/a.html": true, "/archive/d.html": true, "/news/b.html": true}
+	if len(rep.Removed) != len(want) {
+		t.Fatalf("want %v removals; got\n%v", len(want), rep)
+	}
+	for _, c := range rep.Removed {
+		if !want[c.Path] {
+			t.Errorf("unexpected removal %v\n%v", c.Path, rep)
+		}
+	}
+
+	// dry run must leave the files in place
+	if _, err := fs.Stat("/news/a.html"); err != nil {
+		t.Errorf("dry run removed a file: %v", err)
+	}
+
+	_, err = Sweep(fs, "/", p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/news/a.html"); err == nil {
+		t.Errorf("a.html should be removed")
+	}
+	if _, err := fs.Stat("/news/c.html"); err != nil {
+		t.Errorf("c.html should remain: %v", err)
+	}
+
+}
@@ -0,0 +1,73 @@
+// Package expiry removes stale files from any fsi.FileSystem.
+//
+// A Policy combines a maximum age by mtime,
+// path-pattern rules overriding that age,
+// and a maximum total size, enforced by evicting the oldest files first.
+//
+// Sweep() walks a subtree once and applies the policy.
+// With dryRun set, it only reports, what would be removed.
+//
+// Sweeper runs Sweep() periodically for long living processes.
+// On appengine, there are no long living processes;
+// point a cron job to webapi's expiry handler instead.
+package expiry
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// Rule overrides the default max age for matching paths.
+//
+// Pattern is matched against the path relative to the sweep root.
+// A pattern ending in "/" is a prefix:          "/news/"       matches everything below /news.
+// A pattern without any "/" matches base names: "*.snappy"     matches all snappy files.
+// Any other pattern uses path.Match:            "/*/rss.xml"   matches rss.xml one level down.
+type Rule struct {
+	Pattern string
+	MaxAge  time.Duration // 0 means no expiry by age
+	Keep    bool          // exempt from age expiry *and* from size eviction
+}
+
+// Policy for one mount or subtree.
+type Policy struct {
+	MaxAge       time.Duration // applies to files without matching rule; 0 means no expiry by age
+	Rules        []Rule        // first matching rule wins
+	MaxTotalSize int64         // bytes; 0 means unlimited
+}
+
+// Matches checks whether the rule applies to relPath.
+func (ru Rule) Matches(relPath string) bool {
+	if ru.Pattern == "" {
+		return false
+	}
+	if strings.HasSuffix(ru.Pattern, "/") {
+		return strings.HasPrefix(relPath, ru.Pattern) || relPath+"/" == ru.Pattern
+	}
+	if !strings.Contains(ru.Pattern, "/") {
+		ok, _ := path.Match(ru.Pattern, path.Base(relPath))
+		return ok
+	}
+	ok, _ := path.Match(ru.Pattern, relPath)
+	return ok
+}
+
+// rule returns the first matching rule, or nil.
+func (p Policy) rule(relPath string) *Rule {
+	for i := 0; i < len(p.Rules); i++ {
+		if p.Rules[i].Matches(relPath) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// maxAge returns the effective max age for relPath,
+// and whether the file is exempt from eviction.
+func (p Policy) maxAge(relPath string) (time.Duration, bool) {
+	if ru := p.rule(relPath); ru != nil {
+		return ru.MaxAge, ru.Keep
+	}
+	return p.MaxAge, false
+}
@@ -0,0 +1,158 @@
+package expiry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Candidate is a file, that was removed - or would be removed on dry run.
+type Candidate struct {
+	Path   string
+	Size   int64
+	Mod    time.Time
+	Reason string
+}
+
+// Report summarizes one sweep.
+type Report struct {
+	Root      string
+	DryRun    bool
+	Started   time.Time
+	Scanned   int   // files visited; directories are not counted
+	TotalSize int64 // of all visited files, before removal
+	Removed   []Candidate
+	Errors    []error
+}
+
+// RemovedSize sums up the sizes of the removed candidates.
+func (r *Report) RemovedSize() int64 {
+	sum := int64(0)
+	for _, c := range r.Removed {
+		sum += c.Size
+	}
+	return sum
+}
+
+func (r *Report) String() string {
+	b := new(bytes.Buffer)
+	verb := "removed"
+	if r.DryRun {
+		verb = "would remove"
+	}
+	fmt.Fprintf(b, "sweep of %q at %v\n", r.Root, r.Started.Format(time.ANSIC))
+	fmt.Fprintf(b, "scanned %v files, %vkB\n", r.Scanned, r.TotalSize/1024)
+	fmt.Fprintf(b, "%v %v files, %vkB\n", verb, len(r.Removed), r.RemovedSize()/1024)
+	for _, c := range r.Removed {
+		fmt.Fprintf(b, "  %-60v %8vB  %v  %v\n", c.Path, c.Size, c.Mod.Format("2006-01-02 15:04"), c.Reason)
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(b, "  Err %v\n", err)
+	}
+	return b.String()
+}
+
+type file struct {
+	Candidate
+	keep bool
+}
+
+// Sweep walks root and removes all files expired under policy p.
+// If dryRun is true, nothing is removed; the report lists the candidates.
+//
+// Walk errors and removal errors do not abort the sweep;
+// they are collected in Report.Errors.
+// Directories are never removed, since dsfs requires explicit directories.
+func Sweep(fs fsi.FileSystem, root string, p Policy, dryRun bool) (*Report, error) {
+
+	rep := &Report{Root: root, DryRun: dryRun, Started: time.Now()}
+
+	files := []file{}
+	walkCollect := func(pth string, fi os.FileInfo, err error) error {
+		if err != nil {
+			rep.Errors = append(rep.Errors, fmt.Errorf("walking %v => %v", pth, err))
+			return nil // don't break the walk
+		}
+		if fi == nil || fi.IsDir() {
+			return nil
+		}
+		f := file{}
+		f.Path = pth
+		f.Size = fi.Size()
+		f.Mod = fi.ModTime()
+		files = append(files, f)
+		rep.Scanned++
+		rep.TotalSize += fi.Size()
+		return nil
+	}
+
+	err := common.Walk(fs, root, walkCollect)
+	if err != nil {
+		return rep, err
+	}
+
+	// expiry by age
+	remaining := make([]file, 0, len(files))
+	for _, f := range files {
+		maxAge, keep := p.maxAge(relative(root, f.Path))
+		f.keep = keep
+		age := rep.Started.Sub(f.Mod)
+		if !keep && maxAge > 0 && age > maxAge {
+			f.Reason = fmt.Sprintf("age %v exceeds %v", age.Truncate(time.Minute), maxAge)
+			rep.Removed = append(rep.Removed, f.Candidate)
+			continue
+		}
+		remaining = append(remaining, f)
+	}
+
+	// eviction by size - oldest first
+	if p.MaxTotalSize > 0 {
+		total := int64(0)
+		for _, f := range remaining {
+			total += f.Size
+		}
+		sort.Sort(byModAsc(remaining))
+		for _, f := range remaining {
+			if total <= p.MaxTotalSize {
+				break
+			}
+			if f.keep {
+				continue
+			}
+			f.Reason = fmt.Sprintf("total size %vkB exceeds %vkB", total/1024, p.MaxTotalSize/1024)
+			rep.Removed = append(rep.Removed, f.Candidate)
+			total -= f.Size
+		}
+	}
+
+	if dryRun {
+		return rep, nil
+	}
+
+	for _, c := range rep.Removed {
+		err := fs.Remove(c.Path)
+		if err != nil {
+			rep.Errors = append(rep.Errors, fmt.Errorf("removing %v => %v", c.Path, err))
+		}
+	}
+
+	return rep, nil
+}
+
+// relative returns pth relative to root; always with leading slash.
+func relative(root, pth string) string {
+	rel := strings.TrimPrefix(pth, root)
+	return "/" + strings.TrimPrefix(rel, "/")
+}
+
+type byModAsc []file
+
+func (f byModAsc) Len() int           { return len(f) }
+func (f byModAsc) Less(i, j int) bool { return f[i].Mod.Before(f[j].Mod) }
+func (f byModAsc) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
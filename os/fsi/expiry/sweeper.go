@@ -0,0 +1,55 @@
+package expiry
+
+import (
+	"log"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Sweeper runs Sweep periodically.
+// Use it with osfs or memfs in long living processes.
+type Sweeper struct {
+	FS       fsi.FileSystem
+	Root     string
+	Policy   Policy
+	Interval time.Duration
+	DryRun   bool
+
+	// OnSweep receives each report; defaults to logging its summary.
+	OnSweep func(*Report)
+}
+
+// Run sweeps once immediately, then every s.Interval,
+// until stop is closed.
+func (s *Sweeper) Run(stop <-chan struct{}) {
+
+	if s.Interval <= 0 {
+		s.Interval = time.Hour
+	}
+	onSweep := s.OnSweep
+	if onSweep == nil {
+		onSweep = func(rep *Report) {
+			log.Printf("expiry sweep of %v: %v of %v files removed, %v errors",
+				rep.Root, len(rep.Removed), rep.Scanned, len(rep.Errors))
+		}
+	}
+
+	tick := time.NewTicker(s.Interval)
+	defer tick.Stop()
+
+	for {
+		rep, err := Sweep(s.FS, s.Root, s.Policy, s.DryRun)
+		if err != nil {
+			log.Printf("expiry sweep of %v failed: %v", s.Root, err)
+		} else {
+			onSweep(rep)
+		}
+
+		select {
+		case <-tick.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -2,11 +2,11 @@ package memfs
 
 import (
 	"os"
-	"sort"
 	"sync"
 	"time"
 
 	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
 )
 
 // The main type is unexported.
@@ -25,6 +25,11 @@ type memMapFs struct {
 	ident         string
 	readdirsorter func([]os.FileInfo)
 	shadow        fsi.FileSystem
+
+	// advisory locks by full path; see 33_fs_locker.go
+	// separate mutex; fos locking is deadlock prone enough
+	lockMtx *sync.Mutex
+	locks   map[string]*lockEntry
 }
 
 // Ident is an option func, adding a specific identification to the filesystem
@@ -45,21 +50,14 @@ func ShadowFS(behindFS fsi.FileSystem) func(fsi.FileSystem) {
 	}
 }
 
-// Default sort for ReadDir... is ByNameAsc
-// We may want to change this; for instance sort byDate
-func DirSort(srt string) func(fsi.FileSystem) {
+// DirSort is an option func, setting the order of ReadDir and Readdir.
+// Default is by name. Keys are combined, i.e.
+//
+//	DirSort(common.DirsFirst, common.Desc(common.ByModTime))
+func DirSort(keys ...common.Less) func(fsi.FileSystem) {
 	return func(fs fsi.FileSystem) {
 		fst := fs.(*memMapFs)
-
-		switch srt {
-		case "byDateAsc":
-			fst.readdirsorter = func(fis []os.FileInfo) { sort.Sort(byDateAsc(fis)) }
-
-		case "byDateDesc":
-			fst.readdirsorter = func(fis []os.FileInfo) { sort.Sort(byDateDesc(fis)) }
-		case "byName":
-			fst.readdirsorter = func(fis []os.FileInfo) { sort.Sort(byName(fis)) }
-		}
+		fst.readdirsorter = common.SortBy(keys...).Sort
 	}
 }
 
@@ -71,7 +69,9 @@ func New(options ...func(fsi.FileSystem)) *memMapFs {
 	m := &memMapFs{
 		fos:           map[string]fsi.File{}, // secure init
 		ident:         "mnt00",
-		readdirsorter: func(fis []os.FileInfo) { sort.Sort(byName(fis)) },
+		readdirsorter: common.SortBy(common.ByName).Sort,
+		lockMtx:       &sync.Mutex{},
+		locks:         map[string]*lockEntry{},
 	}
 	for _, option := range options {
 		option(m)
@@ -296,9 +296,10 @@ func (m *memMapFs) Remove(name string) error {
 	name = dir + bname // not join, since it removes trailing slash
 
 	m.rlock()
-	defer m.runlock()
+	_, ok := m.fos[name]
+	m.runlock() // released before lock(); RWMutex is not upgradeable
 
-	if _, ok := m.fos["name"]; ok {
+	if ok {
 		m.lock()
 		delete(m.fos, name)
 		m.unlock()
@@ -0,0 +1,39 @@
+package memfs
+
+import (
+	"os"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+)
+
+// memfs operations never block on I/O.
+// Checking the context before each call is all we can do.
+
+func (m *memMapFs) OpenContext(ctx context.Context, name string) (fsi.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Open(name)
+}
+
+func (m *memMapFs) LstatContext(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Lstat(path)
+}
+
+func (m *memMapFs) ReadDirContext(ctx context.Context, name string) ([]os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.ReadDir(name)
+}
+
+// RemoveAllContext removes entry by entry,
+// so that it can be interrupted halfway.
+func (m *memMapFs) RemoveAllContext(ctx context.Context, name string) error {
+	return common.RemoveAllByWalk(ctx, m, name)
+}
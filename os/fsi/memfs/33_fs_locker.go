@@ -0,0 +1,101 @@
+package memfs
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Locks are in-process only,
+// just as the memfs itself.
+
+type lockEntry struct {
+	exclusive bool
+	holders   map[int64]time.Time // holder id => lease expiry; zero time means no expiry
+}
+
+type memLock struct {
+	fs   *memMapFs
+	name string
+	id   int64
+}
+
+var lockIDs int64
+
+// prune removes expired holders. lockMtx must be held.
+func (e *lockEntry) prune(now time.Time) {
+	for id, exp := range e.holders {
+		if !exp.IsZero() && now.After(exp) {
+			delete(e.holders, id)
+		}
+	}
+}
+
+func expiry(now time.Time, lease time.Duration) time.Time {
+	if lease <= 0 {
+		return time.Time{}
+	}
+	return now.Add(lease)
+}
+
+func (m *memMapFs) TryLock(name string, mode fsi.LockMode, lease time.Duration) (fsi.Lock, error) {
+
+	dir, bname := m.SplitX(name)
+	name = dir + bname
+
+	m.lockMtx.Lock()
+	defer m.lockMtx.Unlock()
+
+	now := time.Now()
+	e, ok := m.locks[name]
+	if !ok {
+		e = &lockEntry{holders: map[int64]time.Time{}}
+		m.locks[name] = e
+	}
+	e.prune(now)
+
+	if len(e.holders) > 0 && (e.exclusive || mode == fsi.LockExclusive) {
+		return nil, fsi.ErrLocked
+	}
+
+	id := atomic.AddInt64(&lockIDs, 1)
+	e.exclusive = mode == fsi.LockExclusive
+	e.holders[id] = expiry(now, lease)
+
+	return &memLock{fs: m, name: name, id: id}, nil
+}
+
+func (l *memLock) Unlock() error {
+	l.fs.lockMtx.Lock()
+	defer l.fs.lockMtx.Unlock()
+	e, ok := l.fs.locks[l.name]
+	if !ok {
+		return fsi.ErrLockExpired
+	}
+	e.prune(time.Now())
+	if _, ok := e.holders[l.id]; !ok {
+		return fsi.ErrLockExpired
+	}
+	delete(e.holders, l.id)
+	if len(e.holders) == 0 {
+		delete(l.fs.locks, l.name)
+	}
+	return nil
+}
+
+func (l *memLock) Refresh(lease time.Duration) error {
+	l.fs.lockMtx.Lock()
+	defer l.fs.lockMtx.Unlock()
+	now := time.Now()
+	e, ok := l.fs.locks[l.name]
+	if !ok {
+		return fsi.ErrLockExpired
+	}
+	e.prune(now)
+	if _, ok := e.holders[l.id]; !ok {
+		return fsi.ErrLockExpired
+	}
+	e.holders[l.id] = expiry(now, lease)
+	return nil
+}
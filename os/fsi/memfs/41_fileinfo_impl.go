@@ -17,22 +17,3 @@ func (s *InMemoryFileInfo) Size() int64 {
 	}
 	return int64(len(s.file.data))
 }
-
-// byName implements sort.Interface.
-type byName []os.FileInfo
-
-func (f byName) Len() int           { return len(f) }
-func (f byName) Less(i, j int) bool { return f[i].Name() < f[j].Name() }
-func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
-
-type byDateAsc []os.FileInfo
-
-func (f byDateAsc) Len() int           { return len(f) }
-func (f byDateAsc) Less(i, j int) bool { return f[i].ModTime().Before(f[j].ModTime()) }
-func (f byDateAsc) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
-
-type byDateDesc []os.FileInfo
-
-func (f byDateDesc) Len() int           { return len(f) }
-func (f byDateDesc) Less(i, j int) bool { return f[i].ModTime().After(f[j].ModTime()) }
-func (f byDateDesc) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
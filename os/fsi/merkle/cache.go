@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// CacheEntry is valid, as long as size and mtime of the file are unchanged.
+type CacheEntry struct {
+	Size int64
+	Mod  time.Time
+	Sum  string
+}
+
+// Cache holds file hashes keyed by path.
+// Use one cache per filesystem.
+// A nil *Cache is valid and caches nothing.
+type Cache struct {
+	sync.Mutex
+	Entries map[string]CacheEntry
+}
+
+func NewCache() *Cache {
+	return &Cache{Entries: map[string]CacheEntry{}}
+}
+
+func (c *Cache) lookup(pth string, size int64, mod time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.Entries[pth]
+	if !ok || e.Size != size || !e.Mod.Equal(mod) {
+		return "", false
+	}
+	return e.Sum, true
+}
+
+func (c *Cache) store(pth string, size int64, mod time.Time, sum string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.Entries[pth] = CacheEntry{Size: size, Mod: mod, Sum: sum}
+}
+
+// LoadCache reads a cache saved by Save.
+// A missing file yields an empty cache, and the error.
+func LoadCache(fs fsi.FileSystem, fn string) (*Cache, error) {
+	c := NewCache()
+	b, err := fs.ReadFile(fn)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c.Entries)
+	if c.Entries == nil {
+		c.Entries = map[string]CacheEntry{}
+	}
+	return c, err
+}
+
+// Save stores the cache as JSON.
+func (c *Cache) Save(fs fsi.FileSystem, fn string) error {
+	c.Lock()
+	b, err := json.Marshal(c.Entries)
+	c.Unlock()
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(fs, fn, b)
+}
@@ -0,0 +1,107 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Delta lists the differences from tree a to tree b.
+// Paths are relative to the roots, with leading slash.
+// Directories only appear, if they are empty;
+// otherwise their files are listed.
+type Delta struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Equal is true, if the trees hold the same content.
+func (d *Delta) Equal() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d *Delta) String() string {
+	b := new(bytes.Buffer)
+	for _, p := range d.Added {
+		fmt.Fprintf(b, "+ %v\n", p)
+	}
+	for _, p := range d.Removed {
+		fmt.Fprintf(b, "- %v\n", p)
+	}
+	for _, p := range d.Changed {
+		fmt.Fprintf(b, "~ %v\n", p)
+	}
+	return b.String()
+}
+
+// Diff compares two trees, i.e. two snapshots.
+// Subtrees with equal hashes are skipped.
+func Diff(a, b *Node) *Delta {
+	d := &Delta{}
+	d.diff("/", a, b)
+	return d
+}
+
+// Compare builds the trees of two filesystems and diffs them.
+func Compare(fsA fsi.FileSystem, rootA string, fsB fsi.FileSystem, rootB string, cacheA, cacheB *Cache) (*Delta, error) {
+	a, err := Build(fsA, rootA, cacheA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Build(fsB, rootB, cacheB)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(a, b), nil
+}
+
+func (d *Delta) diff(pth string, a, b *Node) {
+
+	if a.Sum == b.Sum && a.Dir == b.Dir {
+		return
+	}
+
+	if !a.Dir && !b.Dir {
+		d.Changed = append(d.Changed, pth)
+		return
+	}
+	if a.Dir != b.Dir {
+		d.Removed = appendLeaves(d.Removed, pth, a)
+		d.Added = appendLeaves(d.Added, pth, b)
+		return
+	}
+
+	// both directories; kids are sorted by name
+	i, j := 0, 0
+	for i < len(a.Kids) || j < len(b.Kids) {
+		switch {
+		case j >= len(b.Kids) || i < len(a.Kids) && a.Kids[i].Name < b.Kids[j].Name:
+			d.Removed = appendLeaves(d.Removed, path.Join(pth, a.Kids[i].Name), a.Kids[i])
+			i++
+		case i >= len(a.Kids) || a.Kids[i].Name > b.Kids[j].Name:
+			d.Added = appendLeaves(d.Added, path.Join(pth, b.Kids[j].Name), b.Kids[j])
+			j++
+		default:
+			d.diff(path.Join(pth, a.Kids[i].Name), a.Kids[i], b.Kids[j])
+			i++
+			j++
+		}
+	}
+}
+
+// appendLeaves appends all files below n.
+func appendLeaves(list []string, pth string, n *Node) []string {
+	if !n.Dir {
+		return append(list, pth)
+	}
+	if len(n.Kids) == 0 {
+		return append(list, pth+"/")
+	}
+	for _, kid := range n.Kids {
+		list = appendLeaves(list, path.Join(pth, kid.Name), kid)
+	}
+	return list
+}
@@ -0,0 +1,124 @@
+// Package merkle computes content hashes of files
+// and a Merkle tree of directories over any fsi.FileSystem.
+//
+// Two trees - from two filesystems, or from two snapshots
+// of the same filesystem - can then be compared by Diff(),
+// descending only into directories with differing hashes.
+//
+// Reading every file is expensive on dsfs.
+// A Cache keeps file hashes keyed by path, size and mtime;
+// unchanged files are not read again.
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+// Node is a file or directory of the tree.
+// For files, Sum is the hash of the content.
+// For directories, Sum is the hash over the names, types and sums of all children.
+type Node struct {
+	Name string    // base name; "" for the root
+	Dir  bool      `json:",omitempty"`
+	Size int64     `json:",omitempty"`
+	Mod  time.Time `json:",omitempty"`
+	Sum  string
+	Kids []*Node `json:",omitempty"` // sorted by name
+}
+
+// Build computes the tree below root.
+// The cache may be nil.
+func Build(fs fsi.FileSystem, root string, cache *Cache) (*Node, error) {
+	fi, err := fs.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	n, err := build(fs, root, fi, cache)
+	if err != nil {
+		return nil, err
+	}
+	n.Name = ""
+	return n, nil
+}
+
+func build(fs fsi.FileSystem, pth string, fi os.FileInfo, cache *Cache) (*Node, error) {
+
+	n := &Node{Name: fi.Name(), Dir: fi.IsDir(), Mod: fi.ModTime()}
+
+	if !fi.IsDir() {
+		n.Size = fi.Size()
+		if sum, ok := cache.lookup(pth, n.Size, n.Mod); ok {
+			n.Sum = sum
+			return n, nil
+		}
+		bts, err := fs.ReadFile(pth)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %v => %v", pth, err)
+		}
+		h := sha256.Sum256(bts)
+		n.Sum = hex.EncodeToString(h[:])
+		cache.store(pth, n.Size, n.Mod, n.Sum)
+		return n, nil
+	}
+
+	fis, err := fs.ReadDir(pth)
+	if err != nil && err != fsi.EmptyQueryResult {
+		return nil, fmt.Errorf("reading dir %v => %v", pth, err)
+	}
+	for _, fiKid := range fis {
+		kid, err := build(fs, path.Join(pth, common.Filify(fiKid.Name())), fiKid, cache)
+		if err != nil {
+			return nil, err
+		}
+		n.Kids = append(n.Kids, kid)
+	}
+	sort.Sort(byName(n.Kids))
+
+	h := sha256.New()
+	for _, kid := range n.Kids {
+		tp := "f"
+		if kid.Dir {
+			tp = "d"
+		}
+		fmt.Fprintf(h, "%v %v %v\n", tp, kid.Sum, kid.Name)
+	}
+	n.Sum = hex.EncodeToString(h.Sum(nil))
+
+	return n, nil
+}
+
+// SaveSnapshot stores the tree as JSON, for later comparison.
+func SaveSnapshot(fs fsi.FileSystem, fn string, n *Node) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(fs, fn, b)
+}
+
+// LoadSnapshot reads a tree stored by SaveSnapshot.
+func LoadSnapshot(fs fsi.FileSystem, fn string) (*Node, error) {
+	b, err := fs.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{}
+	err = json.Unmarshal(b, n)
+	return n, err
+}
+
+type byName []*Node
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Less(i, j int) bool { return f[i].Name < f[j].Name }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
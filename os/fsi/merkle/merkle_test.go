@@ -0,0 +1,80 @@
+package merkle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"github.com/pbberlin/tools/os/fsi/memfs"
+)
+
+func fill(t *testing.T, fs fsi.FileSystem, files map[string]string) {
+	for name, content := range files {
+		err := common.WriteFile(fs, name, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+
+	fsA := memfs.New(memfs.Ident("mntA"))
+	fsB := memfs.New(memfs.Ident("mntB"))
+
+	fill(t, fsA, map[string]string{
+		"/news/a.html":    "aaa",
+		"/news/b.html":    "bbb",
+		"/archive/c.html": "ccc",
+		"/old/d.html":     "ddd",
+	})
+	fill(t, fsB, map[string]string{
+		"/news/a.html":    "aaa",
+		"/news/b.html":    "BBB",
+		"/archive/c.html": "ccc",
+		"/new/e.html":     "eee",
+	})
+
+	cache := NewCache()
+	d, err := Compare(fsA, "/", fsB, "/", cache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Delta{
+		Added:   []string{"/new/e.html"},
+		Removed: []string{"/old/d.html"},
+		Changed: []string{"/news/b.html"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("got\n%vwant\n%v", d, want)
+	}
+
+	// identical trees
+	a1, err := Build(fsA, "/", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := Build(fsA, "/", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(a1, a2); !d.Equal() {
+		t.Errorf("equal trees yield diff\n%v", d)
+	}
+
+	// snapshot round trip
+	err = SaveSnapshot(fsB, "/snap.json", a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a3, err := LoadSnapshot(fsB, "/snap.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a3.Sum != a1.Sum {
+		t.Errorf("snapshot changed the root hash %v => %v", a1.Sum, a3.Sum)
+	}
+
+}
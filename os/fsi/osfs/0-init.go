@@ -25,4 +25,10 @@ func init() {
 	ifs := fsi.FileSystem(&fs)
 	_ = ifs
 
+	ifsc := fsi.FileSystemContext(&fs)
+	_ = ifsc
+
+	ilck := fsi.Locker(&fs)
+	_ = ilck
+
 }
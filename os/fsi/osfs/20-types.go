@@ -3,14 +3,15 @@ package osfs
 import (
 	"os"
 	"runtime"
-	"sort"
 
 	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
 )
 
 type osFileSys struct {
 	replacePath   bool
 	readdirsorter func([]os.FileInfo)
+	lockDir       string // sidecar files of TryLock
 }
 
 func New(options ...func(fsi.FileSystem)) *osFileSys {
@@ -31,19 +32,21 @@ func New(options ...func(fsi.FileSystem)) *osFileSys {
 	return o
 }
 
-// Default sort for ReadDir... is ByNameAsc
-// We may want to change this; for instance sort byDate
-func DirSort(srt string) func(fsi.FileSystem) {
+// DirSort is an option func, overriding the order of package os.
+// Readdir(n) only sorts each chunk of n.
+func DirSort(keys ...common.Less) func(fsi.FileSystem) {
 	return func(fs fsi.FileSystem) {
 		fst := fs.(*osFileSys)
+		fst.readdirsorter = common.SortBy(keys...).Sort
+	}
+}
 
-		switch srt {
-		case "byDateAsc":
-			fst.readdirsorter = func(fis []os.FileInfo) { sort.Sort(byDateAsc(fis)) }
-		case "byDateDesc":
-			fst.readdirsorter = func(fis []os.FileInfo) { sort.Sort(byDateDesc(fis)) }
-		case "byName":
-			fst.readdirsorter = func(fis []os.FileInfo) {} // unchanged
-		}
+// LockDir is an option func, setting the directory for lock files.
+// Processes sharing files must share it.
+// Default is fsi-locks in the temp dir.
+func LockDir(dir string) func(fsi.FileSystem) {
+	return func(fs fsi.FileSystem) {
+		fst := fs.(*osFileSys)
+		fst.lockDir = dir
 	}
 }
@@ -0,0 +1,39 @@
+package osfs
+
+import (
+	"os"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+)
+
+// Syscalls cannot be interrupted.
+// We check the context before each of them.
+
+func (fs *osFileSys) OpenContext(ctx context.Context, name string) (fsi.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.Open(name)
+}
+
+func (fs *osFileSys) LstatContext(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.Lstat(path)
+}
+
+func (fs *osFileSys) ReadDirContext(ctx context.Context, dirname string) ([]os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(dirname)
+}
+
+// RemoveAllContext does not use os.RemoveAll,
+// which cannot be interrupted.
+func (fs *osFileSys) RemoveAllContext(ctx context.Context, path string) error {
+	return common.RemoveAllByWalk(ctx, fs, path)
+}
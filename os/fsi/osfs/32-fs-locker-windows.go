@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package osfs
+
+import (
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// No flock under windows; LockFileEx is not wrapped yet.
+func (fs *osFileSys) TryLock(name string, mode fsi.LockMode, lease time.Duration) (fsi.Lock, error) {
+	return nil, fsi.NotImplemented
+}
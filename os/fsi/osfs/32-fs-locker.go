@@ -0,0 +1,140 @@
+//go:build !windows
+// +build !windows
+
+package osfs
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+)
+
+// Locks are taken with flock(2) on a sidecar file in the lock directory,
+// named by the sha1 of the absolute file name.
+// Locking the file itself would require creating it,
+// and readers would take an empty file for a fresh one.
+// Keeping sidecars out of the data tree spares listings, walks and digests;
+// and the locked file's directory need not exist yet.
+// The sidecar is never removed; removing it would race
+// with processes waiting on the old inode.
+//
+// flock has no expiry. A lease > 0 is enforced by a timer,
+// which releases the lock. A dead process releases its locks anyway.
+
+const lockSuffix = ".lock"
+
+// sidecar returns the lock file for name.
+func (fs *osFileSys) sidecar(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	dir := fs.lockDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "fsi-locks")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%x", sha1.Sum([]byte(abs)))+lockSuffix), nil
+}
+
+type flockLock struct {
+	mu    sync.Mutex
+	f     *os.File
+	timer *time.Timer
+	gen   int // counts arm() calls
+}
+
+func (fs *osFileSys) TryLock(name string, mode fsi.LockMode, lease time.Duration) (fsi.Lock, error) {
+
+	name = fs.WinGoofify(name)
+
+	fn, err := fs.sidecar(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_SH
+	if mode == fsi.LockExclusive {
+		how = syscall.LOCK_EX
+	}
+	err = syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		f.Close()
+		return nil, fsi.ErrLocked
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &flockLock{f: f}
+	l.mu.Lock()
+	l.arm(lease)
+	l.mu.Unlock()
+	return l, nil
+}
+
+// arm (re)starts the lease timer. l.mu must be held.
+func (l *flockLock) arm(lease time.Duration) {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.gen++
+	if lease > 0 {
+		gen := l.gen
+		l.timer = time.AfterFunc(lease, func() { l.expire(gen) })
+	}
+}
+
+// expire is called by the timer of generation gen.
+// A refreshed lock has a newer generation; then gen is stale.
+func (l *flockLock) expire(gen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.gen == gen {
+		l.release()
+	}
+}
+
+func (l *flockLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.release()
+}
+
+// release closes the descriptor. l.mu must be held.
+func (l *flockLock) release() error {
+	if l.f == nil {
+		return fsi.ErrLockExpired
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	// closing the descriptor releases the flock
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+func (l *flockLock) Refresh(lease time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return fsi.ErrLockExpired
+	}
+	l.arm(lease)
+	return nil
+}
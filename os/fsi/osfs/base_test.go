@@ -1,8 +1,13 @@
 package osfs
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
 )
 
 func TestOsFileSys(t *testing.T) {
@@ -40,3 +45,27 @@ func TestOsFileSys(t *testing.T) {
 	}
 
 }
+
+func TestLockOutsideTree(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "osfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := New(LockDir(filepath.Join(dir, "locks")))
+	fn := filepath.Join(dir, "data", "new", "article.html") // dir does not exist yet
+	l, err := fs.TryLock(fn, fsi.LockExclusive, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.TryLock(fn, fsi.LockExclusive, time.Minute); err != fsi.ErrLocked {
+		t.Errorf("want %v; got %v", fsi.ErrLocked, err)
+	}
+	l.Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+		t.Errorf("locking created files in the data tree: %v", err)
+	}
+}
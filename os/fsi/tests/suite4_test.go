@@ -0,0 +1,63 @@
+// +build suite4
+// go test -tags=suite4
+
+package tests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+)
+
+func TestWalkContext(t *testing.T) {
+
+	Fss, c := initFileSystems()
+	defer c.Close()
+
+	for _, fs := range Fss {
+
+		bb, msg := CreateSys(fs)
+		if msg != "" {
+			t.Fatalf("%v %v\n%v", fs.Name(), msg, bb.String())
+		}
+
+		// cancelled after the third visit
+		ctx, cancel := context.WithCancel(context.Background())
+		visits := 0
+		walkFn := func(path string, f os.FileInfo, err error) error {
+			visits++
+			if visits == 3 {
+				cancel()
+			}
+			return nil
+		}
+		err := common.WalkContext(ctx, fs, rel, walkFn)
+		if err != context.Canceled {
+			t.Errorf("%v: walk should return %v; got %v", fs.Name(), context.Canceled, err)
+		}
+		if visits != 3 {
+			t.Errorf("%v: walk should stop after 3 visits; got %v", fs.Name(), visits)
+		}
+
+		// expired deadline
+		ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		_, err = common.ReadDirContext(ctx, fs, rel)
+		if err != context.DeadlineExceeded {
+			t.Errorf("%v: readdir should return %v; got %v", fs.Name(), context.DeadlineExceeded, err)
+		}
+		err = common.RemoveAllContext(ctx, fs, "ch1")
+		if err != context.DeadlineExceeded {
+			t.Errorf("%v: removeall should return %v; got %v", fs.Name(), context.DeadlineExceeded, err)
+		}
+		if _, err := fs.Stat("ch1"); err != nil {
+			t.Errorf("%v: ch1 should survive a cancelled removal: %v", fs.Name(), err)
+		}
+		cancel()
+
+	}
+
+}
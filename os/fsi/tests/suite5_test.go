@@ -0,0 +1,84 @@
+// +build suite5
+// go test -tags=suite5
+
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+	"golang.org/x/net/context"
+)
+
+func TestLocker(t *testing.T) {
+
+	Fss, c := initFileSystems()
+	defer c.Close()
+
+	for _, fs := range Fss {
+
+		lckr, ok := fs.(fsi.Locker)
+		if !ok {
+			t.Errorf("%v is no locker", fs.Name())
+			continue
+		}
+
+		fn := rel + "/lockme.txt"
+
+		// exclusive excludes everyone
+		l1, err := lckr.TryLock(fn, fsi.LockExclusive, time.Minute)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		if _, err := lckr.TryLock(fn, fsi.LockShared, time.Minute); err != fsi.ErrLocked {
+			t.Errorf("%v: want %v; got %v", fs.Name(), fsi.ErrLocked, err)
+		}
+
+		// blocking variant gives up with the context
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err = common.Lock(ctx, fs, fn, fsi.LockExclusive, time.Minute)
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Errorf("%v: want %v; got %v", fs.Name(), context.DeadlineExceeded, err)
+		}
+
+		if err := l1.Unlock(); err != nil {
+			t.Errorf("%v: %v", fs.Name(), err)
+		}
+
+		// shared locks are compatible
+		s1, err := lckr.TryLock(fn, fsi.LockShared, time.Minute)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		s2, err := lckr.TryLock(fn, fsi.LockShared, time.Minute)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		if _, err := lckr.TryLock(fn, fsi.LockExclusive, time.Minute); err != fsi.ErrLocked {
+			t.Errorf("%v: want %v; got %v", fs.Name(), fsi.ErrLocked, err)
+		}
+		s1.Unlock()
+		s2.Unlock()
+
+		// an expired lease frees the lock
+		l2, err := lckr.TryLock(fn, fsi.LockExclusive, 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		l3, err := lckr.TryLock(fn, fsi.LockExclusive, time.Minute)
+		if err != nil {
+			t.Errorf("%v: lease did not expire: %v", fs.Name(), err)
+		} else {
+			l3.Unlock()
+		}
+		if err := l2.Refresh(time.Minute); err != fsi.ErrLockExpired {
+			t.Errorf("%v: want %v; got %v", fs.Name(), fsi.ErrLockExpired, err)
+		}
+
+	}
+
+}
@@ -0,0 +1,105 @@
+// +build suite6
+// go test -tags=suite6
+
+package tests
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbberlin/tools/os/fsi"
+	"github.com/pbberlin/tools/os/fsi/common"
+)
+
+func names(fis []os.FileInfo) []string {
+	ret := []string{}
+	for _, fi := range fis {
+		ret = append(ret, strings.TrimSuffix(fi.Name(), "/")) // memfs dirs have trailing slash
+	}
+	return ret
+}
+
+func TestReadDirPage(t *testing.T) {
+
+	Fss, c := initFileSystems()
+	defer c.Close()
+
+	for _, fs := range Fss {
+
+		err := fs.MkdirAll(relOpt+"srt/sub", os.ModePerm)
+		if err != nil && err != fsi.ErrFileExists {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		files := map[string]string{
+			"srt/p10.txt": "123",
+			"srt/p2.txt":  "1234567890",
+			"srt/p1.txt":  "1",
+		}
+		for name, content := range files {
+			err := fs.WriteFile(relOpt+name, []byte(content), os.ModePerm)
+			if err != nil {
+				t.Fatalf("%v: %v", fs.Name(), err)
+			}
+		}
+
+		fis, err := common.ReadDirPage(fs, relOpt+"srt", "dirs,natural", 0, 0)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		want := []string{"sub", "p1.txt", "p2.txt", "p10.txt"}
+		if got := names(fis); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: natural order\ngot  %v\nwant %v", fs.Name(), got, want)
+		}
+
+		// second page
+		fis, err = common.ReadDirPage(fs, relOpt+"srt", "dirs,natural", 1, 2)
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		want = []string{"p1.txt", "p2.txt"}
+		if got := names(fis); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: page\ngot  %v\nwant %v", fs.Name(), got, want)
+		}
+
+		// keys dsfs orders its queries by
+		pages := []struct {
+			spec          string
+			offset, limit int
+			want          []string
+		}{
+			{"dirs,name", 2, 2, []string{"p10.txt", "p2.txt"}},
+			{"dirs,-name", 0, 2, []string{"sub", "p2.txt"}},
+			{"name", 1, 2, []string{"p10.txt", "p2.txt"}},
+		}
+		for _, p := range pages {
+			fis, err = common.ReadDirPage(fs, relOpt+"srt", p.spec, p.offset, p.limit)
+			if err != nil {
+				t.Fatalf("%v: %v", fs.Name(), err)
+			}
+			if got := names(fis); !reflect.DeepEqual(got, p.want) {
+				t.Errorf("%v: %v page %v,%v\ngot  %v\nwant %v", fs.Name(), p.spec, p.offset, p.limit, got, p.want)
+			}
+		}
+
+		// combined keys, custom comparator
+		noDirs := func(a, b os.FileInfo) bool { return !a.IsDir() && b.IsDir() }
+		s := common.SortBy(noDirs, common.Desc(common.BySize))
+		fis, err = fs.ReadDir(relOpt + "srt")
+		if err != nil {
+			t.Fatalf("%v: %v", fs.Name(), err)
+		}
+		fis = common.Page(fis, s, 0, 3)
+		want = []string{"p2.txt", "p10.txt", "p1.txt"}
+		if got := names(fis); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: by size\ngot  %v\nwant %v", fs.Name(), got, want)
+		}
+
+	}
+
+	if _, err := common.ParseSort("name,bogus"); err == nil {
+		t.Errorf("unknown key should fail")
+	}
+
+}
@@ -0,0 +1,122 @@
+package webapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbberlin/tools/net/http/loghttp"
+	"github.com/pbberlin/tools/net/http/tplx"
+	"github.com/pbberlin/tools/os/fsi/dsfs"
+	"github.com/pbberlin/tools/os/fsi/expiry"
+	"google.golang.org/appengine"
+
+	tt "html/template"
+)
+
+const UriExpire = "/fsi/expire"
+
+var strExpire = `
+	<style>
+		span.b {
+			display:inline-block;
+			width: 150px;
+			align:middle;
+		}
+	</style>
+	<form
+		action='{{.Url}}'
+		method='post'
+		style='line-height:32px;padding:8px;'
+	>
+
+		<span class='b' >MountName </span>
+		<input name='mountname' type='text' value='mntftch' length=5 /><br>
+
+		<span class='b' >Path Prefix </span>
+		<input name='pathprefix' type='text' value='/' length=25 /><br>
+
+		<span class='b' >Max age hours </span>
+		<input name='maxage' type='text' value='240' length=5 /> 0 disables<br>
+
+		<span class='b' >Max total kB </span>
+		<input name='maxsize' type='text' value='0' length=8 /> 0 disables; oldest files are evicted first<br>
+
+		<span class='b' >Keep patterns </span>
+		<input name='keep' type='text' value='*.snappy, *.json' length=40 /> comma separated; never removed<br>
+
+		<span class='b' >Dry run </span>
+		<input name='dryrun' type='checkbox' value='yes' checked /> only list<br>
+
+		<span class='b' > </span>
+		<input type='submit' value='submit1' accesskey='s' /><br>
+
+	</form>
+
+	`
+
+var tplExpire = tt.Must(tt.New("tplName01").Parse(strExpire))
+
+// expireSubtree removes files by age and total size.
+// It takes GET params too, so that appengine cron can call it:
+//
+//	/fsi/expire?mountname=mntftch&pathprefix=/&maxage=240&dryrun=no
+func expireSubtree(w http.ResponseWriter, r *http.Request, m map[string]interface{}) {
+
+	lg, lge := loghttp.Logger(w, r)
+
+	err := r.ParseForm()
+	lge(err)
+
+	wpf(w, tplx.ExecTplHelper(tplx.Head, map[string]interface{}{"HtmlTitle": "Expire files for curr FS"}))
+	defer wpf(w, tplx.Foot)
+
+	if r.Method != "POST" && r.FormValue("maxage") == "" && r.FormValue("maxsize") == "" {
+		tData := map[string]string{"Url": UriExpire}
+		err := tplExpire.ExecuteTemplate(w, "tplName01", tData)
+		lge(err)
+		return
+	}
+
+	wpf(w, "<pre>\n")
+	defer wpf(w, "\n</pre>")
+
+	mountPoint := dsfs.MountPointLast()
+	if len(r.FormValue("mountname")) > 0 {
+		mountPoint = r.FormValue("mountname")
+	}
+
+	pathPrefix := "/"
+	if len(r.FormValue("pathprefix")) > 0 {
+		pathPrefix = r.FormValue("pathprefix")
+	}
+
+	p := expiry.Policy{}
+	if hrs, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("maxage")), 64); err == nil {
+		p.MaxAge = time.Duration(hrs * float64(time.Hour))
+	}
+	if kb, err := strconv.ParseInt(strings.TrimSpace(r.FormValue("maxsize")), 10, 64); err == nil {
+		p.MaxTotalSize = kb * 1024
+	}
+	for _, pattern := range strings.Split(r.FormValue("keep"), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			p.Rules = append(p.Rules, expiry.Rule{Pattern: pattern, Keep: true})
+		}
+	}
+
+	// the checkbox is absent if unchecked; cron sends dryrun=no
+	dryRun := r.FormValue("dryrun") == "yes" || r.Method != "POST" && r.FormValue("dryrun") == ""
+
+	fs := getFS(appengine.NewContext(r), mountPoint)
+	lg("created fs %v-%v ", fs.Name(), fs.String())
+	lg("expiring %q - max age %v - max size %vkB - dry run %v", pathPrefix, p.MaxAge, p.MaxTotalSize/1024, dryRun)
+
+	rep, err := expiry.Sweep(fs, pathPrefix, p, dryRun)
+	lge(err)
+	if rep != nil {
+		wpf(w, "%s", tt.HTMLEscapeString(rep.String()))
+	}
+
+}
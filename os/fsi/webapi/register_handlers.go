@@ -27,6 +27,7 @@ func InitHandlers() {
 
 	http.HandleFunc("/fsi/delete-all", loghttp.Adapter(deleteAll))
 	http.HandleFunc(UriDeleteSubtree, loghttp.Adapter(DeleteSubtree))
+	http.HandleFunc(UriExpire, loghttp.Adapter(expireSubtree))
 
 	http.HandleFunc("/fsi/cntr/last", loghttp.Adapter(lastMountPoint))
 	http.HandleFunc("/fsi/cntr/reset", loghttp.Adapter(resetMountPoint))
@@ -51,6 +52,7 @@ func BackendUIRendered() *bytes.Buffer {
 
 	// htmlfrag.Wb(b1, "delete all", "/fsi/delete-all", "all fs types")
 	htmlfrag.Wb(b1, "delete tree", UriDeleteSubtree, "of selected fs")
+	htmlfrag.Wb(b1, "expire", UriExpire, "by age and size; dry run")
 
 	// htmlfrag.Wb(b1, , "")
 	htmlfrag.Wb(b1, "dsfs mount", "nobr")
@@ -0,0 +1,83 @@
+package fingerprint
+
+import (
+	"fmt"
+	"testing"
+)
+
+const (
+	para1 = "The European Union struggles to agree on the distribution of refugees among its member states, while more people arrive every day."
+	para2 = "The European Union struggles to agree on the distribution of refugees among its member states, while more people arrive each day."
+	para3 = "Brazil's central bank raised interest rates again, citing inflation far above its target and a weakening currency."
+)
+
+func TestSimHash(t *testing.T) {
+	h1 := SimHash(Shingles(para1, 3))
+	h2 := SimHash(Shingles(para2, 3))
+	h3 := SimHash(Shingles(para3, 3))
+	if d := Hamming(h1, h2); d > 12 {
+		t.Errorf("near duplicates differ by %v bits", d)
+	}
+	if d := Hamming(h1, h3); d < 16 {
+		t.Errorf("distinct texts differ by only %v bits", d)
+	}
+	if Hamming(0xff, 0x0f) != 4 {
+		t.Errorf("hamming")
+	}
+}
+
+func TestMinHash(t *testing.T) {
+	mh := NewMinHasher(100, 1)
+	s1 := mh.Signature(Shingles(para1, 3))
+	s2 := mh.Signature(Shingles(para2, 3))
+	s3 := mh.Signature(Shingles(para3, 3))
+	// 19 shingles each, 17 shared => jaccard 17/21 = 0.81
+	if j := s1.Jaccard(s2); j < 0.65 || j > 0.95 {
+		t.Errorf("near duplicates: %v", j)
+	}
+	if j := s1.Jaccard(s3); j > 0.1 {
+		t.Errorf("distinct texts: %v", j)
+	}
+	if mh.Signature(nil) != nil {
+		t.Errorf("empty text should have no signature")
+	}
+	if NewMinHasher(100, 1).Signature(Shingles(para1, 3)).Jaccard(s1) != 1 {
+		t.Errorf("same seed must yield the same signature")
+	}
+}
+
+func TestIndex(t *testing.T) {
+
+	mh := NewMinHasher(100, 1)
+	x := NewIndex(20, 5)
+	for i := 0; i < 1000; i++ {
+		txt := fmt.Sprintf("filler text number %v about topic %v and subtopic %v", i, i*7, i*13)
+		x.Add(fmt.Sprintf("f%04v", i), mh.Signature(Shingles(txt, 3)))
+	}
+	x.Add("p1", mh.Signature(Shingles(para1, 3)))
+	x.Add("p3", mh.Signature(Shingles(para3, 3)))
+	if err := x.Add("p1", mh.Signature(Shingles(para1, 3))); err == nil {
+		t.Errorf("duplicate id accepted")
+	}
+
+	ms := x.Query(mh.Signature(Shingles(para2, 3)), 0.5)
+	if len(ms) != 1 || ms[0].ID != "p1" {
+		t.Errorf("query: %+v", ms)
+	}
+	if c := x.Candidates(mh.Signature(Shingles(para2, 3))); len(c) > 10 {
+		t.Errorf("%v candidates - buckets do not discriminate", len(c))
+	}
+
+	sx := NewSimIndex(16)
+	sx.Add("p1", SimHash(Shingles(para1, 3)))
+	sx.Add("p3", SimHash(Shingles(para3, 3)))
+	h2 := SimHash(Shingles(para2, 3))
+	d := Hamming(h2, SimHash(Shingles(para1, 3)))
+	if ms := sx.Query(h2, d); d < 16 && (len(ms) != 1 || ms[0].ID != "p1") {
+		t.Errorf("simindex at distance %v: %+v", d, ms)
+	}
+	sx.Remove("p1")
+	if ms := sx.Query(h2, d); len(ms) != 0 || sx.Len() != 1 {
+		t.Errorf("removed hash found: %+v", ms)
+	}
+}
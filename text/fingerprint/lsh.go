@@ -0,0 +1,201 @@
+package fingerprint
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Match is a result of a query.
+type Match struct {
+	ID         string
+	Similarity float64 // estimated jaccard for Index; 1 - hamming/64 for SimIndex
+}
+
+type byID []string
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool { return s[i] < s[j] }
+
+type bySimilarity []Match
+
+func (s bySimilarity) Len() int      { return len(s) }
+func (s bySimilarity) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySimilarity) Less(i, j int) bool {
+	if s[i].Similarity != s[j].Similarity {
+		return s[i].Similarity > s[j].Similarity
+	}
+	return s[i].ID < s[j].ID
+}
+
+// Index buckets MinHash signatures by bands of rows components.
+// Two signatures with jaccard similarity s share a bucket
+// with probability 1 - (1 - s^rows)^bands;
+// for 20 bands of 5 rows, that is 0.47 at s=0.5 and 0.9996 at s=0.8.
+type Index struct {
+	bands, rows int
+	buckets     []map[uint64][]string
+	sigs        map[string]Signature
+}
+
+func NewIndex(bands, rows int) *Index {
+	x := &Index{bands: bands, rows: rows, sigs: map[string]Signature{}}
+	x.buckets = make([]map[uint64][]string, bands)
+	for i := range x.buckets {
+		x.buckets[i] = map[uint64][]string{}
+	}
+	return x
+}
+
+// SignatureLen is the length, which signatures must have.
+func (x *Index) SignatureLen() int { return x.bands * x.rows }
+
+func (x *Index) bandKey(sig Signature, band int) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, v := range sig[band*x.rows : (band+1)*x.rows] {
+		for i := uint(0); i < 8; i++ {
+			b[i] = byte(v >> (8 * i))
+		}
+		h.Write(b[:])
+	}
+	return h.Sum64()
+}
+
+// Add indexes sig under id; an id may only be added once.
+func (x *Index) Add(id string, sig Signature) error {
+	if len(sig) != x.SignatureLen() {
+		return fmt.Errorf("signature of %v has length %v; want %v", id, len(sig), x.SignatureLen())
+	}
+	if _, ok := x.sigs[id]; ok {
+		return fmt.Errorf("%v already indexed", id)
+	}
+	x.sigs[id] = sig
+	for band := 0; band < x.bands; band++ {
+		k := x.bandKey(sig, band)
+		x.buckets[band][k] = append(x.buckets[band][k], id)
+	}
+	return nil
+}
+
+func (x *Index) Len() int { return len(x.sigs) }
+
+// Candidates share at least one bucket with sig.
+func (x *Index) Candidates(sig Signature) []string {
+	if len(sig) != x.SignatureLen() {
+		return nil
+	}
+	seen := map[string]bool{}
+	ret := []string{}
+	for band := 0; band < x.bands; band++ {
+		for _, id := range x.buckets[band][x.bandKey(sig, band)] {
+			if !seen[id] {
+				seen[id] = true
+				ret = append(ret, id)
+			}
+		}
+	}
+	sort.Sort(byID(ret))
+	return ret
+}
+
+// Query returns the candidates with estimated jaccard of at least min,
+// most similar first.
+func (x *Index) Query(sig Signature, min float64) []Match {
+	ret := []Match{}
+	for _, id := range x.Candidates(sig) {
+		if j := sig.Jaccard(x.sigs[id]); j >= min {
+			ret = append(ret, Match{id, j})
+		}
+	}
+	sort.Sort(bySimilarity(ret))
+	return ret
+}
+
+// SimIndex buckets SimHashes by bands of bits.
+// By pigeonhole, hashes with a hamming distance below the number of bands
+// share at least one band - and are always found.
+type SimIndex struct {
+	bands   int
+	width   uint
+	buckets []map[uint64][]string
+	hashes  map[string]uint64
+}
+
+// NewSimIndex splits 64 bits into bands; bands must divide 64.
+func NewSimIndex(bands int) *SimIndex {
+	if bands < 1 || 64%bands != 0 {
+		panic(fmt.Sprintf("%v bands do not divide 64 bits", bands))
+	}
+	x := &SimIndex{bands: bands, width: uint(64 / bands), hashes: map[string]uint64{}}
+	x.buckets = make([]map[uint64][]string, bands)
+	for i := range x.buckets {
+		x.buckets[i] = map[uint64][]string{}
+	}
+	return x
+}
+
+func (x *SimIndex) band(h uint64, band int) uint64 {
+	if x.width == 64 {
+		return h
+	}
+	return h >> (uint(band) * x.width) & (1<<x.width - 1)
+}
+
+func (x *SimIndex) Add(id string, h uint64) {
+	if _, ok := x.hashes[id]; ok {
+		return
+	}
+	x.hashes[id] = h
+	for band := 0; band < x.bands; band++ {
+		k := x.band(h, band)
+		x.buckets[band][k] = append(x.buckets[band][k], id)
+	}
+}
+
+// Remove takes id out of its buckets; ids may be added anew afterwards.
+func (x *SimIndex) Remove(id string) {
+	h, ok := x.hashes[id]
+	if !ok {
+		return
+	}
+	delete(x.hashes, id)
+	for band := 0; band < x.bands; band++ {
+		k := x.band(h, band)
+		ids := x.buckets[band][k]
+		for i, id2 := range ids {
+			if id2 == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(x.buckets[band], k)
+		} else {
+			x.buckets[band][k] = ids
+		}
+	}
+}
+
+func (x *SimIndex) Len() int { return len(x.hashes) }
+
+// Query returns the hashes within maxHamming, most similar first.
+// Recall is complete for maxHamming < bands.
+func (x *SimIndex) Query(h uint64, maxHamming int) []Match {
+	seen := map[string]bool{}
+	ret := []Match{}
+	for band := 0; band < x.bands; band++ {
+		for _, id := range x.buckets[band][x.band(h, band)] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if d := Hamming(h, x.hashes[id]); d <= maxHamming {
+				ret = append(ret, Match{id, 1 - float64(d)/64})
+			}
+		}
+	}
+	sort.Sort(bySimilarity(ret))
+	return ret
+}
@@ -0,0 +1,59 @@
+package fingerprint
+
+import "math/rand"
+
+// MinHasher computes signatures of a fixed length.
+// Signatures are only comparable, if computed by MinHashers
+// of equal length and seed.
+type MinHasher struct {
+	a, b []uint64
+}
+
+// NewMinHasher creates n hash functions from seed.
+func NewMinHasher(n int, seed int64) *MinHasher {
+	rnd := rand.New(rand.NewSource(seed))
+	m := &MinHasher{a: make([]uint64, n), b: make([]uint64, n)}
+	for i := 0; i < n; i++ {
+		m.a[i] = uint64(rnd.Int63()) | 1 // odd multipliers permute
+		m.b[i] = uint64(rnd.Int63())
+	}
+	return m
+}
+
+// Signature holds the minimum of each hash function over the shingles.
+type Signature []uint64
+
+// No shingles yield a nil signature.
+func (m *MinHasher) Signature(shingles []uint64) Signature {
+	if len(shingles) == 0 {
+		return nil
+	}
+	sig := make(Signature, len(m.a))
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for _, s := range shingles {
+		for i := range sig {
+			h := m.a[i]*s + m.b[i]
+			h ^= h >> 29 // the low bits of a product are poorly mixed
+			if h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// Jaccard estimates the similarity of the shingle sets.
+func (s Signature) Jaccard(o Signature) float64 {
+	if len(s) == 0 || len(s) != len(o) {
+		return 0
+	}
+	eq := 0
+	for i := range s {
+		if s[i] == o[i] {
+			eq++
+		}
+	}
+	return float64(eq) / float64(len(s))
+}
@@ -0,0 +1,53 @@
+// Package fingerprint finds near-duplicate texts without comparing all pairs.
+//
+// Texts are broken into word shingles - overlapping runs of k words.
+// SimHash condenses them into 64 bits, whose hamming distance
+// reflects the similarity of the texts.
+// MinHash condenses them into a signature, whose share of equal components
+// estimates the jaccard similarity of the shingle sets.
+//
+// Index puts signatures into locality-sensitive-hashing buckets:
+// similar signatures share a bucket with high probability,
+// dissimilar ones rarely. Thus candidates are found in constant time,
+// and only those are compared exactly.
+package fingerprint
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Words splits text into lower case words;
+// punctuation is dropped.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Shingles returns the hashes of all runs of k words.
+// Texts shorter than k yield one shingle of all their words.
+func Shingles(text string, k int) []uint64 {
+	words := Words(text)
+	if len(words) == 0 {
+		return nil
+	}
+	if k < 1 {
+		k = 1
+	}
+	if len(words) < k {
+		k = len(words)
+	}
+	ret := make([]uint64, 0, len(words)-k+1)
+	for i := 0; i+k <= len(words); i++ {
+		ret = append(ret, hash(strings.Join(words[i:i+k], " ")))
+	}
+	return ret
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
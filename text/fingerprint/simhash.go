@@ -0,0 +1,34 @@
+package fingerprint
+
+// SimHash of a set of feature hashes, i.e. shingles.
+// Every bit is the majority vote of the features.
+func SimHash(features []uint64) uint64 {
+	var v [64]int
+	for _, f := range features {
+		for i := uint(0); i < 64; i++ {
+			if f&(1<<i) != 0 {
+				v[i]++
+			} else {
+				v[i]--
+			}
+		}
+	}
+	var ret uint64
+	for i := uint(0); i < 64; i++ {
+		if v[i] > 0 {
+			ret |= 1 << i
+		}
+	}
+	return ret
+}
+
+// Hamming is the number of differing bits.
+func Hamming(a, b uint64) int {
+	x := a ^ b
+	cnt := 0
+	for x != 0 {
+		x &= x - 1
+		cnt++
+	}
+	return cnt
+}